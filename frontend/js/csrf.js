@@ -0,0 +1,46 @@
+// Attach the session's CSRF token to every state-changing request.
+// Scripts get an X-CSRF-Token header, plain forms a hidden csrf_token field.
+(function () {
+    const safeMethods = ['GET', 'HEAD', 'OPTIONS', 'TRACE'];
+
+    const tokenPromise = fetch('/api/auth/status', { credentials: 'same-origin' })
+        .then(response => response.json())
+        .then(data => data.csrfToken || '')
+        .catch(() => '');
+
+    const originalFetch = window.fetch.bind(window);
+    window.fetch = async function (input, init = {}) {
+        const method = (init.method || (input instanceof Request ? input.method : 'GET')).toUpperCase();
+        const url = new URL(input instanceof Request ? input.url : input, window.location.href);
+
+        if (!safeMethods.includes(method) && url.origin === window.location.origin) {
+            const token = await tokenPromise;
+            if (token) {
+                const headers = new Headers(init.headers || (input instanceof Request ? input.headers : undefined));
+                headers.set('X-CSRF-Token', token);
+                init = { ...init, headers };
+            }
+        }
+        return originalFetch(input, init);
+    };
+
+    // Forms are submitted after the token is in place
+    document.addEventListener('submit', async function (event) {
+        const form = event.target;
+        if (event.defaultPrevented || (form.method || '').toUpperCase() !== 'POST' || form.dataset.csrfReady) {
+            return;
+        }
+        event.preventDefault();
+
+        const token = await tokenPromise;
+        if (token && !form.querySelector('input[name="csrf_token"]')) {
+            const input = document.createElement('input');
+            input.type = 'hidden';
+            input.name = 'csrf_token';
+            input.value = token;
+            form.appendChild(input);
+        }
+        form.dataset.csrfReady = 'true';
+        form.submit();
+    });
+})();
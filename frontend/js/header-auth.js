@@ -19,16 +19,8 @@ async function loadHeaderBasedOnAuth() {
                 // If signed in, update notifications and profile image
                 if (isLoggedIn) {
                     setTimeout(() => {
-                        // Update notifications
-                        fetch('/api/notifications/count')
-                            .then(response => response.json())
-                            .then(data => {
-                                const dot = document.getElementById('notification-dot');
-                                if (dot) {
-                                    dot.style.display = data.count > 0 ? 'block' : 'none';
-                                }
-                            })
-                            .catch(error => console.error('🔔 Notification error:', error));
+                        // Update notifications as they arrive
+                        connectNotificationStream();
 
                         // Update profile image 
                         fetch('/api/user/profile')
@@ -63,6 +55,23 @@ async function loadHeaderBasedOnAuth() {
             }
         }
 
+        // Keep the notification dot up to date from the server's event
+        // stream and pass new notifications on to the page as a
+        // "notification" event; the browser reconnects it when it drops
+        function connectNotificationStream() {
+            const stream = new EventSource('/api/notifications/stream');
+            stream.addEventListener('count', event => {
+                const { count } = JSON.parse(event.data);
+                const dot = document.getElementById('notification-dot');
+                if (dot) {
+                    dot.style.display = count > 0 ? 'block' : 'none';
+                }
+            });
+            stream.addEventListener('notification', event => {
+                document.dispatchEvent(new CustomEvent('notification', { detail: JSON.parse(event.data) }));
+            });
+        }
+
         // Call the function
         loadHeaderBasedOnAuth();
 
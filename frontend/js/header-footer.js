@@ -6,17 +6,8 @@
 
       // Wait a moment, then directly call the functions
       setTimeout(() => {
-        // Update notifications
-        fetch('/api/notifications/count')
-          .then(response => response.json())
-          .then(data => {
-            const dot = document.getElementById('notification-dot');
-            if (dot) {
-              dot.style.display = data.count > 0 ? 'block' : 'none';
-              console.log('🔔 Red dot updated:', data.count > 0 ? 'visible' : 'hidden');
-            }
-          })
-          .catch(error => console.error('🔔 Notification error:', error));
+        // Update notifications as they arrive
+        connectNotificationStream();
 
         // Update profile image 
         fetch('/api/user/profile')  // ← Make sure this is singular "user", not "users"
@@ -49,4 +40,21 @@
     // footer
     fetch('/frontend/templates/shared/footer.html').then(r => r.text())
         .then(html => document.getElementById('shared-footer').innerHTML = html);
-    
\ No newline at end of file
+    
+
+// connectNotificationStream keeps the notification dot up to date from the
+// server's event stream and passes new notifications on to the page as a
+// "notification" event; the browser reconnects it when it drops
+function connectNotificationStream() {
+  const stream = new EventSource('/api/notifications/stream');
+  stream.addEventListener('count', event => {
+    const { count } = JSON.parse(event.data);
+    const dot = document.getElementById('notification-dot');
+    if (dot) {
+      dot.style.display = count > 0 ? 'block' : 'none';
+    }
+  });
+  stream.addEventListener('notification', event => {
+    document.dispatchEvent(new CustomEvent('notification', { detail: JSON.parse(event.data) }));
+  });
+}
@@ -38,10 +38,14 @@
     allCategories.forEach(category => {
       const categoryItem = document.createElement('button');
       categoryItem.className = 'list-group-item list-group-item-action category-item';
+      categoryItem.title = category.description || '';
       categoryItem.innerHTML = `
-                <i class="bi bi-tag me-2"></i>
+                <i class="bi bi-${category.icon || 'tag'} me-2"></i>
                 <span>${category.name}</span>
             `;
+      if (category.color) categoryItem.querySelector('i').style.color = category.color;
+      // Sub-categories are indented under their parent
+      if (category.depth) categoryItem.style.paddingLeft = `${1 + category.depth * 1.25}rem`;
       categoryItem.addEventListener('click', () => {
         setActiveCategory(categoryItem);
         setActiveTopbarTab('questions');
@@ -65,7 +69,17 @@
     document.querySelector(`[data-filter="${filter}"]`)?.classList.add('active');
   }
 
+  // Listing currently shown and the cursor of its next page
+  let postsUrl = '/api/posts';
+  let nextCursor = '';
+
+  // Filter of the listing shown, so a new sort order can reload it
+  let currentFilter = '';
+  let currentValue = '';
+
   async function loadPosts(filter = "", value = "") {
+    currentFilter = filter;
+    currentValue = value;
     try {
       let url = '/api/posts';
 
@@ -79,6 +93,8 @@
         url += `?filter=${encodeURIComponent(filter)}`;
       }
 
+      url += (url.includes('?') ? '&' : '?') + sortParams();
+
       const res = await fetch(url);
       if (!res.ok && (filter === 'my-posts' || filter === 'my-likes')) {
         // If unauthorized, redirect to login
@@ -88,13 +104,36 @@
         }
       }
 
-      allPosts = await res.json();
+      const data = await res.json();
+      postsUrl = url;
+      allPosts = data.posts;
+      setNextCursor(data.nextCursor);
       renderPosts(allPosts);
     } catch (e) {
       console.error('Failed to load posts', e);
     }
   }
 
+  // Append the next page of the current listing
+  async function loadMorePosts() {
+    if (!nextCursor) return;
+    try {
+      const sep = postsUrl.includes('?') ? '&' : '?';
+      const res = await fetch(`${postsUrl}${sep}cursor=${encodeURIComponent(nextCursor)}`);
+      const data = await res.json();
+      allPosts = allPosts.concat(data.posts);
+      setNextCursor(data.nextCursor);
+      renderPosts(allPosts);
+    } catch (e) {
+      console.error('Failed to load more posts', e);
+    }
+  }
+
+  function setNextCursor(cursor) {
+    nextCursor = cursor || '';
+    document.getElementById('loadMoreBtn').classList.toggle('d-none', !nextCursor);
+  }
+
   function renderPosts(posts) {
     container.innerHTML = '';
 
@@ -154,13 +193,18 @@
                             <p class="card-text text-light mb-3">${post.excerpt}</p>
                         </div>
                         
-                        <!-- Tags -->
+                        <!-- Categories and tags -->
                         <div class="mb-3 clickable-area" onclick="viewPost(${post.id})" style="cursor: pointer;">
-                            ${post.tags.map(tag => `
+                            ${post.categories.map(category => `
                                 <span class="badge me-1" style="background-color: rgba(109, 214, 40, 0.8); color: white;">
-                                    #${tag}
+                                    ${category}
                                 </span>
                             `).join('')}
+                            ${post.tags.map(tag => `
+                                <a href="/tags/${encodeURIComponent(tag)}" class="badge me-1 text-decoration-none"
+                                   style="background-color: rgba(255, 255, 255, 0.15); color: white;"
+                                   onclick="event.stopPropagation()">#${tag}</a>
+                            `).join('')}
                         </div>
                         
                         <!-- Post Actions and Stats -->
@@ -206,12 +250,12 @@
       event.stopImmediatePropagation();
 
       try {
-        const response = await fetch('/api/posts/like', {
+        const response = await fetch(`/api/posts/${postId}/vote`, {
           method: 'POST',
           headers: {
             'Content-Type': 'application/x-www-form-urlencoded',
           },
-          body: `post_id=${postId}&vote=${vote}`
+          body: `vote=${vote}`
         });
 
         if (!response.ok) {
@@ -303,6 +347,24 @@
     ));
   });
 
+  document.getElementById('loadMoreBtn').addEventListener('click', loadMorePosts);
+
+  // Query parameters of the chosen sort order; the window only applies to top posts
+  function sortParams() {
+    const sort = document.getElementById('sortSelect').value;
+    const params = new URLSearchParams({ sort });
+    if (sort === 'top') {
+      params.set('window', document.getElementById('windowSelect').value);
+    }
+    return params.toString();
+  }
+
+  document.getElementById('sortSelect').addEventListener('change', e => {
+    document.getElementById('windowSelect').classList.toggle('d-none', e.target.value !== 'top');
+    loadPosts(currentFilter, currentValue);
+  });
+  document.getElementById('windowSelect').addEventListener('change', () => loadPosts(currentFilter, currentValue));
+
   // Initial load
   document.addEventListener('DOMContentLoaded', () => {
     loadCategories();
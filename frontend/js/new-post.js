@@ -212,6 +212,7 @@
                     if (result.success) {
                         uploadedImageId = result.filename;
                         document.getElementById('imageId').value = uploadedImageId;
+                        scheduleAutosave();
 
                         setTimeout(() => {
                             uploadProgress.style.display = 'none';
@@ -245,6 +246,7 @@
 
                 uploadedImageId = null;
                 document.getElementById('imageId').value = '';
+                scheduleAutosave();
             }
 
             // Reset UI
@@ -307,23 +309,138 @@
         // Form submission
             document.getElementById('postForm').addEventListener('submit', async (e) => {
                 e.preventDefault();
+                clearTimeout(autosaveTimer);
 
                 if (selectedCategories.size === 0) {
                     alert('Please select at least one category.');
                     return;
                 }
 
-                // Submit the form
-                e.target.action = '/new-post';
-                e.target.submit();
+                // Sent by script so csrf.js can add the token header, which
+                // multipart forms need; success redirects to the new post
+                try {
+                    const response = await fetch('/new-post', { method: 'POST', body: new FormData(e.target) });
+                    if (!response.ok) throw new Error((await response.text()).trim());
+                    window.location.href = response.url;
+                } catch (error) {
+                    alert(error.message || 'Failed to create post');
+                }
             });
 
             // Event listeners for form validation
             document.getElementById('title').addEventListener('input', validateForm);
             document.getElementById('content').addEventListener('input', validateForm);
 
+            // Preview: the content as it will look once published, rendered by the server
+            const contentInput = document.getElementById('content');
+            const contentPreview = document.getElementById('contentPreview');
+            const togglePreview = document.getElementById('togglePreview');
+
+            togglePreview.addEventListener('click', async () => {
+                if (contentPreview.style.display !== 'none') {
+                    contentPreview.style.display = 'none';
+                    contentInput.style.display = '';
+                    togglePreview.innerHTML = '<i class="bi bi-eye me-1"></i>Preview';
+                    return;
+                }
+
+                try {
+                    const response = await fetch('/api/markdown/preview', {
+                        method: 'POST',
+                        body: new URLSearchParams({ content: contentInput.value })
+                    });
+                    if (!response.ok) throw new Error((await response.text()).trim());
+                    const result = await response.json();
+                    contentPreview.innerHTML = result.html || '<p class="text-muted">Nothing to preview</p>';
+                } catch (error) {
+                    console.error('Failed to render preview:', error);
+                    contentPreview.textContent = 'Preview unavailable';
+                }
+                contentInput.style.display = 'none';
+                contentPreview.style.display = 'block';
+                togglePreview.innerHTML = '<i class="bi bi-pencil me-1"></i>Write';
+            });
+
+            // Drafts: the form saves itself a moment after each change, and
+            // /new-post?draft=ID picks a saved draft back up
+            let autosaveTimer = null;
+            const draftIdInput = document.getElementById('draftId');
+            const draftStatus = document.getElementById('draftStatus');
+
+            function scheduleAutosave() {
+                clearTimeout(autosaveTimer);
+                autosaveTimer = setTimeout(saveDraft, 2000);
+            }
+
+            async function saveDraft() {
+                const formData = new FormData(document.getElementById('postForm'));
+                formData.delete('image');
+                formData.delete('csrf_token');
+
+                // Nothing worth keeping in an empty new form
+                const empty = !formData.get('title').trim() && !formData.get('content').trim();
+                if (empty && !draftIdInput.value) return;
+
+                try {
+                    draftStatus.textContent = 'Saving draft...';
+                    const response = await fetch('/api/drafts', { method: 'POST', body: formData });
+                    if (!response.ok) throw new Error((await response.text()).trim());
+
+                    const result = await response.json();
+                    if (!draftIdInput.value) {
+                        draftIdInput.value = result.id;
+                        history.replaceState(null, '', `/new-post?draft=${result.id}`);
+                    }
+                    draftStatus.textContent = `Draft saved at ${new Date().toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' })}`;
+                } catch (error) {
+                    console.error('Failed to save draft:', error);
+                    draftStatus.textContent = 'Draft not saved';
+                }
+            }
+
+            // Fill the form from a saved draft once the categories are loaded
+            async function loadDraft(draftId) {
+                try {
+                    const response = await fetch(`/api/drafts/${encodeURIComponent(draftId)}`);
+                    if (!response.ok) throw new Error('Draft not found');
+                    const draft = await response.json();
+
+                    draftIdInput.value = draft.id;
+                    document.getElementById('title').value = draft.title;
+                    document.getElementById('content').value = draft.content;
+                    document.getElementById('tags').value = draft.tags;
+                    draft.categories.forEach(name => {
+                        if (allCategories.some(category => category.name === name)) selectedCategories.add(name);
+                    });
+                    updateCategoryDisplay();
+                    updateCategoryInputs();
+
+                    if (draft.imageId) {
+                        uploadedImageId = draft.imageId;
+                        document.getElementById('imageId').value = draft.imageId;
+                        previewImg.src = draft.imageUrl;
+                        imageInfo.textContent = '';
+                        imageUploadArea.style.display = 'none';
+                        imagePreview.style.display = 'block';
+                    }
+                    draftStatus.textContent = `Draft saved ${draft.timeAgo}`;
+                    validateForm();
+                } catch (error) {
+                    console.error('Failed to load draft:', error);
+                    history.replaceState(null, '', '/new-post');
+                }
+            }
+
+            ['title', 'content', 'tags'].forEach(id => {
+                document.getElementById(id).addEventListener('input', scheduleAutosave);
+            });
+            document.getElementById('categoryBubbles').addEventListener('click', scheduleAutosave);
+            document.getElementById('selectedCategories').addEventListener('click', scheduleAutosave);
+
             // Initialize page
-            document.addEventListener('DOMContentLoaded', () => {
-                loadCategories();
+            document.addEventListener('DOMContentLoaded', async () => {
+                await loadCategories();
+                const draftId = new URLSearchParams(window.location.search).get('draft');
+                if (draftId) await loadDraft(draftId);
                 validateForm();
             });
\ No newline at end of file
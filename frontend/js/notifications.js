@@ -105,6 +105,7 @@
                 like: { icon: 'bi-heart-fill', color: '#dc3545' },
                 dislike: { icon: 'bi-heartbreak-fill', color: '#6c757d' }, // NEW: Add dislike icon
                 comment: { icon: 'bi-chat-fill', color: '#0d6efd' },
+                mention: { icon: 'bi-at', color: '#6f42c1' },
                 system: { icon: 'bi-info-circle-fill', color: '#198754' }
             };
 
@@ -134,12 +135,8 @@
         }
 
         try {
-            const response = await fetch('/api/notifications/mark-read', {
-                method: 'POST',
-                headers: {
-                    'Content-Type': 'application/x-www-form-urlencoded',
-                },
-                body: `notification_id=${notificationId}`
+            const response = await fetch(`/api/notifications/${notificationId}/read`, {
+                method: 'POST'
             });
 
             if (response.ok) {
@@ -271,13 +268,14 @@
     // Event listeners
     document.addEventListener('DOMContentLoaded', () => {
         loadNotifications();
-
-        // Auto-refresh every 30 seconds
-        setInterval(loadNotifications, 30000);
     });
 
-    // Real-time updates (if you implement WebSocket in the future)
+    // New notifications arrive through the header's event stream
+    document.addEventListener('notification', event => handleRealTimeNotification(event.detail));
+
     function handleRealTimeNotification(notification) {
+        // A reconnecting stream can replay one that was already listed
+        if (notifications.unread.some(n => n.id === notification.id)) return;
         notifications.unread.unshift(notification);
         renderNotifications();
         updateBadges();
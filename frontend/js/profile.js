@@ -125,27 +125,101 @@ document.addEventListener('DOMContentLoaded', () => {
       showSection("bioSection");
     });
 
+    // Profile lists come a page at a time; cursors holds where each one continues
+    const cursors = {};
+    async function loadList(name, url, key) {
+      const cursor = cursors[name];
+      try {
+        const res = await fetch(cursor ? `${url}?cursor=${encodeURIComponent(cursor)}` : url);
+        const data = await res.json();
+        cache[name] = (cache[name] || []).concat(data[key] || []);
+        cursors[name] = data.nextCursor || "";
+      } catch {
+        cache[name] = cache[name] || [];
+        cursors[name] = "";
+      }
+    }
+
+    // Add a "Load more" button under a list that has further pages
+    function addLoadMore(container, name, url, key, render) {
+      if (!cursors[name]) return;
+      const btn = document.createElement("button");
+      btn.className = "list-group-item list-group-item-action text-center";
+      btn.innerHTML = `<i class="bi bi-arrow-down-circle me-2"></i>Load more`;
+      btn.addEventListener("click", async () => {
+        await loadList(name, url, key);
+        render();
+      });
+      document.getElementById(container).appendChild(btn);
+    }
+
+    // Show a tab's post list, fetching its first page on the first visit
+    async function showPostList(name, url, container) {
+      if (!cache[name]) await loadList(name, url, "posts");
+      const render = () => {
+        renderPosts(cache[name], container);
+        addLoadMore(container, name, url, "posts", render);
+      };
+      render();
+    }
+
     document.getElementById("tab-posts").addEventListener("click", async () => {
       activate("tab-posts");
       showSection("postsSection");
-      if (!cache.posts) {
-        try {
-          const res = await fetch("/api/user/posts");
-          cache.posts = await res.json();
-        } catch { cache.posts = []; }
-      }
-      renderPosts(cache.posts, "userPostsContainer");
+      await showPostList("posts", "/api/user/posts", "userPostsContainer");
+    });
+
+    document.getElementById("tab-drafts").addEventListener("click", async () => {
+      activate("tab-drafts");
+      showSection("draftsSection");
+      if (!cache.drafts) await loadList("drafts", "/api/drafts", "drafts");
+      renderDrafts();
     });
 
+    // Drafts open in the new post form, where they keep autosaving
+    function renderDrafts() {
+      const el = document.getElementById("userDraftsContainer");
+      el.innerHTML = "";
+      if (!cache.drafts || cache.drafts.length === 0) {
+        el.innerHTML = `<div class="text-muted px-2">No drafts.</div>`;
+      } else {
+        cache.drafts.forEach(d => {
+          const a = document.createElement("a");
+          a.className = "list-group-item list-group-item-action post-card";
+          a.href = `/new-post?draft=${d.id}`;
+          a.innerHTML = `
+            <div class="d-flex w-100 justify-content-between">
+              <strong class="mb-1"></strong>
+              <small class="text-muted">
+                saved ${d.timeAgo || ""}
+                <i class="bi bi-trash ms-2" role="button" title="Delete draft"></i>
+              </small>
+            </div>
+          `;
+          a.querySelector("strong").textContent = d.title || "(untitled)";
+          a.querySelector(".bi-trash").addEventListener("click", async e => {
+            e.preventDefault();
+            if (!confirm("Delete this draft?")) return;
+            const res = await fetch(`/api/drafts/${d.id}`, { method: "DELETE" });
+            if (res.ok) {
+              cache.drafts = cache.drafts.filter(other => other.id !== d.id);
+              renderDrafts();
+            }
+          });
+          el.appendChild(a);
+        });
+      }
+      addLoadMore("userDraftsContainer", "drafts", "/api/drafts", "drafts", renderDrafts);
+    }
+
     document.getElementById("tab-comments").addEventListener("click", async () => {
       activate("tab-comments");
       showSection("commentsSection");
-      if (!cache.comments) {
-        try {
-          const res = await fetch("/api/user/comments");
-          cache.comments = await res.json();
-        } catch { cache.comments = []; }
-      }
+      if (!cache.comments) await loadList("comments", "/api/user/comments", "comments");
+      renderComments();
+    });
+
+    function renderComments() {
       const el = document.getElementById("userCommentsContainer");
       el.innerHTML = "";
       if (!cache.comments || cache.comments.length === 0) {
@@ -165,30 +239,79 @@ document.addEventListener('DOMContentLoaded', () => {
           el.appendChild(a);
         });
       }
-    });
+      addLoadMore("userCommentsContainer", "comments", "/api/user/comments", "comments", renderComments);
+    }
 
     document.getElementById("tab-likes").addEventListener("click", async () => {
       activate("tab-likes");
       showSection("likesSection");
-      if (!cache.likes) {
-        try {
-          const res = await fetch("/api/user/likes");
-          cache.likes = await res.json();
-        } catch { cache.likes = []; }
-      }
-      renderPosts(cache.likes, "userLikesContainer");
+      await showPostList("likes", "/api/user/likes", "userLikesContainer");
     });
 
     // Dislikes
     document.getElementById("tab-dislikes").addEventListener("click", async () => {
       activate("tab-dislikes");
       showSection("dislikesSection");
-      if (!cache.dislikes) {
-        try {
-          const res = await fetch("/api/user/dislikes");
-          cache.dislikes = await res.json();
-        } catch { cache.dislikes = []; }
+      await showPostList("dislikes", "/api/user/dislikes", "userDislikesContainer");
+    });
+
+    // Trash
+    const trashLists = {
+      trashPosts: { url: "/api/trash/posts", container: "userTrashPostsContainer" },
+      trashComments: { url: "/api/trash/comments", container: "userTrashCommentsContainer" },
+    };
+    document.getElementById("tab-trash").addEventListener("click", async () => {
+      activate("tab-trash");
+      showSection("trashSection");
+      for (const name of Object.keys(trashLists)) {
+        if (!cache[name]) await loadList(name, trashLists[name].url, "items");
+        renderTrash(name);
       }
-      renderPosts(cache.dislikes, "userDislikesContainer");
     });
+
+    // Items deleted by a moderator can only be restored by a moderator
+    function renderTrash(name) {
+      const { url, container } = trashLists[name];
+      const el = document.getElementById(container);
+      el.innerHTML = "";
+      if (!cache[name] || cache[name].length === 0) {
+        el.innerHTML = `<div class="text-muted px-2">Nothing in the trash.</div>`;
+      } else {
+        cache[name].forEach(item => {
+          const div = document.createElement("div");
+          div.className = "list-group-item post-card";
+          div.innerHTML = `
+            <div class="d-flex w-100 justify-content-between">
+              <strong class="mb-1"></strong>
+              <small class="text-muted">deleted ${item.deletedAgo || ""}</small>
+            </div>
+            <p class="mb-1"></p>
+            <div class="d-flex w-100 justify-content-between align-items-center">
+              <small class="text-muted">Removed for good on ${new Date(item.purgeAt).toLocaleDateString()}</small>
+              ${item.canRestore
+                ? `<button class="btn btn-sm btn-outline-light"><i class="bi bi-arrow-counterclockwise me-1"></i>Restore</button>`
+                : `<small class="text-muted">Removed by a moderator</small>`}
+            </div>
+          `;
+          div.querySelector("strong").textContent = item.title || "(untitled)";
+          div.querySelector("p").textContent = item.excerpt;
+          const btn = div.querySelector("button");
+          if (btn) {
+            btn.addEventListener("click", async () => {
+              const res = await fetch(`/api/${item.type}s/${item.id}/restore`, { method: "POST" });
+              if (res.ok) {
+                cache[name] = cache[name].filter(other => other.id !== item.id);
+                cache.posts = null;
+                cache.comments = null;
+                renderTrash(name);
+              } else {
+                alert(await res.text());
+              }
+            });
+          }
+          el.appendChild(div);
+        });
+      }
+      addLoadMore(container, name, url, "items", () => renderTrash(name));
+    }
   });
\ No newline at end of file
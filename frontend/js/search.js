@@ -0,0 +1,151 @@
+document.addEventListener('DOMContentLoaded', () => {
+  const form = document.getElementById('searchForm');
+  const input = document.getElementById('searchInput');
+  const categoryFilter = document.getElementById('categoryFilter');
+  const results = document.getElementById('searchResults');
+  const loadMoreBtn = document.getElementById('loadMoreBtn');
+
+  let searchType = 'posts';
+  let searchUrl = '';
+  let nextCursor = '';
+
+  // Fill the form from the address bar, so searches can be shared and the
+  // header search box lands here with its words
+  const params = new URLSearchParams(window.location.search);
+  input.value = params.get('q') || '';
+  document.getElementById('authorFilter').value = params.get('author') || '';
+  document.getElementById('fromFilter').value = params.get('from') || '';
+  document.getElementById('toFilter').value = params.get('to') || '';
+  if (params.get('type') === 'comments') searchType = 'comments';
+  selectTab(searchType);
+
+  loadCategories(params.get('category') || '').then(() => search());
+
+  form.addEventListener('submit', e => {
+    e.preventDefault();
+    search();
+  });
+
+  document.querySelectorAll('#searchTabs .nav-link').forEach(tab => {
+    tab.addEventListener('click', () => {
+      searchType = tab.dataset.type;
+      selectTab(searchType);
+      search();
+    });
+  });
+
+  loadMoreBtn.addEventListener('click', loadMore);
+
+  // Load the categories of the filter, selecting the one given
+  async function loadCategories(selected) {
+    try {
+      const res = await fetch('/api/categories');
+      const categories = await res.json();
+      (categories || []).forEach(category => {
+        const option = document.createElement('option');
+        option.value = category.name;
+        option.textContent = category.name;
+        option.selected = category.name === selected;
+        categoryFilter.appendChild(option);
+      });
+    } catch (e) {
+      console.error('Failed to load categories', e);
+    }
+  }
+
+  function selectTab(type) {
+    document.querySelectorAll('#searchTabs .nav-link').forEach(tab => {
+      tab.classList.toggle('active', tab.dataset.type === type);
+    });
+  }
+
+  // Run the search in the form and show the first page of results
+  async function search() {
+    const query = new URLSearchParams(new FormData(form));
+    for (const [key, value] of [...query.entries()]) {
+      if (!value.trim()) query.delete(key);
+    }
+    if (searchType !== 'posts') query.set('type', searchType);
+    history.replaceState(null, '', `/search?${query}`);
+
+    results.innerHTML = '';
+    setNextCursor('');
+    if (!query.get('q')) {
+      showEmpty('Search the forum', 'Type a few words to find posts and comments.');
+      return;
+    }
+
+    searchUrl = `/api/search?${query}`;
+    showLoading(true);
+    try {
+      const data = await fetchResults(searchUrl);
+      renderResults(data.results);
+      setNextCursor(data.nextCursor);
+      if (data.results.length === 0) {
+        showEmpty('No results', 'Try other words or fewer filters.');
+      }
+    } catch (e) {
+      console.error('Search failed', e);
+      showEmpty('Search failed', e.message);
+    } finally {
+      showLoading(false);
+    }
+  }
+
+  // Append the next page of results
+  async function loadMore() {
+    if (!nextCursor) return;
+    try {
+      const data = await fetchResults(`${searchUrl}&cursor=${encodeURIComponent(nextCursor)}`);
+      renderResults(data.results);
+      setNextCursor(data.nextCursor);
+    } catch (e) {
+      console.error('Failed to load more results', e);
+    }
+  }
+
+  async function fetchResults(url) {
+    const res = await fetch(url);
+    if (!res.ok) {
+      throw new Error((await res.text()).trim() || `HTTP ${res.status}`);
+    }
+    return res.json();
+  }
+
+  // Title and snippet come escaped from the server, with the matched words in <mark>
+  function renderResults(list) {
+    document.getElementById('searchEmpty').style.display = 'none';
+    list.forEach(result => {
+      const link = document.createElement('a');
+      link.className = 'search-result p-3';
+      link.href = `/view-post?id=${result.postId}`;
+
+      const kind = result.type === 'comment' ? 'Comment on' : 'Post';
+      link.innerHTML = `
+        <div class="search-result-title">
+          <i class="bi ${result.type === 'comment' ? 'bi-chat-left-text' : 'bi-file-text'} me-1"></i>
+          ${kind}: ${result.title}
+        </div>
+        <div class="search-result-snippet">${result.snippet}</div>
+        <div class="search-result-meta"></div>
+      `;
+      link.querySelector('.search-result-meta').textContent = `by ${result.author} • ${result.timeAgo}`;
+      results.appendChild(link);
+    });
+  }
+
+  function setNextCursor(cursor) {
+    nextCursor = cursor || '';
+    loadMoreBtn.classList.toggle('d-none', !nextCursor);
+  }
+
+  function showLoading(show) {
+    document.getElementById('searchLoading').style.display = show ? 'block' : 'none';
+  }
+
+  function showEmpty(title, text) {
+    document.getElementById('searchEmptyTitle').textContent = title;
+    document.getElementById('searchEmptyText').textContent = text;
+    document.getElementById('searchEmpty').style.display = 'block';
+  }
+});
@@ -0,0 +1,43 @@
+// Suggest existing tags while typing a comma-separated tag list. Every input
+// with a data-tag-suggest attribute gets the most used tags starting with the
+// word being typed in its datalist.
+(function () {
+    function attach(input) {
+        const list = document.getElementById(input.getAttribute('list'));
+        if (!list) return;
+
+        let timer;
+        input.addEventListener('input', () => {
+            clearTimeout(timer);
+            timer = setTimeout(() => suggest(input, list), 200);
+        });
+    }
+
+    async function suggest(input, list) {
+        const parts = input.value.split(',');
+        const typed = parts.pop().trim();
+        const before = parts.map(part => part.trim()).filter(Boolean);
+        if (!typed) {
+            list.innerHTML = '';
+            return;
+        }
+
+        try {
+            const response = await fetch(`/api/tags?q=${encodeURIComponent(typed)}&limit=8`);
+            const tags = await response.json();
+            list.innerHTML = '';
+            tags.filter(tag => !before.includes(tag.name)).forEach(tag => {
+                const option = document.createElement('option');
+                option.value = [...before, tag.name].join(', ');
+                option.label = `#${tag.name} (${tag.postCount})`;
+                list.appendChild(option);
+            });
+        } catch (error) {
+            console.error('Error loading tag suggestions:', error);
+        }
+    }
+
+    document.addEventListener('DOMContentLoaded', () => {
+        document.querySelectorAll('input[data-tag-suggest]').forEach(attach);
+    });
+})();
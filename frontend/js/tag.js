@@ -0,0 +1,94 @@
+document.addEventListener('DOMContentLoaded', () => {
+  const posts = document.getElementById('tagPosts');
+  const loadMoreBtn = document.getElementById('loadMoreBtn');
+
+  // The tag comes from the address, /tags/{name}
+  const requested = decodeURIComponent(window.location.pathname.split('/').pop());
+  let postsUrl = '';
+  let nextCursor = '';
+
+  loadMoreBtn.addEventListener('click', loadMore);
+  loadTag();
+
+  // Look the tag up first: a merged name shows the tag it was merged into
+  async function loadTag() {
+    try {
+      const res = await fetch(`/api/tags/${encodeURIComponent(requested)}`);
+      if (!res.ok) {
+        document.getElementById('tagName').textContent = requested;
+        showEmpty('Tag not found', 'No post has this tag yet.');
+        return;
+      }
+      const tag = await res.json();
+      document.title = `#${tag.name} - Plant Talk`;
+      document.getElementById('tagName').textContent = tag.name;
+      document.getElementById('tagCount').textContent =
+        `${tag.postCount} ${tag.postCount === 1 ? 'post' : 'posts'}`;
+      if (tag.name !== requested) {
+        history.replaceState(null, '', `/tags/${encodeURIComponent(tag.name)}`);
+      }
+
+      postsUrl = `/api/posts?filter=tag&value=${encodeURIComponent(tag.name)}`;
+      const data = await fetchPosts(postsUrl);
+      renderPosts(data.posts);
+      setNextCursor(data.nextCursor);
+      if (data.posts.length === 0) {
+        showEmpty('No posts', 'No post has this tag yet.');
+      }
+    } catch (e) {
+      console.error('Failed to load tag', e);
+      showEmpty('Failed to load posts', e.message);
+    } finally {
+      document.getElementById('tagLoading').style.display = 'none';
+    }
+  }
+
+  // Append the next page of posts
+  async function loadMore() {
+    if (!nextCursor) return;
+    try {
+      const data = await fetchPosts(`${postsUrl}&cursor=${encodeURIComponent(nextCursor)}`);
+      renderPosts(data.posts);
+      setNextCursor(data.nextCursor);
+    } catch (e) {
+      console.error('Failed to load more posts', e);
+    }
+  }
+
+  async function fetchPosts(url) {
+    const res = await fetch(url);
+    if (!res.ok) {
+      throw new Error((await res.text()).trim() || `HTTP ${res.status}`);
+    }
+    return res.json();
+  }
+
+  function renderPosts(list) {
+    list.forEach(post => {
+      const link = document.createElement('a');
+      link.className = 'search-result p-3';
+      link.href = `/view-post?id=${post.id}`;
+      link.innerHTML = `
+        <div class="search-result-title"><i class="bi bi-file-text me-1"></i><span></span></div>
+        <div class="search-result-snippet"></div>
+        <div class="search-result-meta"></div>
+      `;
+      link.querySelector('.search-result-title span').textContent = post.title;
+      link.querySelector('.search-result-snippet').textContent = post.excerpt;
+      link.querySelector('.search-result-meta').textContent =
+        `by ${post.author} • ${post.timeAgo} • ${(post.tags || []).map(tag => `#${tag}`).join(' ')}`;
+      posts.appendChild(link);
+    });
+  }
+
+  function setNextCursor(cursor) {
+    nextCursor = cursor || '';
+    loadMoreBtn.classList.toggle('d-none', !nextCursor);
+  }
+
+  function showEmpty(title, text) {
+    document.getElementById('tagEmptyTitle').textContent = title;
+    document.getElementById('tagEmptyText').textContent = text;
+    document.getElementById('tagEmpty').style.display = 'block';
+  }
+});
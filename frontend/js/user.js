@@ -0,0 +1,80 @@
+document.addEventListener('DOMContentLoaded', () => {
+  const posts = document.getElementById('userPosts');
+  const loadMoreBtn = document.getElementById('loadMoreBtn');
+
+  // The username comes from the address, /users/{name}
+  const username = decodeURIComponent(window.location.pathname.split('/').pop());
+  const postsUrl = `/api/posts?filter=author&value=${encodeURIComponent(username)}`;
+  let nextCursor = '';
+
+  document.title = `${username} - Plant Talk`;
+  document.getElementById('userName').textContent = username;
+
+  loadMoreBtn.addEventListener('click', loadMore);
+  loadPosts();
+
+  async function loadPosts() {
+    try {
+      const data = await fetchPosts(postsUrl);
+      renderPosts(data.posts);
+      setNextCursor(data.nextCursor);
+      if (data.posts.length === 0) {
+        showEmpty('No posts', 'This user has not posted yet.');
+      }
+    } catch (e) {
+      console.error('Failed to load posts', e);
+      showEmpty('Failed to load posts', e.message);
+    } finally {
+      document.getElementById('userLoading').style.display = 'none';
+    }
+  }
+
+  // Append the next page of posts
+  async function loadMore() {
+    if (!nextCursor) return;
+    try {
+      const data = await fetchPosts(`${postsUrl}&cursor=${encodeURIComponent(nextCursor)}`);
+      renderPosts(data.posts);
+      setNextCursor(data.nextCursor);
+    } catch (e) {
+      console.error('Failed to load more posts', e);
+    }
+  }
+
+  async function fetchPosts(url) {
+    const res = await fetch(url);
+    if (!res.ok) {
+      throw new Error((await res.text()).trim() || `HTTP ${res.status}`);
+    }
+    return res.json();
+  }
+
+  function renderPosts(list) {
+    list.forEach(post => {
+      const link = document.createElement('a');
+      link.className = 'search-result p-3';
+      link.href = `/view-post?id=${post.id}`;
+      link.innerHTML = `
+        <div class="search-result-title"><i class="bi bi-file-text me-1"></i><span></span></div>
+        <div class="search-result-snippet"></div>
+        <div class="search-result-meta"></div>
+      `;
+      link.querySelector('.search-result-title span').textContent = post.title;
+      link.querySelector('.search-result-snippet').textContent = post.excerpt;
+      link.querySelector('.search-result-meta').textContent =
+        `${post.timeAgo} • ${(post.tags || []).map(tag => `#${tag}`).join(' ')}`;
+      posts.appendChild(link);
+    });
+  }
+
+  function setNextCursor(cursor) {
+    nextCursor = cursor || '';
+    loadMoreBtn.classList.toggle('d-none', !nextCursor);
+  }
+
+  function showEmpty(title, text) {
+    document.getElementById('userEmptyTitle').textContent = title;
+    document.getElementById('userEmptyText').textContent = text;
+    document.getElementById('userEmpty').style.display = 'block';
+  }
+});
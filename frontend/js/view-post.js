@@ -3,6 +3,10 @@
         let isLoggedIn = false;
         let currentUserId = null;
         let currentEditingCommentId = null;
+        let replyingToCommentId = null;
+        let loadedComments = [];
+        let commentsCursor = '';
+        let postStream = null;
         let editAllCategories = [];
         let editSelectedCategories = new Set();
 
@@ -23,7 +27,7 @@
             currentPostId = postId;
 
             try {
-                const response = await fetch(`/api/post?id=${postId}`);
+                const response = await fetch(`/api/posts/${postId}`);
                 if (!response.ok) {
                     throw new Error('Post not found');
                 }
@@ -31,6 +35,7 @@
                 const post = await response.json();
                 displayPost(post);
                 loadComments();
+                connectPostStream();
 
                 // Hide loading, show post
                 document.getElementById('loading').classList.add('d-none');
@@ -49,18 +54,14 @@
             document.getElementById('post-title').textContent = post.title;
             document.getElementById('post-author').textContent = post.author;
             document.getElementById('post-time').textContent = post.timeAgo;
-            document.getElementById('post-content').innerHTML = post.content.replace(/\n/g, '<br>');
+            document.getElementById('post-edited').classList.toggle('d-none', !post.edited);
+            document.getElementById('post-content').innerHTML = post.contentHtml;
             document.getElementById('like-count').textContent = post.likes || 0;
             document.getElementById('dislike-count').textContent = post.dislikes || 0;
             document.getElementById('comment-count').textContent = post.comments || 0;
 
-            // Display tags
-            const tagsContainer = document.getElementById('post-tags');
-            if (post.tags && post.tags.length > 0) {
-                tagsContainer.innerHTML = post.tags.map(tag =>
-                    `<span class="tag-badge">#${tag}</span>`
-                ).join('');
-            }
+            // Display categories and tags
+            renderPostLabels(post);
 
             // Display image if available
             if (post.imageUrl) {
@@ -70,11 +71,14 @@
                 imageContainer.classList.remove('d-none');
             }
 
-            // Add edit/delete buttons if user is the author
-            if (isLoggedIn && post.isAuthor) {
+            // Add edit/delete buttons if user is the author or a moderator
+            if (isLoggedIn && post.canEdit) {
                 addPostActionButtons();
             }
 
+            // Other users can report the post
+            document.getElementById('report-btn').classList.toggle('d-none', !isLoggedIn || post.isAuthor);
+
             // Update vote buttons
             updateVoteButtons(post.userVote || 0);
 
@@ -124,6 +128,7 @@
                     // Pre-populate form fields
                     document.getElementById('edit-post-title').value = currentPost.title;
                     document.getElementById('edit-post-content').value = currentPost.content;
+                    document.getElementById('edit-post-tags').value = (currentPost.tags || []).join(', ');
 
                     // Load categories and show modal
                     loadEditCategories().then(() => {
@@ -140,8 +145,8 @@
                             editAllCategories = categories;
 
                             // Pre-select current post categories
-                            if (currentPost && currentPost.tags) {
-                                currentPost.tags.forEach(tag => editSelectedCategories.add(tag));
+                            if (currentPost && currentPost.categories) {
+                                currentPost.categories.forEach(category => editSelectedCategories.add(category));
                             }
 
                             renderEditCategories();
@@ -251,7 +256,6 @@
                     try {
                         // Create form data with categories
                         const formData = new FormData();
-                        formData.append('post_id', currentPostId);
                         formData.append('title', title);
                         formData.append('content', content);
 
@@ -259,9 +263,10 @@
                         editSelectedCategories.forEach(category => {
                             formData.append('categories[]', category);
                         });
+                        formData.append('tags', document.getElementById('edit-post-tags').value);
 
-                        const response = await fetch('/api/posts/edit', {
-                            method: 'POST',
+                        const response = await fetch(`/api/posts/${currentPostId}`, {
+                            method: 'PATCH',
                             body: formData
                         });
 
@@ -269,24 +274,20 @@
                             // Update the current display
                             currentPost.title = title;
                             currentPost.content = content;
-                            currentPost.tags = Array.from(editSelectedCategories);
+                            currentPost.categories = Array.from(editSelectedCategories);
+                            const updated = await response.json();
+                            currentPost.tags = updated.tags || [];
 
                             document.getElementById('post-title').textContent = title;
-                            document.getElementById('post-content').innerHTML = content.replace(/\n/g, '<br>');
-
-                            // Update tags display
-                            const tagsContainer = document.getElementById('post-tags');
-                            if (currentPost.tags && currentPost.tags.length > 0) {
-                                tagsContainer.innerHTML = currentPost.tags.map(tag =>
-                                    `<span class="tag-badge">#${tag}</span>`
-                                ).join('');
-                            } else {
-                                tagsContainer.innerHTML = '';
-                            }
+                            document.getElementById('post-content').innerHTML = updated.contentHtml;
+
+                            // Update categories and tags display
+                            renderPostLabels(currentPost);
+                            document.getElementById('post-edited').classList.remove('d-none');
 
                             showSuccessMessage('Post updated successfully!');
                         } else {
-                            showErrorMessage('Failed to edit post');
+                            showErrorMessage((await response.text()).trim() || 'Failed to edit post');
                         }
                     } catch (error) {
                         console.error('Error editing post:', error);
@@ -297,16 +298,12 @@
             // Delete post API call
             async function deletePost() {
                 try {
-                    const response = await fetch('/api/posts/delete', {
-                        method: 'POST',
-                        headers: {
-                            'Content-Type': 'application/x-www-form-urlencoded',
-                        },
-                        body: `post_id=${currentPostId}`
+                    const response = await fetch(`/api/posts/${currentPostId}`, {
+                        method: 'DELETE'
                     });
 
                     if (response.ok) {
-                        showSuccessMessage('Post deleted successfully!');
+                        showSuccessMessage('Post moved to the trash. You can restore it from your profile.');
                         setTimeout(() => {
                             window.location.href = '/'; // Redirect to home
                         }, 1500);
@@ -323,7 +320,10 @@
             function displayComments(comments) {
                 const commentsList = document.getElementById('comments-list');
 
-                const commentCount = comments ? comments.length : 0;
+                // While pages remain, the post's own count covers the unloaded comments
+                const commentCount = commentsCursor
+                    ? parseInt(document.getElementById('comment-count').textContent)
+                    : (comments ? comments.length : 0);
                 const commentText = commentCount === 1 ? 'Comment' : 'Comments';
 
                 const commentsHeader = document.querySelector('.comment-section h4');
@@ -335,12 +335,13 @@
                     document.getElementById('no-comments').classList.remove('d-none');
                     return;
                 }
+                document.getElementById('no-comments').classList.add('d-none');
 
                 commentsList.innerHTML = comments.map(comment => {
-                    // Check if current user is the comment author
-                    const isCommentAuthor = isLoggedIn && comment.isAuthor;
+                    // Check if current user is the comment author or a moderator
+                    const canEditComment = isLoggedIn && comment.canEdit;
 
-                    const actionsDropdown = isCommentAuthor ? `
+                    const actionsDropdown = canEditComment ? `
             <div class="dropdown">
                 <button class="btn btn-outline-light btn-sm dropdown-toggle" type="button" 
                         data-bs-toggle="dropdown" aria-expanded="false">
@@ -362,15 +363,29 @@
             </div>
         ` : '';
 
+                    // Replies are indented under their parent comment
+                    const indent = comment.depth ? `style="margin-left: ${comment.depth * 1.5}rem; border-left: 2px solid rgba(255, 255, 255, 0.15);"` : '';
+
+                    // Deleted comments stay as a placeholder above their replies
+                    if (comment.deleted) {
+                        return `
+            <div class="comment-item comment-deleted p-3" id="comment-${comment.id}" ${indent}>
+                <p class="text-muted fst-italic mb-0">[deleted]</p>
+            </div>
+        `;
+                    }
+
                     return `
-            <div class="comment-item p-3" id="comment-${comment.id}">
+            <div class="comment-item p-3" id="comment-${comment.id}" ${indent}>
                 <div class="d-flex justify-content-between align-items-start">
                     <div class="flex-grow-1">
                         <div class="d-flex align-items-center mb-2">
                             <strong class="text-white">${comment.author}</strong>
                             <small class="text-muted ms-2">${comment.timeAgo}</small>
+                            <a href="#" class="ms-1 edited-link ${comment.edited ? '' : 'd-none'}" id="comment-edited-${comment.id}"
+                                onclick="showRevisions('comment', ${comment.id}); return false;">(edited)</a>
                         </div>
-                        <p class="text-white mb-2" id="comment-content-${comment.id}">${comment.content.replace(/\n/g, '<br>')}</p>
+                        <div class="text-white mb-2 markdown-body" id="comment-content-${comment.id}">${comment.contentHtml}</div>
                         <div class="d-flex align-items-center gap-2">
                             <button class="btn btn-sm btn-outline-light vote-comment-btn ${comment.userVote === 1 ? 'active-like' : ''}" 
                                 onclick="voteComment(${comment.id}, 1)">
@@ -382,6 +397,16 @@
                                 <i class="bi bi-hand-thumbs-down"></i> 
                             </button>
                             <span class="vote-count like-count">${comment.dislikeCount || 0}</span>
+                            ${isLoggedIn ? `
+                            <button class="btn btn-sm btn-outline-light ms-2" title="Reply"
+                                onclick="replyToComment(${comment.id}, '${comment.author.replace(/'/g, "\\'")}')">
+                                <i class="bi bi-reply"></i> Reply
+                            </button>` : ''}
+                            ${isLoggedIn && !comment.isAuthor ? `
+                            <button class="btn btn-sm btn-outline-light ms-2" title="Report comment"
+                                onclick="reportContent('comment', ${comment.id})">
+                                <i class="bi bi-flag"></i>
+                            </button>` : ''}
                         </div>
                     </div>
                     ${actionsDropdown}
@@ -391,6 +416,19 @@
                 }).join('');
             }
 
+            // Reply to a comment: the next comment submitted is posted under it
+            function replyToComment(commentId, author) {
+                replyingToCommentId = commentId;
+                document.getElementById('reply-author').textContent = author;
+                document.getElementById('reply-indicator').classList.remove('d-none');
+                document.getElementById('comment-text').focus();
+            }
+
+            function cancelReply() {
+                replyingToCommentId = null;
+                document.getElementById('reply-indicator').classList.add('d-none');
+            }
+
             // Edit comment function
             function editComment(commentId, currentContent) {
                 currentEditingCommentId = commentId;
@@ -402,17 +440,19 @@
             // Edit comment API call
             async function editCommentAPI(commentId, content) {
                 try {
-                    const response = await fetch('/api/comments/edit', {
-                        method: 'POST',
+                    const response = await fetch(`/api/comments/${commentId}`, {
+                        method: 'PATCH',
                         headers: {
                             'Content-Type': 'application/x-www-form-urlencoded',
                         },
-                        body: `comment_id=${commentId}&content=${encodeURIComponent(content)}`
+                        body: `content=${encodeURIComponent(content)}`
                     });
 
                     if (response.ok) {
                         // Update the comment display
-                        document.getElementById(`comment-content-${commentId}`).innerHTML = content.replace(/\n/g, '<br>');
+                        const result = await response.json();
+                        document.getElementById(`comment-content-${commentId}`).innerHTML = result.contentHtml;
+                        document.getElementById(`comment-edited-${commentId}`).classList.remove('d-none');
                         showSuccessMessage('Comment updated successfully!');
                     } else {
                         showErrorMessage('Failed to edit comment');
@@ -434,23 +474,21 @@
                 // Delete comment API call
                     async function deleteCommentAPI(commentId) {
                         try {
-                            const response = await fetch('/api/comments/delete', {
-                                method: 'POST',
-                                headers: {
-                                    'Content-Type': 'application/x-www-form-urlencoded',
-                                },
-                                body: `comment_id=${commentId}`
+                            const response = await fetch(`/api/comments/${commentId}`, {
+                                method: 'DELETE'
                             });
 
                             if (response.ok) {
-                                // Remove comment from display
-                                document.getElementById(`comment-${commentId}`).remove();
+                                // Update comment count, unless the live stream does
+                                if (!isLive()) {
+                                    const currentCount = parseInt(document.getElementById('comment-count').textContent);
+                                    document.getElementById('comment-count').textContent = Math.max(0, currentCount - 1);
+                                }
 
-                                // Update comment count
-                                const currentCount = parseInt(document.getElementById('comment-count').textContent);
-                                document.getElementById('comment-count').textContent = Math.max(0, currentCount - 1);
+                                // Reload, as replies keep the comment as a placeholder
+                                loadComments();
 
-                                showSuccessMessage('Comment deleted successfully!');
+                                showSuccessMessage('Comment moved to the trash. You can restore it from your profile.');
                             } else {
                                 showErrorMessage('Failed to delete comment');
                             }
@@ -460,6 +498,32 @@
                         }
                     }
 
+                    // Report a post or comment to the moderators
+                    async function reportContent(targetType, targetId) {
+                        const reason = prompt('Why are you reporting this? (spam, abuse, misinformation or other)', 'spam');
+                        if (!reason) return;
+                        const details = prompt('Anything the moderators should know? (optional)', '') || '';
+
+                        try {
+                            const response = await fetch('/api/reports', {
+                                method: 'POST',
+                                headers: {
+                                    'Content-Type': 'application/x-www-form-urlencoded',
+                                },
+                                body: new URLSearchParams({ targetType, targetId, reason: reason.trim().toLowerCase(), details })
+                            });
+
+                            if (response.ok) {
+                                showSuccessMessage('Thanks, the moderators will take a look.');
+                            } else {
+                                showErrorMessage(await response.text());
+                            }
+                        } catch (error) {
+                            console.error('Error reporting content:', error);
+                            showErrorMessage('Error reporting content');
+                        }
+                    }
+
                      // Show success message
                         function showSuccessMessage(message) {
                             const alertDiv = document.createElement('div');
@@ -501,11 +565,13 @@
             try {
                 document.getElementById('comments-loading').classList.remove('d-none');
 
-                const response = await fetch(`/api/comments?post_id=${currentPostId}`);
-                const comments = await response.json();
+                const response = await fetch(`/api/posts/${currentPostId}/comments`);
+                const data = await response.json();
+                loadedComments = data.comments;
+                setCommentsCursor(data.nextCursor);
 
                 document.getElementById('comments-loading').classList.add('d-none');
-                displayComments(comments);
+                displayComments(loadedComments);
 
             } catch (error) {
                 console.error('Error loading comments:', error);
@@ -514,6 +580,99 @@
             }
         }
 
+        // Load the next page of comment threads
+        async function loadMoreComments() {
+            if (!commentsCursor) return;
+            try {
+                const response = await fetch(`/api/posts/${currentPostId}/comments?cursor=${encodeURIComponent(commentsCursor)}`);
+                const data = await response.json();
+                loadedComments = loadedComments.concat(data.comments);
+                setCommentsCursor(data.nextCursor);
+                displayComments(loadedComments);
+            } catch (error) {
+                console.error('Error loading more comments:', error);
+            }
+        }
+
+        function setCommentsCursor(cursor) {
+            commentsCursor = cursor || '';
+            document.getElementById('more-comments').classList.toggle('d-none', !commentsCursor);
+        }
+
+        // Follow new, edited and deleted comments and vote counts while the
+        // page is open; the browser reconnects the stream when it drops
+        function connectPostStream() {
+            postStream = new EventSource(`/api/posts/${currentPostId}/stream`);
+
+            postStream.addEventListener('comment-created', event => {
+                const { comment, commentCount } = JSON.parse(event.data);
+                document.getElementById('comment-count').textContent = commentCount;
+                if (loadedComments.some(c => c.id === comment.id)) return;
+
+                if (comment.parentId === null) {
+                    // New threads come last, after any pages not loaded yet
+                    if (commentsCursor) return;
+                    loadedComments.push(comment);
+                } else {
+                    // Replies come after everything already under their parent
+                    const parentIndex = loadedComments.findIndex(c => c.id === comment.parentId);
+                    if (parentIndex < 0) return;
+                    let i = parentIndex + 1;
+                    while (i < loadedComments.length && loadedComments[i].depth > loadedComments[parentIndex].depth) i++;
+                    loadedComments.splice(i, 0, comment);
+                }
+                displayComments(loadedComments);
+            });
+
+            postStream.addEventListener('comment-edited', event => {
+                const { comment } = JSON.parse(event.data);
+                const shown = loadedComments.find(c => c.id === comment.id);
+                if (!shown) return;
+                Object.assign(shown, {
+                    content: comment.content,
+                    contentHtml: comment.contentHtml,
+                    edited: comment.edited,
+                    editedAt: comment.editedAt
+                });
+                displayComments(loadedComments);
+            });
+
+            postStream.addEventListener('comment-deleted', event => {
+                const { id, commentCount } = JSON.parse(event.data);
+                document.getElementById('comment-count').textContent = commentCount;
+                // Reload, as replies keep the comment as a placeholder
+                if (loadedComments.some(c => c.id === id)) {
+                    loadComments();
+                }
+            });
+
+            postStream.addEventListener('post-votes', event => {
+                const votes = JSON.parse(event.data);
+                document.getElementById('like-count').textContent = votes.likeCount;
+                document.getElementById('dislike-count').textContent = votes.dislikeCount;
+                if (votes.userVote !== undefined) {
+                    updateVoteButtons(votes.userVote);
+                }
+            });
+
+            postStream.addEventListener('comment-votes', event => {
+                const votes = JSON.parse(event.data);
+                const shown = loadedComments.find(c => c.id === votes.commentId);
+                if (!shown) return;
+                shown.likeCount = votes.likeCount;
+                shown.dislikeCount = votes.dislikeCount;
+                if (votes.userVote !== undefined) {
+                    shown.userVote = votes.userVote;
+                }
+                displayComments(loadedComments);
+            });
+        }
+
+        // isLive reports whether the post's stream is keeping the page up to date
+        function isLive() {
+            return postStream !== null && postStream.readyState === EventSource.OPEN;
+        }
+
         // Vote on post
         async function votePost(vote) {
             if (!isLoggedIn) {
@@ -522,12 +681,12 @@
             }
 
             try {
-                const response = await fetch('/api/posts/like', {
+                const response = await fetch(`/api/posts/${currentPostId}/vote`, {
                     method: 'POST',
                     headers: {
                         'Content-Type': 'application/x-www-form-urlencoded',
                     },
-                    body: `post_id=${currentPostId}&vote=${vote}`
+                    body: `vote=${vote}`
                 });
 
                 if (!response.ok) {
@@ -557,16 +716,16 @@
             }
 
             try {
-                const response = await fetch('/api/comments/like', {
+                const response = await fetch(`/api/comments/${commentId}/vote`, {
                     method: 'POST',
                     headers: {
                         'Content-Type': 'application/x-www-form-urlencoded',
                     },
-                    body: `comment_id=${commentId}&vote=${vote}`
+                    body: `vote=${vote}`
                 });
 
                 const result = await response.json();
-                if (result.success) {
+                if (result.success && !isLive()) {
                     // Reload comments to update counts
                     loadComments();
                 }
@@ -576,6 +735,133 @@
             }
         }
 
+        // Show the edit history of the post or of a comment, newest first, with
+        // each version comparable to the one before it
+        async function showRevisions(type, id) {
+            const list = document.getElementById('revisions-list');
+            const diff = document.getElementById('revision-diff');
+            list.innerHTML = '';
+            diff.classList.add('d-none');
+
+            try {
+                const response = await fetch(`/api/${type}s/${id}/revisions`);
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || 'Failed to load the edit history');
+                }
+                const revisions = await response.json();
+
+                revisions.slice().reverse().forEach(revision => {
+                    const isCurrent = revision.number === revisions.length;
+                    const item = document.createElement('div');
+                    item.className = 'revision-item d-flex justify-content-between align-items-center py-2';
+                    item.innerHTML = `
+                        <div>
+                            <strong></strong>
+                            <small class="text-muted ms-2"></small>
+                        </div>
+                        <div class="d-flex gap-2"></div>
+                    `;
+                    item.querySelector('strong').textContent = revision.number === 1
+                        ? 'Original'
+                        : `Version ${revision.number}${isCurrent ? ' (current)' : ''}`;
+                    item.querySelector('small').textContent = `by ${revision.editor} • ${revision.timeAgo}`;
+
+                    const buttons = item.querySelector('.d-flex.gap-2');
+                    if (revision.number > 1) {
+                        const previous = revisions[revision.number - 2];
+                        const changes = document.createElement('button');
+                        changes.className = 'btn btn-outline-light btn-sm';
+                        changes.textContent = 'Changes';
+                        changes.addEventListener('click', () => showRevisionDiff(type, id, previous.id, revision.id));
+                        buttons.appendChild(changes);
+                    }
+                    if (currentPost && currentPost.canModerate && !isCurrent) {
+                        const revert = document.createElement('button');
+                        revert.className = 'btn btn-outline-warning btn-sm';
+                        revert.textContent = 'Revert';
+                        revert.addEventListener('click', () => revertRevision(type, id, revision.id));
+                        buttons.appendChild(revert);
+                    }
+                    list.appendChild(item);
+                });
+
+                bootstrap.Modal.getOrCreateInstance(document.getElementById('revisionsModal')).show();
+            } catch (error) {
+                console.error('Error loading revisions:', error);
+                showErrorMessage(error.message);
+            }
+        }
+
+        // Show what changed between two versions, line by line
+        async function showRevisionDiff(type, id, from, to) {
+            const diff = document.getElementById('revision-diff');
+            try {
+                const response = await fetch(`/api/${type}s/${id}/revisions/diff?from=${from}&to=${to}`);
+                if (!response.ok) {
+                    throw new Error((await response.text()).trim() || 'Failed to compare versions');
+                }
+                const data = await response.json();
+
+                diff.innerHTML = `<h6 class="text-white">Version ${data.from.number} → Version ${data.to.number}</h6>`;
+                const sections = type === 'post' ? [['Title', data.title], ['Content', data.content]] : [['Content', data.content]];
+                sections.forEach(([label, lines]) => {
+                    const heading = document.createElement('div');
+                    heading.className = 'text-white small mt-2 mb-1';
+                    heading.textContent = label;
+                    const block = document.createElement('div');
+                    block.className = 'revision-diff p-2';
+                    lines.forEach(line => {
+                        const row = document.createElement('div');
+                        row.className = `diff-${line.op}`;
+                        row.textContent = (line.op === 'insert' ? '+ ' : line.op === 'delete' ? '- ' : '  ') + line.text;
+                        block.appendChild(row);
+                    });
+                    diff.append(heading, block);
+                });
+
+                const changes = [
+                    ...data.categoriesAdded.map(name => `+ ${name}`),
+                    ...data.categoriesRemoved.map(name => `- ${name}`),
+                ];
+                if (changes.length > 0) {
+                    const categories = document.createElement('div');
+                    categories.className = 'text-white small mt-2';
+                    categories.textContent = `Categories: ${changes.join(', ')}`;
+                    diff.appendChild(categories);
+                }
+                diff.classList.remove('d-none');
+            } catch (error) {
+                console.error('Error comparing revisions:', error);
+                showErrorMessage(error.message);
+            }
+        }
+
+        // Restore an earlier version (moderators only); the page reloads to show it
+        async function revertRevision(type, id, revisionId) {
+            if (!confirm('Restore this version? It will be saved as a new edit.')) return;
+            try {
+                const response = await fetch(`/api/${type}s/${id}/revisions/${revisionId}/revert`, { method: 'POST' });
+                if (response.ok) {
+                    window.location.reload();
+                } else {
+                    showErrorMessage((await response.text()).trim() || 'Failed to revert');
+                }
+            } catch (error) {
+                console.error('Error reverting:', error);
+                showErrorMessage('Error reverting');
+            }
+        }
+
+        // Show a post's categories, then its tags linking to their tag pages
+        function renderPostLabels(post) {
+            const container = document.getElementById('post-tags');
+            container.innerHTML =
+                (post.categories || []).map(category => `<span class="tag-badge">${category}</span>`).join('') +
+                (post.tags || []).map(tag =>
+                    `<a href="/tags/${encodeURIComponent(tag)}" class="tag-badge user-tag">#${tag}</a>`
+                ).join('');
+        }
+
         // Update vote button states
         function updateVoteButtons(userVote) {
             const likeBtn = document.getElementById('like-btn');
@@ -601,22 +887,26 @@
             if (!commentText) return;
 
             try {
-                const response = await fetch('/api/comments/create', {
+                const response = await fetch(`/api/posts/${currentPostId}/comments`, {
                     method: 'POST',
                     headers: {
                         'Content-Type': 'application/x-www-form-urlencoded',
                     },
-                    body: `post_id=${currentPostId}&content=${encodeURIComponent(commentText)}`
+                    body: `content=${encodeURIComponent(commentText)}` +
+                        (replyingToCommentId ? `&parent_comment_id=${replyingToCommentId}` : '')
                 });
 
                 const result = await response.json();
                 if (result.success) {
                     document.getElementById('comment-text').value = '';
+                    cancelReply();
                     loadComments();
 
-                    // Update comment count
-                    const currentCount = parseInt(document.getElementById('comment-count').textContent);
-                    document.getElementById('comment-count').textContent = currentCount + 1;
+                    // Update comment count, unless the live stream does
+                    if (!isLive()) {
+                        const currentCount = parseInt(document.getElementById('comment-count').textContent);
+                        document.getElementById('comment-count').textContent = currentCount + 1;
+                    }
                 }
 
             } catch (error) {
@@ -666,10 +956,13 @@
                         document.getElementById('comment-form-container').classList.remove('d-none');
                         document.getElementById('login-prompt').classList.add('d-none');
 
-                        // Add post action buttons if user is the author
-                        if (currentPost && currentPost.isAuthor) {
+                        // Add post action buttons if user is the author or a moderator
+                        if (currentPost && currentPost.canEdit) {
                             addPostActionButtons();
                         }
+                        if (currentPost && !currentPost.isAuthor) {
+                            document.getElementById('report-btn').classList.remove('d-none');
+                        }
                     } else {
                         document.getElementById('login-prompt').classList.remove('d-none');
                         document.getElementById('comment-form-container').classList.add('d-none');
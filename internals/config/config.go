@@ -0,0 +1,392 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every setting the forum needs at runtime.
+//
+// Values are resolved in this order, later sources overriding earlier ones:
+// built-in defaults, the optional JSON config file, environment variables
+// and finally command-line flags.
+type Config struct {
+	Port    string `json:"port"`
+	DBPath  string `json:"dbPath"`
+	BaseURL string `json:"baseUrl"`
+
+	DBPool DBPool `json:"dbPool"`
+
+	GitHub OAuthClient `json:"github"`
+	Google OAuthClient `json:"google"`
+	SMTP   SMTP        `json:"smtp"`
+
+	RateLimits RateLimits `json:"rateLimits"`
+
+	Comments Comments `json:"comments"`
+	Tags     Tags     `json:"tags"`
+	Trash    Trash    `json:"trash"`
+	Live     Live     `json:"live"`
+
+	// File is the config file that was loaded, if any
+	File string `json:"-"`
+}
+
+// DBPool controls the shared database connection pool
+type DBPool struct {
+	MaxOpenConns    int      `json:"maxOpenConns"`
+	MaxIdleConns    int      `json:"maxIdleConns"`
+	ConnMaxLifetime Duration `json:"connMaxLifetime"`
+	BusyTimeout     Duration `json:"busyTimeout"`
+}
+
+// Duration is a time.Duration written as "30s" or "5m" in config files
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// OAuthClient holds the credentials of an OAuth application
+type OAuthClient struct {
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+}
+
+// Enabled reports whether the provider has been configured
+func (o OAuthClient) Enabled() bool {
+	return o.ClientID != "" && o.ClientSecret != ""
+}
+
+// SMTP holds the mail server used for password reset emails
+type SMTP struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+// Enabled reports whether outgoing email has been configured
+func (s SMTP) Enabled() bool {
+	return s.Host != ""
+}
+
+// RateLimits throttles the auth and write endpoints per client IP and per account
+type RateLimits struct {
+	// Backend is "sqlite", so limits survive restarts, or "memory"
+	Backend string `json:"backend"`
+	// Routes holds the limit of each throttled route by name; routes left
+	// out of a config file keep their defaults
+	Routes map[string]RateLimit `json:"routes"`
+}
+
+// RateLimit lets a client make Requests requests at once and then refills
+// them evenly over Window; zero requests turns the limit off
+type RateLimit struct {
+	Requests int      `json:"requests"`
+	Window   Duration `json:"window"`
+}
+
+// Comments shapes comment threads
+type Comments struct {
+	// MaxDepth is how deeply replies nest; a reply to a comment at the
+	// limit is attached to that comment's parent instead
+	MaxDepth int `json:"maxDepth"`
+}
+
+// Tags limits the free-form tags of posts
+type Tags struct {
+	MaxPerPost int `json:"maxPerPost"`
+}
+
+// Trash keeps deleted posts and comments restorable for a while
+type Trash struct {
+	// Retention is how long deleted content can be restored before the
+	// purge job removes it for good
+	Retention Duration `json:"retention"`
+	// PurgeInterval is how often the purge job runs
+	PurgeInterval Duration `json:"purgeInterval"`
+}
+
+// Live limits the live updates streamed to open pages
+type Live struct {
+	// MaxPostViewers is how many pages can follow one post at once; zero
+	// turns live updates off
+	MaxPostViewers int `json:"maxPostViewers"`
+	// MaxUserStreams is how many notification streams, one per open page,
+	// a user can have at once
+	MaxUserStreams int `json:"maxUserStreams"`
+}
+
+// Default returns the configuration used when nothing else is provided
+func Default() *Config {
+	return &Config{
+		Port:   "8080",
+		DBPath: "./forum.db",
+		DBPool: DBPool{
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: Duration(30 * time.Minute),
+			BusyTimeout:     Duration(5 * time.Second),
+		},
+		SMTP: SMTP{
+			Port: "587",
+		},
+		RateLimits: RateLimits{
+			Backend: "sqlite",
+			Routes: map[string]RateLimit{
+				"login":           {Requests: 10, Window: Duration(15 * time.Minute)},
+				"register":        {Requests: 5, Window: Duration(time.Hour)},
+				"forgot-password": {Requests: 3, Window: Duration(time.Hour)},
+				"reset-password":  {Requests: 10, Window: Duration(time.Hour)},
+				"post":            {Requests: 5, Window: Duration(10 * time.Minute)},
+				"draft":           {Requests: 30, Window: Duration(time.Minute)},
+				"preview":         {Requests: 60, Window: Duration(time.Minute)},
+				"comment":         {Requests: 10, Window: Duration(time.Minute)},
+				"vote":            {Requests: 60, Window: Duration(time.Minute)},
+				"report":          {Requests: 10, Window: Duration(time.Hour)},
+			},
+		},
+		Comments: Comments{
+			MaxDepth: 4,
+		},
+		Tags: Tags{
+			MaxPerPost: 5,
+		},
+		Trash: Trash{
+			Retention:     Duration(30 * 24 * time.Hour),
+			PurgeInterval: Duration(time.Hour),
+		},
+		Live: Live{
+			MaxPostViewers: 100,
+			MaxUserStreams: 10,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, config file, environment and
+// the given command-line arguments, then validates the result.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("forum", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a JSON config file")
+	port := fs.String("port", "", "port to listen on (env PORT)")
+	dbPath := fs.String("db", "", "path to the SQLite database (env DB_PATH)")
+	baseURL := fs.String("base-url", "", "public base URL of the forum (env BASE_URL)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	// Only flags given explicitly override the other sources
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "port":
+			cfg.Port = *port
+		case "db":
+			cfg.DBPath = *dbPath
+		case "base-url":
+			cfg.BaseURL = *baseURL
+		}
+	})
+
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://localhost:" + cfg.Port
+	}
+	cfg.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overlays the values found in a JSON config file
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	c.File = path
+	return nil
+}
+
+// loadEnv overlays the values found in environment variables
+func (c *Config) loadEnv() error {
+	setFromEnv(&c.Port, "PORT")
+	setFromEnv(&c.DBPath, "DB_PATH")
+	setFromEnv(&c.BaseURL, "BASE_URL")
+
+	var errs []error
+	errs = append(errs, setIntFromEnv(&c.DBPool.MaxOpenConns, "DB_MAX_OPEN_CONNS"))
+	errs = append(errs, setIntFromEnv(&c.DBPool.MaxIdleConns, "DB_MAX_IDLE_CONNS"))
+	errs = append(errs, setDurationFromEnv(&c.DBPool.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME"))
+	errs = append(errs, setDurationFromEnv(&c.DBPool.BusyTimeout, "DB_BUSY_TIMEOUT"))
+
+	setFromEnv(&c.GitHub.ClientID, "GITHUB_CLIENT_ID")
+	setFromEnv(&c.GitHub.ClientSecret, "GITHUB_CLIENT_SECRET")
+	setFromEnv(&c.Google.ClientID, "GOOGLE_CLIENT_ID")
+	setFromEnv(&c.Google.ClientSecret, "GOOGLE_CLIENT_SECRET")
+
+	setFromEnv(&c.SMTP.Host, "SMTP_HOST")
+	setFromEnv(&c.SMTP.Port, "SMTP_PORT")
+	setFromEnv(&c.SMTP.Username, "SMTP_USERNAME")
+	setFromEnv(&c.SMTP.Password, "SMTP_PASSWORD")
+	setFromEnv(&c.SMTP.From, "SMTP_FROM")
+
+	setFromEnv(&c.RateLimits.Backend, "RATE_LIMIT_BACKEND")
+
+	errs = append(errs, setIntFromEnv(&c.Comments.MaxDepth, "COMMENT_MAX_DEPTH"))
+	errs = append(errs, setIntFromEnv(&c.Tags.MaxPerPost, "TAGS_MAX_PER_POST"))
+	errs = append(errs, setDurationFromEnv(&c.Trash.Retention, "TRASH_RETENTION"))
+	errs = append(errs, setDurationFromEnv(&c.Trash.PurgeInterval, "TRASH_PURGE_INTERVAL"))
+	errs = append(errs, setIntFromEnv(&c.Live.MaxPostViewers, "LIVE_MAX_POST_VIEWERS"))
+	errs = append(errs, setIntFromEnv(&c.Live.MaxUserStreams, "LIVE_MAX_USER_STREAMS"))
+
+	return errors.Join(errs...)
+}
+
+func setFromEnv(dst *string, key string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = strings.TrimSpace(v)
+	}
+}
+
+func setIntFromEnv(dst *int, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a number", key, v)
+	}
+	*dst = n
+	return nil
+}
+
+func setDurationFromEnv(dst *Duration, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	if err != nil {
+		return fmt.Errorf("%s: %q is not a duration", key, v)
+	}
+	*dst = Duration(d)
+	return nil
+}
+
+// Validate checks that the configuration is usable before the server starts
+func (c *Config) Validate() error {
+	var errs []error
+
+	if n, err := strconv.Atoi(c.Port); err != nil || n < 1 || n > 65535 {
+		errs = append(errs, fmt.Errorf("port %q is not a valid TCP port", c.Port))
+	}
+
+	if c.DBPath == "" {
+		errs = append(errs, errors.New("database path must not be empty"))
+	}
+
+	if c.DBPool.MaxOpenConns < 1 {
+		errs = append(errs, errors.New("database pool must allow at least one open connection"))
+	}
+	if c.DBPool.MaxIdleConns < 0 || c.DBPool.MaxIdleConns > c.DBPool.MaxOpenConns {
+		errs = append(errs, errors.New("database pool idle connections must be between 0 and the open connection limit"))
+	}
+	if c.DBPool.ConnMaxLifetime < 0 || c.DBPool.BusyTimeout < 0 {
+		errs = append(errs, errors.New("database pool durations must not be negative"))
+	}
+
+	if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("base URL %q must be an absolute http(s) URL", c.BaseURL))
+	}
+
+	if (c.GitHub.ClientID == "") != (c.GitHub.ClientSecret == "") {
+		errs = append(errs, errors.New("github: client ID and client secret must be set together"))
+	}
+	if (c.Google.ClientID == "") != (c.Google.ClientSecret == "") {
+		errs = append(errs, errors.New("google: client ID and client secret must be set together"))
+	}
+
+	if c.SMTP.Enabled() {
+		if _, err := strconv.Atoi(c.SMTP.Port); err != nil {
+			errs = append(errs, fmt.Errorf("smtp: port %q is not a number", c.SMTP.Port))
+		}
+		if c.SMTP.From == "" {
+			errs = append(errs, errors.New("smtp: from address is required when a host is set"))
+		}
+	}
+
+	if c.RateLimits.Backend != "sqlite" && c.RateLimits.Backend != "memory" {
+		errs = append(errs, fmt.Errorf("rate limits: backend %q must be \"sqlite\" or \"memory\"", c.RateLimits.Backend))
+	}
+	for name, limit := range c.RateLimits.Routes {
+		if limit.Requests < 0 || (limit.Requests > 0 && limit.Window <= 0) {
+			errs = append(errs, fmt.Errorf("rate limits: %s needs a positive window and a non-negative number of requests", name))
+		}
+	}
+
+	if c.Comments.MaxDepth < 1 {
+		errs = append(errs, errors.New("comments: max depth must be at least 1"))
+	}
+	if c.Tags.MaxPerPost < 0 {
+		errs = append(errs, errors.New("tags: max per post must not be negative"))
+	}
+	if c.Trash.Retention <= 0 || c.Trash.PurgeInterval <= 0 {
+		errs = append(errs, errors.New("trash: retention and purge interval must be positive"))
+	}
+	if c.Live.MaxPostViewers < 0 {
+		errs = append(errs, errors.New("live: max post viewers must not be negative"))
+	}
+	if c.Live.MaxUserStreams < 1 {
+		errs = append(errs, errors.New("live: max user streams must be at least 1"))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// Addr returns the address the HTTP server listens on
+func (c *Config) Addr() string {
+	return ":" + c.Port
+}
+
+// URL returns an absolute URL for the given path on the public site
+func (c *Config) URL(path string) string {
+	return c.BaseURL + path
+}
@@ -3,20 +3,32 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"os"
+	"forum/internals/config"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var dbPath = "./forum.db"
+// Open connects to the SQLite database at path and returns the shared connection pool.
+// The pragmas are passed in the DSN so every connection in the pool gets them.
+func Open(path string, pool config.DBPool) (*sql.DB, error) {
+	dsn := fmt.Sprintf("%s?_foreign_keys=on&_busy_timeout=%d&_journal_mode=WAL&_synchronous=NORMAL",
+		path, time.Duration(pool.BusyTimeout).Milliseconds())
 
-// Connect with SQLite DB
-func CreateTable() *sql.DB {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	return db
+
+	db.SetMaxOpenConns(pool.MaxOpenConns)
+	db.SetMaxIdleConns(pool.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(pool.ConnMaxLifetime))
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening database %s: %w", path, err)
+	}
+	return db, nil
 }
 
 // imports the database schema from a file
@@ -35,53 +47,11 @@ func Insert(db *sql.DB, table string, columns string, values ...any) {
 	}
 }
 
-// InitializeDatabase sets up the database schema and default data
-func InitializeDatabase() {
-	db := CreateTable()
-	defer db.Close()
-
-	// Read and execute SQL schema
-	sqlContent, err := os.ReadFile("internals/database/table.sql")
-	if err != nil {
-		fmt.Printf("Warning: Could not read table.sql: %v\n", err)
-		return
-	}
-
-	// Makes reads/writes not block each other
-	db.Exec("PRAGMA journal_mode=WAL;")
-	// Waits a bit before returning 'database is locked
-	db.Exec("PRAGMA busy_timeout=5000;")
-	// Good balance of performance/safety for WAL
-	db.Exec("PRAGMA synchronous=NORMAL;")
-
-	// Execute SQL commands
-	_, err = db.Exec(string(sqlContent))
-	if err != nil {
-		fmt.Printf("Warning: Error executing SQL schema: %v\n", err)
-	}
-
-	// Insert default categories if they don't exist
-	insertDefaultCategories(db)
-}
-
-// insertDefaultCategories adds the default forum categories
-func insertDefaultCategories(db *sql.DB) {
-	categories := []string{
-		"Succulents",
-		"Tropical Plants",
-		"Herb Garden",
-		"Indoor Plants",
-		"Plant Care Tips",
-		"Plant Diseases",
-		"Propagation",
-		"Flowering Plants",
-	}
-
-	for _, category := range categories {
-		var exists int
-		db.QueryRow("SELECT COUNT(*) FROM Categories WHERE name = ?", category).Scan(&exists)
-		if exists == 0 {
-			Insert(db, "Categories", "(name)", category)
-		}
+// InitializeDatabase brings the schema up to date
+func InitializeDatabase(db *sql.DB) error {
+	applied, err := Migrate(db)
+	for _, m := range applied {
+		fmt.Printf("Applied migration %04d_%s\n", m.Version, m.Name)
 	}
+	return err
 }
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration files are named NNNN_description.sql and applied in version order.
+// A migration must never be edited once released; add a new file instead.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a single numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationState pairs a migration with the time it was applied, if it was
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Migrations returns every embedded migration sorted by version
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		filename := entry.Name()
+		versionPart, name, ok := strings.Cut(strings.TrimSuffix(filename, ".sql"), "_")
+		version, err := strconv.Atoi(versionPart)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: file name must look like 0001_description.sql", filename)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, filename, version)
+		}
+		seen[version] = filename
+
+		content, err := migrationFiles.ReadFile("migrations/" + filename)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrationStatus reports which migrations have been applied to the database
+func MigrationStatus(db *sql.DB) ([]MigrationState, error) {
+	if _, err := db.Exec(createMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]time.Time)
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i].Migration = m
+		if at, ok := applied[m.Version]; ok {
+			states[i].AppliedAt = &at
+		}
+	}
+	return states, nil
+}
+
+// Migrate applies every pending migration, each one in its own transaction,
+// and returns the migrations that were applied.
+func Migrate(db *sql.DB) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, state := range states {
+		if state.AppliedAt != nil {
+			continue
+		}
+		if err := applyMigration(db, state.Migration); err != nil {
+			return applied, err
+		}
+		applied = append(applied, state.Migration)
+	}
+	return applied, nil
+}
+
+// applyMigration runs one migration and records it atomically
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+		return fmt.Errorf("recording migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,4 @@
+-- Comments can reply to another comment on the same post
+ALTER TABLE Comments ADD COLUMN parent_comment_id INTEGER REFERENCES Comments(comment_id);
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON Comments(parent_comment_id);
@@ -0,0 +1,5 @@
+-- Each session carries its own CSRF token; sessions created before this
+-- migration have none and are simply logged out
+ALTER TABLE Sessions ADD COLUMN csrf_token TEXT NOT NULL DEFAULT '';
+
+DELETE FROM Sessions WHERE csrf_token = '';
@@ -0,0 +1,10 @@
+-- Token buckets of the rate limiter, so limits survive restarts. Times are
+-- Unix milliseconds; a bucket past full_at has refilled and can be dropped
+CREATE TABLE IF NOT EXISTS RateLimits (
+    bucket_key TEXT PRIMARY KEY,
+    tokens REAL NOT NULL,
+    updated_at INTEGER NOT NULL,
+    full_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_full_at ON RateLimits(full_at);
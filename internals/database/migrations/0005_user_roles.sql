@@ -0,0 +1,17 @@
+-- Every account is a member; moderators look after posts and comments,
+-- admins also manage users and categories
+ALTER TABLE Users ADD COLUMN role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'moderator', 'admin'));
+
+-- ModerationLog records each moderator or admin action and who performed it
+CREATE TABLE IF NOT EXISTS ModerationLog (
+    log_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_id INTEGER NOT NULL,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id INTEGER NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (actor_id) REFERENCES Users(user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_log_date ON ModerationLog(creation_date);
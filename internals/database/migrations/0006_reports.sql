@@ -0,0 +1,25 @@
+-- Moderators can hide posts and comments instead of deleting them
+ALTER TABLE Posts ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE Comments ADD COLUMN hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Reports Table: posts, comments and users flagged for the moderators
+CREATE TABLE IF NOT EXISTS Reports (
+    report_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    reporter_id INTEGER NOT NULL,
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'user')),
+    target_id INTEGER NOT NULL,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'abuse', 'misinformation', 'other')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    resolution TEXT CHECK (resolution IN ('dismiss', 'hide', 'delete', 'warn')),
+    resolved_by INTEGER,
+    resolved_at TIMESTAMP,
+    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (reporter_id) REFERENCES Users(user_id),
+    FOREIGN KEY (resolved_by) REFERENCES Users(user_id)
+);
+
+-- A user can only have one open report on the same content
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reports_open_unique ON Reports(reporter_id, target_type, target_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_reports_status_date ON Reports(status, creation_date);
+CREATE INDEX IF NOT EXISTS idx_reports_target ON Reports(target_type, target_id);
@@ -0,0 +1,45 @@
+-- Full-text search over posts and comments. FTS5 must be compiled into
+-- SQLite, which go-sqlite3 does with the sqlite_fts5 build tag.
+-- The indexes only store the search terms and read the text back from
+-- Posts and Comments, and triggers keep them in sync.
+CREATE VIRTUAL TABLE IF NOT EXISTS PostsSearch USING fts5(
+    title, content,
+    content = 'Posts', content_rowid = 'post_id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS CommentsSearch USING fts5(
+    content,
+    content = 'Comments', content_rowid = 'comment_id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS posts_search_insert AFTER INSERT ON Posts BEGIN
+    INSERT INTO PostsSearch (rowid, title, content) VALUES (new.post_id, new.title, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_search_delete AFTER DELETE ON Posts BEGIN
+    INSERT INTO PostsSearch (PostsSearch, rowid, title, content) VALUES ('delete', old.post_id, old.title, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS posts_search_update AFTER UPDATE OF title, content ON Posts BEGIN
+    INSERT INTO PostsSearch (PostsSearch, rowid, title, content) VALUES ('delete', old.post_id, old.title, old.content);
+    INSERT INTO PostsSearch (rowid, title, content) VALUES (new.post_id, new.title, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_search_insert AFTER INSERT ON Comments BEGIN
+    INSERT INTO CommentsSearch (rowid, content) VALUES (new.comment_id, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_search_delete AFTER DELETE ON Comments BEGIN
+    INSERT INTO CommentsSearch (CommentsSearch, rowid, content) VALUES ('delete', old.comment_id, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS comments_search_update AFTER UPDATE OF content ON Comments BEGIN
+    INSERT INTO CommentsSearch (CommentsSearch, rowid, content) VALUES ('delete', old.comment_id, old.content);
+    INSERT INTO CommentsSearch (rowid, content) VALUES (new.comment_id, new.content);
+END;
+
+-- Index the posts and comments written before search existed
+INSERT INTO PostsSearch (PostsSearch) VALUES ('rebuild');
+INSERT INTO CommentsSearch (CommentsSearch) VALUES ('rebuild');
@@ -0,0 +1,34 @@
+-- Categories are managed from the admin API instead of lists in the code,
+-- so everything the pages show about them lives in the table
+ALTER TABLE Categories ADD COLUMN description TEXT NOT NULL DEFAULT '';
+ALTER TABLE Categories ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+ALTER TABLE Categories ADD COLUMN color TEXT NOT NULL DEFAULT '';
+ALTER TABLE Categories ADD COLUMN icon TEXT NOT NULL DEFAULT '';
+ALTER TABLE Categories ADD COLUMN sort_order INTEGER NOT NULL DEFAULT 0;
+-- Archived categories stay on their posts but take no new ones
+ALTER TABLE Categories ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Existing categories keep the order they were created in
+UPDATE Categories SET
+    slug = lower(replace(trim(name), ' ', '-')),
+    sort_order = category_id;
+
+-- Names differing only in case would share a slug
+UPDATE Categories SET slug = slug || '-' || category_id
+WHERE EXISTS (
+    SELECT 1 FROM Categories other
+    WHERE other.slug = Categories.slug AND other.category_id < Categories.category_id
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_slug ON Categories(slug);
+CREATE INDEX IF NOT EXISTS idx_categories_sort ON Categories(archived, sort_order);
+
+-- Details of the starter categories
+UPDATE Categories SET description = 'Low-maintenance plants perfect for beginners', color = '#6dd628', icon = 'flower1' WHERE name = 'Succulents';
+UPDATE Categories SET description = 'Exotic plants that bring the tropics indoors', color = '#20c997', icon = 'tree' WHERE name = 'Tropical Plants';
+UPDATE Categories SET description = 'Edible plants for cooking and natural remedies', color = '#198754', icon = 'basket' WHERE name = 'Herb Garden';
+UPDATE Categories SET description = 'Plants that thrive in indoor environments', color = '#0dcaf0', icon = 'house' WHERE name = 'Indoor Plants';
+UPDATE Categories SET description = 'General advice and tips for plant care', color = '#ffc107', icon = 'lightbulb' WHERE name = 'Plant Care Tips';
+UPDATE Categories SET description = 'Help with identifying and treating plant problems', color = '#dc3545', icon = 'bandaid' WHERE name = 'Plant Diseases';
+UPDATE Categories SET description = 'Growing new plants from existing ones', color = '#fd7e14', icon = 'scissors' WHERE name = 'Propagation';
+UPDATE Categories SET description = 'Plants known for their beautiful blooms', color = '#d63384', icon = 'flower2' WHERE name = 'Flowering Plants';
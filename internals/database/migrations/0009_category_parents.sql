@@ -0,0 +1,5 @@
+-- Categories can sit inside another one, e.g. Monstera inside Tropical Plants;
+-- top-level categories have no parent
+ALTER TABLE Categories ADD COLUMN parent_id INTEGER REFERENCES Categories(category_id);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON Categories(parent_id);
@@ -0,0 +1,26 @@
+-- Tags Table: free-form tags writers add to their posts, next to the fixed
+-- categories; names are normalized to lowercase words joined by dashes
+CREATE TABLE IF NOT EXISTS Tags (
+    tag_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- PostTags Table: connects posts to their tags (many-to-many)
+CREATE TABLE IF NOT EXISTS PostTags (
+    post_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (post_id, tag_id),
+    FOREIGN KEY (post_id) REFERENCES Posts(post_id),
+    FOREIGN KEY (tag_id) REFERENCES Tags(tag_id)
+);
+
+-- TagAliases Table: names of tags merged into another one by a moderator;
+-- posts tagged with an alias get the tag it points to
+CREATE TABLE IF NOT EXISTS TagAliases (
+    alias TEXT PRIMARY KEY,
+    tag_id INTEGER NOT NULL,
+    FOREIGN KEY (tag_id) REFERENCES Tags(tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_tag_id ON PostTags(tag_id);
@@ -0,0 +1,25 @@
+-- Drafts Table: posts a writer has started but not published yet, saved as
+-- they type; tags are kept as typed and only checked when publishing
+CREATE TABLE IF NOT EXISTS Drafts (
+    draft_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL DEFAULT '',
+    image_id INTEGER,
+    tags TEXT NOT NULL DEFAULT '',
+    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (image_id) REFERENCES Images(image_id)
+);
+
+-- DraftCategories Table: the categories picked for a draft so far
+CREATE TABLE IF NOT EXISTS DraftCategories (
+    draft_id INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    PRIMARY KEY (draft_id, category_id),
+    FOREIGN KEY (draft_id) REFERENCES Drafts(draft_id),
+    FOREIGN KEY (category_id) REFERENCES Categories(category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_drafts_user_id ON Drafts(user_id, updated_at);
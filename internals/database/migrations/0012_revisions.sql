@@ -0,0 +1,31 @@
+-- Posts and comments remember when they were last edited
+ALTER TABLE Posts ADD COLUMN edited_at TIMESTAMP;
+ALTER TABLE Comments ADD COLUMN edited_at TIMESTAMP;
+
+-- PostRevisions Table: every version of an edited post, starting with the
+-- original; categories holds the category names as a JSON array
+CREATE TABLE IF NOT EXISTS PostRevisions (
+    revision_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    post_id INTEGER NOT NULL,
+    editor_id INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    categories TEXT NOT NULL DEFAULT '[]',
+    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES Posts(post_id),
+    FOREIGN KEY (editor_id) REFERENCES Users(user_id)
+);
+
+-- CommentRevisions Table: every version of an edited comment, starting with the original
+CREATE TABLE IF NOT EXISTS CommentRevisions (
+    revision_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    comment_id INTEGER NOT NULL,
+    editor_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (comment_id) REFERENCES Comments(comment_id),
+    FOREIGN KEY (editor_id) REFERENCES Users(user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_revisions_post_id ON PostRevisions(post_id);
+CREATE INDEX IF NOT EXISTS idx_comment_revisions_comment_id ON CommentRevisions(comment_id);
@@ -0,0 +1,9 @@
+-- Deleted posts and comments stay in the trash, restorable by their author,
+-- until the purge job removes them for good
+ALTER TABLE Posts ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE Posts ADD COLUMN deleted_by INTEGER REFERENCES Users(user_id);
+ALTER TABLE Comments ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE Comments ADD COLUMN deleted_by INTEGER REFERENCES Users(user_id);
+
+CREATE INDEX IF NOT EXISTS idx_posts_deleted_at ON Posts(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_comments_deleted_at ON Comments(deleted_at);
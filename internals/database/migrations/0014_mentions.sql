@@ -0,0 +1,44 @@
+-- @mentions notify the users named in posts and comments. SQLite cannot
+-- change a CHECK constraint in place, so Notifications is rebuilt with the
+-- new 'mention' type.
+CREATE TABLE Notifications_new (
+    notification_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    type TEXT NOT NULL CHECK (type IN ('like', 'dislike', 'comment', 'mention', 'system')),
+    title TEXT NOT NULL,
+    message TEXT NOT NULL,
+    related_post_id INTEGER,
+    related_comment_id INTEGER,
+    related_user_id INTEGER,
+    is_read BOOLEAN DEFAULT FALSE,
+    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES Users(user_id),
+    FOREIGN KEY (related_post_id) REFERENCES Posts(post_id),
+    FOREIGN KEY (related_comment_id) REFERENCES Comments(comment_id),
+    FOREIGN KEY (related_user_id) REFERENCES Users(user_id)
+);
+
+INSERT INTO Notifications_new (notification_id, user_id, type, title, message, related_post_id,
+    related_comment_id, related_user_id, is_read, creation_date)
+SELECT notification_id, user_id, type, title, message, related_post_id,
+    related_comment_id, related_user_id, is_read, creation_date
+FROM Notifications;
+
+DROP TABLE Notifications;
+ALTER TABLE Notifications_new RENAME TO Notifications;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_read ON Notifications(user_id, is_read, creation_date DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_type ON Notifications(type);
+CREATE INDEX IF NOT EXISTS idx_notifications_related_post ON Notifications(related_post_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_related_user ON Notifications(related_user_id);
+
+-- Mentions records who has been mentioned in each post and comment, so an
+-- edit only notifies the users it adds
+CREATE TABLE IF NOT EXISTS Mentions (
+    target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment')),
+    target_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    creation_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (target_type, target_id, user_id),
+    FOREIGN KEY (user_id) REFERENCES Users(user_id)
+);
@@ -0,0 +1,10 @@
+-- Search results mark the matched terms with the \x02 and \x03 control
+-- characters, which posts and comments are now stored without. Remove them
+-- from the text written before, so no stray mark ends up in a result.
+UPDATE Posts SET
+    title = replace(replace(title, char(2), ''), char(3), ''),
+    content = replace(replace(content, char(2), ''), char(3), '')
+WHERE instr(title, char(2)) OR instr(title, char(3)) OR instr(content, char(2)) OR instr(content, char(3));
+
+UPDATE Comments SET content = replace(replace(content, char(2), ''), char(3), '')
+WHERE instr(content, char(2)) OR instr(content, char(3));
@@ -2,49 +2,79 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
 )
 
+// Scanner is implemented by both *sql.Rows and *sql.Row
+type Scanner interface {
+	Scan(dest ...any) error
+}
+
 type Table interface {
-	ScanRows(rows *sql.Rows) error
+	ScanRows(rows Scanner) error
 }
 
 // User structure
-func (u *User) ScanRows(rows *sql.Rows) error {
-	return rows.Scan(&u.UserID, &u.Username, &u.Email, &u.PasswordHash, &u.RegistrationDate, &u.ResetToken)
+func (u *User) ScanRows(rows Scanner) error {
+	return rows.Scan(&u.UserID, &u.Username, &u.Email, &u.PasswordHash, &u.RegistrationDate, &u.ResetToken, &u.Role)
 }
 
-// Post structure
-func (p *Post) ScanRows(rows *sql.Rows) error {
-	return rows.Scan(&p.PostID, &p.UserID, &p.Title, &p.PhotoURL, &p.Content, &p.CreationDate)
+// Post structure, with author, image and counters joined in
+func (p *Post) ScanRows(rows Scanner) error {
+	var imageURL, thumbnailURL sql.NullString
+	var editedAt, deletedAt sql.NullTime
+	var deletedBy sql.NullInt64
+	err := rows.Scan(&p.PostID, &p.UserID, &p.Username, &p.Title, &p.Content, &p.ImageID, &p.CreationDate,
+		&p.Nbrcomments, &p.Nbrlike, &p.Nbrdislike, &imageURL, &thumbnailURL, &p.Hidden, &editedAt,
+		&deletedAt, &deletedBy)
+	p.ImageURL = imageURL.String
+	p.ThumbnailURL = thumbnailURL.String
+	p.EditedAt = nullTime(editedAt)
+	p.DeletedAt = nullTime(deletedAt)
+	p.DeletedBy = int(deletedBy.Int64)
+	return err
 }
 
-// Comment structure
-func (c *Comment) ScanRows(rows *sql.Rows) error {
-	return rows.Scan(&c.CommentID, &c.PostID, &c.UserID, &c.Content, &c.CreationDate)
+// Comment structure, with author, post title and vote counters joined in
+func (c *Comment) ScanRows(rows Scanner) error {
+	var editedAt, deletedAt sql.NullTime
+	var deletedBy sql.NullInt64
+	err := rows.Scan(&c.CommentID, &c.PostID, &c.PostTitle, &c.ParentID, &c.UserID, &c.Username,
+		&c.Content, &c.CreationDate, &c.NbrLike, &c.NbrDislike, &c.Hidden, &editedAt, &deletedAt, &deletedBy)
+	c.EditedAt = nullTime(editedAt)
+	c.DeletedAt = nullTime(deletedAt)
+	c.DeletedBy = int(deletedBy.Int64)
+	return err
 }
 
 // Category structure
-func (cat *Category) ScanRows(rows *sql.Rows) error {
-	return rows.Scan(&cat.CategoryID, &cat.Name)
+func (cat *Category) ScanRows(rows Scanner) error {
+	return rows.Scan(&cat.CategoryID, &cat.Name, &cat.Description, &cat.Slug, &cat.Color, &cat.Icon, &cat.SortOrder, &cat.Archived, &cat.ParentID)
 }
 
 // PstCategory structure
-func (pc *PostCategory) ScanRows(rows *sql.Rows) error {
+func (pc *PostCategory) ScanRows(rows Scanner) error {
 	return rows.Scan(&pc.PostID, &pc.CategoryID)
 }
 
 // LikeDislike structure
-func (ld *LikeDislike) ScanRows(rows *sql.Rows) error {
+func (ld *LikeDislike) ScanRows(rows Scanner) error {
 	return rows.Scan(&ld.LikeDislikeID, &ld.PostID, &ld.CommentID, &ld.UserID, &ld.LikeDislikeType, &ld.CreationDate)
 }
 
 // Session structure
-func (s *Session) ScanRows(rows *sql.Rows) error {
-	return rows.Scan(&s.SessionID, &s.UserID, &s.Cookie_value, &s.ExpirationDate)
+func (s *Session) ScanRows(rows Scanner) error {
+	return rows.Scan(&s.SessionID, &s.UserID, &s.Cookie_value, &s.ExpirationDate, &s.CSRFToken)
+}
+
+// Session owner, followed by the session's CSRF token
+func (su *SessionUser) ScanRows(rows Scanner) error {
+	return rows.Scan(&su.UserID, &su.Username, &su.Email, &su.PasswordHash, &su.RegistrationDate, &su.ResetToken, &su.Role, &su.CSRFToken)
 }
 
 // Scanning function
-func (n *Notification) ScanRows(rows *sql.Rows) error {
+func (n *Notification) ScanRows(rows Scanner) error {
 	return rows.Scan(
 		&n.NotificationID,
 		&n.UserID,
@@ -60,7 +90,57 @@ func (n *Notification) ScanRows(rows *sql.Rows) error {
 }
 
 // Image scanning function
-func (img *Image) ScanRows(rows *sql.Rows) error {
+func (img *Image) ScanRows(rows Scanner) error {
 	return rows.Scan(&img.ImageID, &img.UserID, &img.Filename, &img.OriginalName,
 		&img.FileSize, &img.FileType, &img.ImageType, &img.ImageURL, &img.ThumbnailURL, &img.UploadDate)
 }
+
+// Moderation log entry, with the actor's username joined in
+func (a *ModerationAction) ScanRows(rows Scanner) error {
+	return rows.Scan(&a.ActionID, &a.ActorID, &a.ActorName, &a.Action, &a.TargetType, &a.TargetID, &a.Details, &a.CreationDate)
+}
+
+// Report, with the reporter's username joined in
+func (rp *Report) ScanRows(rows Scanner) error {
+	return rows.Scan(&rp.ReportID, &rp.ReporterID, &rp.ReporterName, &rp.TargetType, &rp.TargetID,
+		&rp.Reason, &rp.Details, &rp.Status, &rp.Resolution, &rp.CreationDate)
+}
+
+// Search hit, with the author's username joined in
+func (h *SearchHit) ScanRows(rows Scanner) error {
+	return rows.Scan(&h.PostID, &h.CommentID, &h.Title, &h.Snippet, &h.Username, &h.CreationDate, &h.Rank)
+}
+
+// Tag structure, with the number of visible posts using it
+func (t *Tag) ScanRows(rows Scanner) error {
+	return rows.Scan(&t.TagID, &t.Name, &t.PostCount)
+}
+
+// Draft structure, with the filename and URL of its image joined in
+func (d *Draft) ScanRows(rows Scanner) error {
+	var filename, imageURL sql.NullString
+	err := rows.Scan(&d.DraftID, &d.UserID, &d.Title, &d.Content, &d.ImageID, &filename, &imageURL,
+		&d.Tags, &d.UpdatedAt)
+	d.ImageFilename = filename.String
+	d.ImageURL = imageURL.String
+	return err
+}
+
+// Revision structure, with the editor's username joined in and the category
+// names decoded from their JSON array
+func (rev *Revision) ScanRows(rows Scanner) error {
+	var categories string
+	if err := rows.Scan(&rev.RevisionID, &rev.TargetID, &rev.EditorID, &rev.Editor, &rev.Title, &rev.Content,
+		&categories, &rev.CreationDate); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(categories), &rev.Categories)
+}
+
+// nullTime turns a nullable timestamp column into a pointer, nil for NULL
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -11,6 +11,7 @@ type User struct {
 	PasswordHash     string
 	RegistrationDate time.Time
 	ResetToken       *string
+	Role             string
 }
 
 type UserProfile struct {
@@ -44,26 +45,39 @@ type CommentActivity struct {
 type Post struct {
 	PostID         int
 	UserID         int
+	Username       string
 	Title          string
 	PhotoURL       string
 	Content        string
 	ImageID        *int
+	ImageURL       string
+	ThumbnailURL   string
 	CreationDate   time.Time
 	FormatedDate   string
 	Categories     []string
+	Tags           []string
 	StatusLiked    string
 	StatusDisliked string
 	Nbrlike        int
 	Nbrdislike     int
 	Nbrcomments    int
+	Hidden         bool
+	// EditedAt is when the post was last edited, nil if it never was
+	EditedAt *time.Time
+	// DeletedAt is when the post was moved to the trash, by DeletedBy; nil
+	// while it is live
+	DeletedAt *time.Time
+	DeletedBy int
 }
 
 type PostResponse struct {
 	ID           int      `json:"id"`
 	Title        string   `json:"title"`
 	Content      string   `json:"content"`
+	ContentHTML  string   `json:"contentHtml"`
 	Author       string   `json:"author"`
 	TimeAgo      string   `json:"timeAgo"`
+	Categories   []string `json:"categories"`
 	Tags         []string `json:"tags"`
 	Comments     int      `json:"comments"`
 	Likes        int      `json:"likes"`
@@ -73,6 +87,10 @@ type PostResponse struct {
 	ThumbnailURL string   `json:"thumbnailUrl,omitempty"`
 	UserVote     int      `json:"userVote,omitempty"` 
 	IsAuthor     bool     `json:"isAuthor,omitempty"`
+	Hidden       bool     `json:"hidden,omitempty"`
+	// Edited marks a post changed since it was published, last at EditedAt
+	Edited   bool       `json:"edited"`
+	EditedAt *time.Time `json:"editedAt,omitempty"`
 }
 
 type Image struct {
@@ -103,6 +121,8 @@ type ImageResponse struct {
 type Comment struct {
 	CommentID    int
 	PostID       int
+	PostTitle    string
+	ParentID     *int
 	UserID       int
 	Username     string
 	Content      string
@@ -110,29 +130,91 @@ type Comment struct {
 	NbrDislike   int
 	CreationDate time.Time
 	Formatdate   string
+	Hidden       bool
+	// EditedAt is when the comment was last edited, nil if it never was
+	EditedAt *time.Time
+	// DeletedAt is when the comment was moved to the trash, by DeletedBy;
+	// nil while it is live
+	DeletedAt *time.Time
+	DeletedBy int
 }
 
 type CommentResponse struct {
-	ID           int    `json:"id"`
-	PostID       int    `json:"postId"`
+	ID     int  `json:"id"`
+	PostID int  `json:"postId"`
+	// ParentID is the comment this one replies to, nil for top-level comments
+	ParentID *int `json:"parentId"`
+	// Depth is 0 for top-level comments and one more for each level of replies
+	Depth        int    `json:"depth"`
+	ReplyCount   int    `json:"replyCount"`
 	Author       string `json:"author"`
 	Content      string `json:"content"`
+	ContentHTML  string `json:"contentHtml"`
 	TimeAgo      string `json:"timeAgo"`
 	LikeCount    int    `json:"likeCount"`
 	DislikeCount int    `json:"dislikeCount"`
 	UserVote     int    `json:"userVote"`
 	IsAuthor     bool   `json:"isAuthor"`
+	CanEdit      bool   `json:"canEdit"`
+	Hidden       bool   `json:"hidden,omitempty"`
+	// Edited marks a comment changed since it was posted, last at EditedAt
+	Edited   bool       `json:"edited"`
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+	// Deleted marks a "[deleted]" placeholder kept for the replies below it
+	Deleted bool `json:"deleted,omitempty"`
+	// Replies holds the direct replies when comments are requested as a tree
+	Replies []CommentResponse `json:"replies,omitempty"`
+}
+
+// PostPage is one page of a post listing; NextCursor is empty on the last page
+type PostPage struct {
+	Posts      []PostResponse `json:"posts"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// CommentPage is one page of a post's comment threads
+type CommentPage struct {
+	Comments   []CommentResponse `json:"comments"`
+	NextCursor string            `json:"nextCursor,omitempty"`
 }
 
 type Category struct {
-	CategoryID int
-	Name       string
+	CategoryID  int
+	Name        string
+	Description string
+	Slug        string
+	// Color is a #rrggbb color and Icon a Bootstrap Icons name, both optional
+	Color     string
+	Icon      string
+	SortOrder int
+	Archived  bool
+	// ParentID is the category this one sits in, nil at the top level
+	ParentID *int
 }
 
 type CategoryResponse struct {
+	ID          int      `json:"id"`
 	Name        string   `json:"name"`
+	Slug        string   `json:"slug"`
 	Description string   `json:"description"`
+	Color       string   `json:"color,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+	SortOrder   int      `json:"sortOrder"`
+	Archived    bool     `json:"archived,omitempty"`
 	Tags        []string `json:"tags"`
+	ParentID    *int     `json:"parentId"`
+	// Depth is 0 for top-level categories, 1 for their children and so on
+	Depth int `json:"depth"`
+	// Breadcrumb lists the category's ancestors from the top level down,
+	// ending with the category itself
+	Breadcrumb []CategoryCrumb `json:"breadcrumb"`
+}
+
+// CategoryCrumb is one step of a category breadcrumb
+type CategoryCrumb struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
 }
 
 type PostCategory struct {
@@ -170,6 +252,13 @@ type Session struct {
 	UserID         int
 	Cookie_value   string
 	ExpirationDate time.Time
+	CSRFToken      string
+}
+
+// SessionUser is the owner of a session together with the session's CSRF token
+type SessionUser struct {
+	User
+	CSRFToken string
 }
 
 type Notification struct {
@@ -189,4 +278,186 @@ type Notification struct {
 type NotificationResponse struct {
 	Unread []Notification `json:"unread"`
 	Read   []Notification `json:"read"`
+	// The cursors continue each list with ?status=unread or ?status=read
+	NextUnreadCursor string `json:"nextUnreadCursor,omitempty"`
+	NextReadCursor   string `json:"nextReadCursor,omitempty"`
+}
+
+// ModerationAction is an entry of the moderation log
+type ModerationAction struct {
+	ActionID     int
+	ActorID      int
+	ActorName    string
+	Action       string
+	TargetType   string
+	TargetID     int
+	Details      string
+	CreationDate time.Time
+}
+
+type UserSummary struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	JoinDate string `json:"joinDate"`
+}
+
+type ModerationActionResponse struct {
+	ID         int    `json:"id"`
+	Actor      string `json:"actor"`
+	Action     string `json:"action"`
+	TargetType string `json:"targetType"`
+	TargetID   int    `json:"targetId"`
+	Details    string `json:"details"`
+	TimeAgo    string `json:"timeAgo"`
+}
+
+// Report flags a post, comment or user for the moderators
+type Report struct {
+	ReportID     int
+	ReporterID   int
+	ReporterName string
+	TargetType   string
+	TargetID     int
+	Reason       string
+	Details      string
+	Status       string
+	Resolution   string
+	CreationDate time.Time
+}
+
+type ReportResponse struct {
+	ID         int            `json:"id"`
+	Reporter   string         `json:"reporter"`
+	TargetType string         `json:"targetType"`
+	TargetID   int            `json:"targetId"`
+	Reason     string         `json:"reason"`
+	Details    string         `json:"details"`
+	TimeAgo    string         `json:"timeAgo"`
+	Content    *ReportContent `json:"content"`
+}
+
+// ReportContent is the reported post, comment or user shown in the moderation queue
+type ReportContent struct {
+	Author string `json:"author"`
+	Title  string `json:"title,omitempty"`
+	Text   string `json:"text,omitempty"`
+	PostID int    `json:"postId,omitempty"`
+	Hidden bool   `json:"hidden,omitempty"`
+}
+
+// SearchHit is a post or comment matching a full-text search; CommentID is 0
+// for posts. Title and Snippet mark the matched terms, see store.MarkStart.
+type SearchHit struct {
+	PostID       int
+	CommentID    int
+	Title        string
+	Snippet      string
+	Username     string
+	CreationDate time.Time
+	Rank         float64
+}
+
+// SearchResult is a search hit as sent to the frontend; Title and Snippet are
+// escaped HTML with the matched terms in <mark> tags
+type SearchResult struct {
+	Type      string `json:"type"`
+	PostID    int    `json:"postId"`
+	CommentID int    `json:"commentId,omitempty"`
+	Title     string `json:"title"`
+	Snippet   string `json:"snippet"`
+	Author    string `json:"author"`
+	TimeAgo   string `json:"timeAgo"`
+}
+
+// SearchPage is one page of search results, best match first
+type SearchPage struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// Tag is a free-form post tag; PostCount counts the visible posts using it
+type Tag struct {
+	TagID     int
+	Name      string
+	PostCount int
+}
+
+type TagResponse struct {
+	Name      string `json:"name"`
+	PostCount int    `json:"postCount"`
+}
+
+// Draft is a post its writer has not published yet. Tags holds the tag field
+// as typed; categories are kept by name.
+type Draft struct {
+	DraftID       int
+	UserID        int
+	Title         string
+	Content       string
+	ImageID       *int
+	ImageFilename string
+	ImageURL      string
+	Tags          string
+	Categories    []string
+	UpdatedAt     time.Time
+}
+
+// DraftResponse is a draft as the new post form reloads it; ImageID is the
+// image filename the form's image_id field takes
+type DraftResponse struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	Content    string    `json:"content"`
+	Categories []string  `json:"categories"`
+	Tags       string    `json:"tags"`
+	ImageID    string    `json:"imageId,omitempty"`
+	ImageURL   string    `json:"imageUrl,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	TimeAgo    string    `json:"timeAgo"`
+}
+
+// Revision is one version of a post or comment; TargetID is the post or
+// comment ID. Comment revisions have no title or categories.
+type Revision struct {
+	RevisionID   int
+	TargetID     int
+	EditorID     int
+	Editor       string
+	Title        string
+	Content      string
+	Categories   []string
+	CreationDate time.Time
+}
+
+// RevisionResponse is a revision as listed; Number counts the versions from 1,
+// the original
+type RevisionResponse struct {
+	ID         int       `json:"id"`
+	Number     int       `json:"number"`
+	Editor     string    `json:"editor"`
+	Title      string    `json:"title,omitempty"`
+	Content    string    `json:"content"`
+	Categories []string  `json:"categories,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	TimeAgo    string    `json:"timeAgo"`
+}
+
+// TrashItemResponse is a deleted post or comment as listed in its author's
+// trash; for a comment, PostID and Title are those of its post
+type TrashItemResponse struct {
+	Type       string    `json:"type"`
+	ID         int       `json:"id"`
+	PostID     int       `json:"postId"`
+	Title      string    `json:"title"`
+	Excerpt    string    `json:"excerpt"`
+	DeletedAt  time.Time `json:"deletedAt"`
+	DeletedAgo string    `json:"deletedAgo"`
+	// PurgeAt is when the item is removed for good
+	PurgeAt time.Time `json:"purgeAt"`
+	// ByModerator marks content a moderator deleted, which only moderators
+	// can restore
+	ByModerator bool `json:"byModerator"`
+	CanRestore  bool `json:"canRestore"`
 }
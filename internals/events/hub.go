@@ -0,0 +1,209 @@
+// Package events is an in-process publish/subscribe hub that fans events out
+// to the subscribers of a topic, such as the open pages of one user
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrClosed is returned when subscribing to a hub that has been closed
+	ErrClosed = errors.New("events: hub closed")
+	// ErrFull is returned when a topic already has as many subscribers as allowed
+	ErrFull = errors.New("events: too many subscribers")
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind by before
+// it is dropped; a dropped client reconnects and catches up from the history
+const subscriberBuffer = 16
+
+// Event is one message published on a topic. IDs grow across the hub, and
+// across restarts too, since they start from the clock. Every subscriber
+// gets the same Data, so it must not be changed once published.
+type Event struct {
+	ID   uint64
+	Name string
+	Data any
+
+	at time.Time
+}
+
+// Hub delivers the events published on a topic to all of its current
+// subscribers, and keeps the latest ones for a while so a client that
+// reconnects can be sent what it missed
+type Hub struct {
+	mu     sync.Mutex
+	lastID uint64
+	topics map[string]*topic
+	closed bool
+
+	historySize int
+	historyTTL  time.Duration
+}
+
+type topic struct {
+	subs   map[*Subscription]bool
+	recent []Event
+}
+
+// Subscription receives the events of one topic on C until it is closed,
+// or the hub drops it for falling behind or shuts down
+type Subscription struct {
+	C <-chan Event
+
+	c     chan Event
+	hub   *Hub
+	topic string
+}
+
+// NewHub creates a hub that keeps up to historySize events per topic, for
+// at most historyTTL, to replay to reconnecting clients
+func NewHub(historySize int, historyTTL time.Duration) *Hub {
+	return &Hub{
+		lastID:      uint64(time.Now().UnixMicro()),
+		topics:      map[string]*topic{},
+		historySize: historySize,
+		historyTTL:  historyTTL,
+	}
+}
+
+// Publish sends an event to the subscribers of a topic. Subscribers too
+// slow to take it are dropped rather than waited for.
+func (h *Hub) Publish(name, eventName string, data any) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ErrClosed
+	}
+
+	h.lastID++
+	e := Event{ID: h.lastID, Name: eventName, Data: data, at: time.Now()}
+	t := h.topic(name)
+	t.recent = append(t.recent, e)
+	if len(t.recent) > h.historySize {
+		t.recent = t.recent[len(t.recent)-h.historySize:]
+	}
+
+	for s := range t.subs {
+		select {
+		case s.c <- e:
+		default:
+			delete(t.subs, s)
+			close(s.c)
+		}
+	}
+	return nil
+}
+
+// Subscribe starts receiving the events of a topic, unless it already has
+// limit subscribers (0 for no limit). It also returns the events after
+// lastID that the hub still holds, in order, so nothing published in
+// between is lost; pass 0 to skip them.
+func (h *Hub) Subscribe(name string, lastID uint64, limit int) (*Subscription, []Event, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, nil, ErrClosed
+	}
+	t := h.topic(name)
+	if limit > 0 && len(t.subs) >= limit {
+		return nil, nil, ErrFull
+	}
+
+	c := make(chan Event, subscriberBuffer)
+	s := &Subscription{C: c, c: c, hub: h, topic: name}
+	t.subs[s] = true
+
+	var missed []Event
+	if lastID != 0 {
+		cutoff := time.Now().Add(-h.historyTTL)
+		for _, e := range t.recent {
+			if e.ID > lastID && e.at.After(cutoff) {
+				missed = append(missed, e)
+			}
+		}
+	}
+	return s, missed, nil
+}
+
+// Close stops receiving events. It is safe to call more than once, and
+// after the hub dropped the subscription.
+func (s *Subscription) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.topics[s.topic]
+	if !ok || !t.subs[s] {
+		return
+	}
+	delete(t.subs, s)
+	close(s.c)
+	if len(t.subs) == 0 && len(t.recent) == 0 {
+		delete(h.topics, s.topic)
+	}
+}
+
+// Close ends every subscription and refuses new ones and new events, so
+// open streams finish during shutdown
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for _, t := range h.topics {
+		for s := range t.subs {
+			close(s.c)
+		}
+	}
+	h.topics = map[string]*topic{}
+}
+
+// Prune forgets the events older than the history window, and the topics
+// left with neither events nor subscribers
+func (h *Hub) Prune() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cutoff := time.Now().Add(-h.historyTTL)
+	for name, t := range h.topics {
+		i := 0
+		for i < len(t.recent) && !t.recent[i].at.After(cutoff) {
+			i++
+		}
+		t.recent = t.recent[i:]
+		if len(t.subs) == 0 && len(t.recent) == 0 {
+			delete(h.topics, name)
+		}
+	}
+}
+
+// Run prunes the history every history window until ctx is done
+func (h *Hub) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.historyTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.Prune()
+		}
+	}
+}
+
+// topic returns the named topic, creating it if needed; h.mu must be held
+func (h *Hub) topic(name string) *topic {
+	t, ok := h.topics[name]
+	if !ok {
+		t = &topic{subs: map[*Subscription]bool{}}
+		h.topics[name] = t
+	}
+	return t
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum/internals/database"
+	"forum/internals/roles"
+	"forum/internals/store"
+	"forum/internals/utils"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AdminUsersHandler lists every user with their role (GET /api/admin/users)
+func (app *App) AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	list, err := app.Store.Users.List(r.Context())
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	users := []database.UserSummary{}
+	for _, u := range list {
+		users = append(users, database.UserSummary{
+			ID:       u.UserID,
+			Username: u.Username,
+			Email:    u.Email,
+			Role:     u.Role,
+			JoinDate: u.RegistrationDate.Format("January 2006"),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(users)
+}
+
+// SetUserRoleHandler changes a user's role (PATCH /api/admin/users/{id}/role)
+func (app *App) SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	role := strings.TrimSpace(r.FormValue("role"))
+	if !roles.Valid(role) {
+		http.Error(w, "Role must be member, moderator or admin", http.StatusBadRequest)
+		return
+	}
+
+	// Admins cannot demote themselves, so there is always one left
+	if userID == currentUserID(r) {
+		http.Error(w, "You cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	user, err := app.Store.Users.Get(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	if err := app.Store.Users.SetRole(r.Context(), userID, role); err != nil {
+		http.Error(w, "Failed to update role", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "set_role", "user", userID, fmt.Sprintf("%s: %s -> %s", user.Username, user.Role, role))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "role": role})
+}
+
+// categoryColor and categoryIcon match the accepted category colors
+// (#rrggbb) and Bootstrap Icons names
+var (
+	categoryColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	categoryIcon  = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+)
+
+// readCategoryForm copies the name, description, color, icon and parent_id
+// fields of the request onto category, keeping the current value of fields
+// not sent, and checks the result. The slug follows the name, and an empty or
+// 0 parent_id moves the category to the top level.
+func readCategoryForm(r *http.Request, category *database.Category) error {
+	r.FormValue("name") // parses the form
+	field := func(name string, value *string) {
+		if _, ok := r.Form[name]; ok {
+			*value = strings.TrimSpace(r.FormValue(name))
+		}
+	}
+	field("name", &category.Name)
+	field("description", &category.Description)
+	field("color", &category.Color)
+	field("icon", &category.Icon)
+	if _, ok := r.Form["parent_id"]; ok {
+		category.ParentID = nil
+		if value := strings.TrimSpace(r.FormValue("parent_id")); value != "" && value != "0" {
+			parentID, err := strconv.Atoi(value)
+			if err != nil {
+				return errors.New("Invalid parent category ID")
+			}
+			category.ParentID = &parentID
+		}
+	}
+
+	category.Slug = utils.Slugify(category.Name)
+	switch {
+	case category.Name == "":
+		return errors.New("Category name required")
+	case len(category.Name) > 50:
+		return errors.New("Category name must be at most 50 characters")
+	case category.Slug == "":
+		return errors.New("Category name must contain a letter or digit")
+	case len(category.Description) > 200:
+		return errors.New("Category description must be at most 200 characters")
+	case category.Color != "" && !categoryColor.MatchString(category.Color):
+		return errors.New("Category color must look like #6dd628")
+	case category.Icon != "" && !categoryIcon.MatchString(category.Icon):
+		return errors.New("Category icon must be a Bootstrap Icons name like flower1")
+	}
+	return nil
+}
+
+// CreateCategoryHandler adds a category from its name, description, color
+// and icon (POST /api/admin/categories)
+func (app *App) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var category database.Category
+	if err := readCategoryForm(r, &category); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := app.Store.Categories.Create(r.Context(), &category)
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "Category already exists", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Parent category not found", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create category", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "create", "category", id, category.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "id": id, "slug": category.Slug})
+}
+
+// UpdateCategoryHandler renames a category, moves it to another parent or
+// changes its description, color or icon; fields left out keep their value
+// (PATCH /api/admin/categories/{id})
+func (app *App) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	category, ok := app.pathCategory(w, r)
+	if !ok {
+		return
+	}
+	oldName := category.Name
+	if err := readCategoryForm(r, category); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := app.Store.Categories.Update(r.Context(), category)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Parent category not found", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrCycle):
+		http.Error(w, "A category cannot be inside itself or one of its sub-categories", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrConflict):
+		http.Error(w, "Category already exists", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to update category", http.StatusInternalServerError)
+		return
+	}
+	if category.Name != oldName {
+		app.recordModeration(r, "rename", "category", category.CategoryID, oldName+" -> "+category.Name)
+	} else {
+		app.recordModeration(r, "update", "category", category.CategoryID, category.Name)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "slug": category.Slug})
+}
+
+// ReorderCategoriesHandler sets the order categories are listed in from the
+// comma-separated IDs in ids; categories left out go after them
+// (POST /api/admin/categories/reorder)
+func (app *App) ReorderCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	var ids []int
+	for _, field := range strings.Split(r.FormValue("ids"), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			http.Error(w, "ids must be a comma-separated list of category IDs", http.StatusBadRequest)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	err := app.Store.Categories.Reorder(r.Context(), ids)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to reorder categories", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "reorder", "category", ids[0], r.FormValue("ids"))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// MergeCategoryHandler moves every post and sub-category of a category into
+// the category given as into, then deletes it (POST /api/admin/categories/{id}/merge)
+func (app *App) MergeCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	from, ok := app.pathCategory(w, r)
+	if !ok {
+		return
+	}
+	intoID, err := strconv.Atoi(r.FormValue("into"))
+	if err != nil {
+		http.Error(w, "Invalid target category ID", http.StatusBadRequest)
+		return
+	}
+	if intoID == from.CategoryID {
+		http.Error(w, "A category cannot be merged into itself", http.StatusBadRequest)
+		return
+	}
+	into, err := app.Store.Categories.Get(r.Context(), intoID)
+	if err != nil {
+		http.Error(w, "Target category not found", http.StatusNotFound)
+		return
+	}
+
+	err = app.Store.Categories.Merge(r.Context(), from.CategoryID, into.CategoryID)
+	if errors.Is(err, store.ErrCycle) {
+		http.Error(w, "A category cannot be merged into one of its sub-categories", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to merge categories", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "merge", "category", from.CategoryID, from.Name+" -> "+into.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// ArchiveCategoryHandler archives a category, or restores it on the
+// unarchive route: archived categories keep their posts but take no new ones
+// (POST /api/admin/categories/{id}/archive and /unarchive)
+func (app *App) ArchiveCategoryHandler(archived bool) http.HandlerFunc {
+	action := "archive"
+	if !archived {
+		action = "unarchive"
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		category, ok := app.pathCategory(w, r)
+		if !ok {
+			return
+		}
+		if err := app.Store.Categories.SetArchived(r.Context(), category.CategoryID, archived); err != nil {
+			http.Error(w, "Failed to "+action+" category", http.StatusInternalServerError)
+			return
+		}
+		app.recordModeration(r, action, "category", category.CategoryID, category.Name)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "archived": archived})
+	}
+}
+
+// pathCategory loads the category of the {id} wildcard, answering the
+// request itself when it is invalid or unknown
+func (app *App) pathCategory(w http.ResponseWriter, r *http.Request) (*database.Category, bool) {
+	categoryID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return nil, false
+	}
+	category, err := app.Store.Categories.Get(r.Context(), categoryID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return category, true
+}
+
+// DeleteCategoryHandler removes an unused category (DELETE /api/admin/categories/{id})
+func (app *App) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		return
+	}
+
+	err = app.Store.Categories.Delete(r.Context(), categoryID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, "Category not found", http.StatusNotFound)
+		return
+	case errors.Is(err, store.ErrConflict):
+		http.Error(w, "Category is still used by posts", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, "Failed to delete category", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "delete", "category", categoryID, "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// ModerationLogHandler lists recent moderator and admin actions (GET /api/moderation/log)
+func (app *App) ModerationLogHandler(w http.ResponseWriter, r *http.Request) {
+	page := getIntParam(r, "page", 1)
+	limit := getIntParam(r, "limit", 50)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	list, err := app.Store.ModerationLog.List(r.Context(), limit, (page-1)*limit)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	actions := []database.ModerationActionResponse{}
+	for _, a := range list {
+		actions = append(actions, database.ModerationActionResponse{
+			ID:         a.ActionID,
+			Actor:      a.ActorName,
+			Action:     a.Action,
+			TargetType: a.TargetType,
+			TargetID:   a.TargetID,
+			Details:    a.Details,
+			TimeAgo:    utils.FormatTimeAgo(a.CreationDate),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(actions)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"forum/internals/config"
+	"forum/internals/events"
+	"forum/internals/store"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Event streams replay up to eventHistory events per topic, published
+// within eventHistoryTTL, to clients that reconnect
+const (
+	eventHistory    = 50
+	eventHistoryTTL = 5 * time.Minute
+)
+
+// App carries the dependencies shared by all handlers
+type App struct {
+	Store  *store.Store
+	Config *config.Config
+	// Events carries live updates to the pages streaming them
+	Events *events.Hub
+
+	githubOauthConfig *oauth2.Config
+	googleOauthConfig *oauth2.Config
+}
+
+// NewApp creates the handler set around the data store and configuration
+func NewApp(st *store.Store, cfg *config.Config) *App {
+	return &App{
+		Store:             st,
+		Config:            cfg,
+		Events:            events.NewHub(eventHistory, eventHistoryTTL),
+		githubOauthConfig: newGitHubOauthConfig(cfg),
+		googleOauthConfig: newGoogleOauthConfig(cfg),
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"forum/internals/config"
+	"forum/internals/database"
+	"forum/internals/middleware"
+	"forum/internals/store"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testApp is an App on the in-memory store, serving its real routes
+type testApp struct {
+	*App
+	handler http.Handler
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	app := NewApp(store.NewMemory("General"), config.Default())
+	return &testApp{App: app, handler: app.Routes()}
+}
+
+// testUser is a registered user with a live session
+type testUser struct {
+	id            int
+	session, csrf string
+}
+
+func (a *testApp) newUser(t *testing.T, name string) testUser {
+	t.Helper()
+	ctx := context.Background()
+	id, err := a.Store.Users.Create(ctx, name, name+"@example.com", "hash")
+	if err != nil {
+		t.Fatalf("creating user %s: %v", name, err)
+	}
+	u := testUser{id: id, session: name + "-session", csrf: name + "-csrf"}
+	if err := a.Store.Sessions.Create(ctx, id, u.session, u.csrf, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("creating session for %s: %v", name, err)
+	}
+	return u
+}
+
+func (a *testApp) newPost(t *testing.T, author testUser) int {
+	t.Helper()
+	post := &database.Post{UserID: author.id, Title: "Title", Content: "Content"}
+	id, err := a.Store.Posts.Create(context.Background(), post, []int{1})
+	if err != nil {
+		t.Fatalf("creating post: %v", err)
+	}
+	return id
+}
+
+func (a *testApp) newComment(t *testing.T, author testUser, postID int) int {
+	t.Helper()
+	comment := &database.Comment{PostID: postID, UserID: author.id, Content: "Comment"}
+	id, err := a.Store.Comments.Create(context.Background(), comment)
+	if err != nil {
+		t.Fatalf("creating comment: %v", err)
+	}
+	return id
+}
+
+// post sends a form as u, with the session's CSRF token
+func (a *testApp) post(u testUser, path string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set(middleware.CSRFHeader, u.csrf)
+	r.AddCookie(&http.Cookie{Name: middleware.SessionCookie, Value: u.session})
+	w := httptest.NewRecorder()
+	a.handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestLikePost(t *testing.T) {
+	app := newTestApp(t)
+	alice, bob := app.newUser(t, "alice"), app.newUser(t, "bob")
+	postID := app.newPost(t, alice)
+	path := "/api/posts/" + strconv.Itoa(postID) + "/vote"
+
+	votes := []struct {
+		vote                  string
+		likes, dislikes, user int
+	}{
+		{"1", 1, 0, 1},
+		{"-1", 0, 1, -1},
+		{"-1", 0, 0, 0},
+	}
+	for _, v := range votes {
+		w := app.post(bob, path, url.Values{"vote": {v.vote}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("vote %s: got status %d: %s", v.vote, w.Code, w.Body)
+		}
+		var got database.LikeResponse
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("vote %s: decoding response: %v", v.vote, err)
+		}
+		if got.LikeCount != v.likes || got.DislikeCount != v.dislikes || got.UserVote != v.user {
+			t.Errorf("vote %s: got %d likes, %d dislikes, vote %d, want %d, %d, %d",
+				v.vote, got.LikeCount, got.DislikeCount, got.UserVote, v.likes, v.dislikes, v.user)
+		}
+	}
+}
@@ -2,18 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
-	"forum/internals/database"
-	"forum/internals/utils"
 	"net/http"
 )
 
 // LogoutHandler handles user logout
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+func (app *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	cookie, err := r.Cookie("session")
 	if err == nil {
-		db := database.CreateTable()
-		defer db.Close()
-		db.Exec("DELETE FROM Sessions WHERE cookie_value = ?", cookie.Value)
+		app.Store.Sessions.Delete(r.Context(), cookie.Value)
 	}
 
 	http.SetCookie(w, &http.Cookie{
@@ -28,18 +24,15 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // AuthStatusHandler checks authentication status for API calls
-func AuthStatusHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
-	isLoggedIn := err == nil && utils.IsValidSession(cookie.Value)
-
+func (app *App) AuthStatusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	if isLoggedIn {
-		userID := utils.GetUserIDFromSession(cookie.Value)
-		username := utils.GetUsernameFromSession(cookie.Value)
+	if user := currentUser(r); user != nil {
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"loggedIn": true,
-			"userID":   userID,
-			"username": username,
+			"loggedIn":  true,
+			"userID":    user.ID,
+			"username":  user.Username,
+			"role":      user.Role,
+			"csrfToken": user.CSRFToken,
 		})
 	} else {
 		json.NewEncoder(w).Encode(map[string]interface{}{
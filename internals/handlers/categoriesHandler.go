@@ -4,74 +4,58 @@ import (
 	"encoding/json"
 	"forum/internals/database"
 	"net/http"
+	"slices"
 )
 
-// CategoriesAPIHandler returns all categories as JSON
-func CategoriesAPIHandler(w http.ResponseWriter, r *http.Request) {
-	db := database.CreateTable()
-	defer db.Close()
+// CategoriesAPIHandler returns the categories as JSON in tree order, each
+// followed by its sub-categories, siblings in their sort order. Archived ones
+// are only included with ?archived=true.
+func (app *App) CategoriesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	includeArchived := r.URL.Query().Get("archived") == "true"
 
-	// Debug: Check if we can connect to database
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM Categories").Scan(&count)
+	// Breadcrumbs go through archived categories too
+	list, err := app.Store.Categories.List(r.Context(), true)
 	if err != nil {
 		InternalServerErrorHandler(w, r)
 		return
 	}
 
-	// Get all categories
-	rows, err := db.Query("SELECT name FROM Categories ORDER BY name")
-	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
-	}
-	defer rows.Close()
-
-	var categories []database.CategoryResponse
-	for rows.Next() {
-		var categoryName string
-		err := rows.Scan(&categoryName)
-		if err != nil {
-			continue
+	// Sub-categories of each category, 0 holding the top-level ones
+	children := map[int][]database.Category{}
+	for _, c := range list {
+		parentID := 0
+		if c.ParentID != nil {
+			parentID = *c.ParentID
 		}
+		children[parentID] = append(children[parentID], c)
+	}
 
-		// Create category response with basic info
-		category := database.CategoryResponse{
-			Name:        categoryName,
-			Description: getCategoryDescription(categoryName),
-			Tags:        []string{categoryName}, 
+	categories := []database.CategoryResponse{}
+	var walk func(parentID int, breadcrumb []database.CategoryCrumb)
+	walk = func(parentID int, breadcrumb []database.CategoryCrumb) {
+		for _, c := range children[parentID] {
+			crumbs := append(slices.Clip(breadcrumb), database.CategoryCrumb{ID: c.CategoryID, Name: c.Name, Slug: c.Slug})
+			if includeArchived || !c.Archived {
+				categories = append(categories, database.CategoryResponse{
+					ID:          c.CategoryID,
+					Name:        c.Name,
+					Slug:        c.Slug,
+					Description: c.Description,
+					Color:       c.Color,
+					Icon:        c.Icon,
+					SortOrder:   c.SortOrder,
+					Archived:    c.Archived,
+					Tags:        []string{c.Name},
+					ParentID:    c.ParentID,
+					Depth:       len(breadcrumb),
+					Breadcrumb:  crumbs,
+				})
+			}
+			walk(c.CategoryID, crumbs)
 		}
-
-		categories = append(categories, category)
 	}
-
+	walk(0, nil)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(categories)
 }
-
-// CategoryResponse struct
-type CategoryResponse struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Tags        []string `json:"tags"`
-}
-
-// getCategoryDescription returns a description for each category
-func getCategoryDescription(categoryName string) string {
-	descriptions := map[string]string{
-		"Succulents":       "Low-maintenance plants perfect for beginners",
-		"Tropical Plants":  "Exotic plants that bring the tropics indoors",
-		"Herb Garden":      "Edible plants for cooking and natural remedies",
-		"Indoor Plants":    "Plants that thrive in indoor environments",
-		"Plant Care Tips":  "General advice and tips for plant care",
-		"Plant Diseases":   "Help with identifying and treating plant problems",
-		"Propagation":      "Growing new plants from existing ones",
-		"Flowering Plants": "Plants known for their beautiful blooms",
-	}
-
-	if desc, exists := descriptions[categoryName]; exists {
-		return desc
-	}
-	return "Share your knowledge about " + categoryName
-}
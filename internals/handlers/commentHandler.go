@@ -1,38 +1,22 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"forum/internals/database"
+	"forum/internals/markdown"
+	"forum/internals/store"
 	"forum/internals/utils"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 )
 
-// CreateCommentHandler handles comment creation
-func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
-		return
-	}
+// CreateCommentHandler handles comment creation (POST /api/posts/{id}/comments)
+func (app *App) CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	user := currentUser(r)
+	userID, commenterUsername := user.ID, user.Username
 
 	// Parse form data
-	postIDStr := r.FormValue("post_id")
 	content := strings.TrimSpace(r.FormValue("content"))
 
 	parentStr := r.FormValue("parent_comment_id")
@@ -40,7 +24,7 @@ func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 		parentStr = r.FormValue("parent_id")
 	}
 
-	postID, err := strconv.Atoi(postIDStr)
+	postID, err := pathID(r)
 	if err != nil {
 		http.Error(w, "Invalid post ID", http.StatusBadRequest)
 		return
@@ -50,184 +34,359 @@ func CreateCommentHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Comment content cannot be empty", http.StatusBadRequest)
 		return
 	}
+	if contentTooLong(content) {
+		http.Error(w, errContentTooLong.Error(), http.StatusBadRequest)
+		return
+	}
 
 	var parentCommentID *int
 	if parentStr != "" {
-		if pid, err := strconv.Atoi(parentStr); err == nil && pid > 0 {
-			parentCommentID = &pid
+		pid, err := strconv.Atoi(parentStr)
+		if err != nil || pid <= 0 {
+			http.Error(w, "Invalid parent comment ID", http.StatusBadRequest)
+			return
 		}
+		parentCommentID = &pid
 	}
 
-	// Insert comment into database
-	db := database.CreateTable()
-	defer db.Close()
-
 	// Get post details for the notification
-	var postTitle string
-	var postAuthorID int
-	err = db.QueryRow("SELECT title, user_id FROM Posts WHERE post_id = ?", postID).Scan(&postTitle, &postAuthorID)
-	if err != nil {
+	post, err := app.Store.Posts.Get(r.Context(), postID)
+	if err != nil || (post.Hidden && !canModify(r, post.UserID)) {
 		http.Error(w, "Post not found", http.StatusNotFound)
 		return
 	}
+	postTitle, postAuthorID := post.Title, post.UserID
 
-	// Single comment insertion based on whether it has a parent
-	var res sql.Result
+	// Replies must stay on the parent's post and within the nesting limit
+	attachTo, depth := parentCommentID, 0
 	if parentCommentID != nil {
-		res, err = db.Exec("INSERT INTO Comments (post_id, user_id, content, parent_comment_id) VALUES (?, ?, ?, ?)",
-			postID, userID, content, *parentCommentID)
-	} else {
-		res, err = db.Exec("INSERT INTO Comments (post_id, user_id, content) VALUES (?, ?, ?)",
-			postID, userID, content)
+		list, err := app.Store.Comments.ListByPost(r.Context(), postID, store.Page{})
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		parent, attachID, replyDepth := replyParent(list, *parentCommentID, app.Config.Comments.MaxDepth)
+		if parent == nil || parent.DeletedAt != nil || (parent.Hidden && !canModify(r, parent.UserID)) {
+			http.Error(w, "Parent comment not found on this post", http.StatusBadRequest)
+			return
+		}
+		attachTo, depth = &attachID, replyDepth
 	}
 
+	// Insert comment into database
+	commentID, err := app.Store.Comments.Create(r.Context(), &database.Comment{
+		PostID:   postID,
+		UserID:   userID,
+		Content:  content,
+		ParentID: attachTo,
+	})
 	if err != nil {
 		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
 		return
 	}
 
-	lastID, _ := res.LastInsertId()
-	commentID := int(lastID)
-
 	// Create notification for the post author if the comment is not by the author
 	if postAuthorID != userID {
-		commenterUsername := utils.GetUsernameFromSession(cookie.Value)
-		CreateCommentNotification(postID, commentID, userID, commenterUsername, postTitle)
+		app.CreateCommentNotification(postID, commentID, userID, commenterUsername, postTitle)
 	}
 
 	// Notify previous commenters (followup notifications)
-	commenterUsername := utils.GetUsernameFromSession(cookie.Value)
-	CreateFollowupCommentNotifications(postID, commentID, userID, commenterUsername, postTitle)
+	app.CreateFollowupCommentNotifications(r, postID, commentID, userID, commenterUsername, postTitle)
 
 	// If this is a reply to a specific comment, notify the parent comment author
 	if parentCommentID != nil {
-		CreateDirectReplyNotification(*parentCommentID, commentID, userID, commenterUsername, postTitle, postID)
+		app.CreateDirectReplyNotification(*parentCommentID, commentID, userID, commenterUsername, postTitle, postID)
 	}
 
+	// Notify the users mentioned in the comment
+	app.CreateMentionNotifications(postID, &commentID, userID, commenterUsername, postTitle, content)
+
+	// Show the comment on the pages following the post
+	app.publishComment(r.Context(), eventCommentCreated, commentID, depth)
+
 	// Return success response
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "id": commentID, "parentId": attachTo})
 }
 
-// CommentsAPIHandler returns comments for a specific post
-func CommentsAPIHandler(w http.ResponseWriter, r *http.Request) {
-	postIDStr := r.URL.Query().Get("post_id")
-	if postIDStr == "" {
-		http.Error(w, "Post ID required", http.StatusBadRequest)
+// CommentsAPIHandler returns comments for a specific post (GET /api/posts/{id}/comments)
+//
+// Comments come as a flat list in thread order, each reply after its parent
+// with its depth and parent ID; ?format=tree nests the replies instead.
+// Pages are counted in top-level comments, each with all of its replies.
+func (app *App) CommentsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
 		return
 	}
+	nested := r.URL.Query().Get("format") == "tree"
 
-	postID, err := strconv.Atoi(postIDStr)
+	page, err := pageParams(r, 20, 100)
 	if err != nil {
-		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
 		return
 	}
 
 	// Get current user ID if logged in
-	var currentUserID int
-	if cookie, err := r.Cookie("session"); err == nil && utils.IsValidSession(cookie.Value) {
-		currentUserID = utils.GetUserIDFromSession(cookie.Value)
-	}
-
-	db := database.CreateTable()
-	defer db.Close()
+	viewerID := currentUserID(r)
 
-	query := `
-		SELECT c.comment_id, c.post_id, c.content, c.creation_date, u.username, c.user_id
-		FROM Comments c
-		JOIN Users u ON c.user_id = u.user_id
-		WHERE c.post_id = ?
-		ORDER BY c.creation_date ASC`
-
-	rows, err := db.Query(query, postID)
+	list, err := app.Store.Comments.ListByPost(r.Context(), postID, page)
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
+	list, next := nextThreadCursor(list, page)
 
-	var comments []database.CommentResponse
-	for rows.Next() {
-		var c database.CommentResponse
-		var creationDate time.Time
-		var commentAuthorID int
+	// Hidden comments are only shown to their author and moderators, deleted
+	// ones only as a placeholder above replies that are shown
+	visible := func(c database.Comment) bool {
+		return !c.Hidden || canModify(r, c.UserID)
+	}
+	placeholders := deletedWithReplies(list, visible)
+	roots, replies := threadComments(list, func(c database.Comment) bool {
+		if c.DeletedAt != nil {
+			return placeholders[c.CommentID]
+		}
+		return visible(c)
+	})
+
+	// The users @mentioned anywhere on the page are looked up at once
+	var contents []string
+	for _, c := range list {
+		if c.DeletedAt == nil && visible(c) {
+			contents = append(contents, c.Content)
+		}
+	}
+	isUser := app.mentionedUsers(r.Context(), contents...)
+
+	var build func(i, depth int, parentID *int) database.CommentResponse
+	build = func(i, depth int, parentID *int) database.CommentResponse {
+		comment := list[i]
+		c := database.CommentResponse{
+			ID:           comment.CommentID,
+			PostID:       comment.PostID,
+			ParentID:     parentID,
+			Depth:        depth,
+			ReplyCount:   len(replies[i]),
+			Author:       comment.Username,
+			Content:      comment.Content,
+			TimeAgo:      utils.FormatTimeAgo(comment.CreationDate),
+			LikeCount:    comment.NbrLike,
+			DislikeCount: comment.NbrDislike,
+		}
 
-		err := rows.Scan(&c.ID, &c.PostID, &c.Content, &creationDate, &c.Author, &commentAuthorID)
-		if err != nil {
-			continue
+		if comment.DeletedAt != nil {
+			c.Author, c.Content = "[deleted]", "[deleted]"
+			c.LikeCount, c.DislikeCount = 0, 0
+			c.Deleted = true
+			for _, child := range replies[i] {
+				c.Replies = append(c.Replies, build(child, depth+1, &comment.CommentID))
+			}
+			return c
 		}
 
-		c.TimeAgo = utils.FormatTimeAgo(creationDate)
+		c.ContentHTML = markdown.Render(comment.Content, isUser)
 
-		// Get like/dislike counts and user's vote
-		likeStats := getCommentLikeStats(db, c.ID, currentUserID)
-		c.LikeCount = likeStats.LikeCount
-		c.DislikeCount = likeStats.DislikeCount
-		c.UserVote = likeStats.UserVote
+		// Get user's vote
+		if viewerID > 0 {
+			c.UserVote, _ = app.Store.Votes.CommentVote(r.Context(), c.ID, viewerID)
+		}
+
+		// Check if current user is the comment author, or may edit it anyway
+		c.IsAuthor = viewerID > 0 && viewerID == comment.UserID
+		c.CanEdit = canModify(r, comment.UserID)
+		c.Hidden = comment.Hidden
+		c.Edited = comment.EditedAt != nil
+		c.EditedAt = comment.EditedAt
 
-		// Check if current user is the comment author
-		c.IsAuthor = currentUserID > 0 && currentUserID == commentAuthorID
+		for _, child := range replies[i] {
+			c.Replies = append(c.Replies, build(child, depth+1, &comment.CommentID))
+		}
+		return c
+	}
 
+	comments := []database.CommentResponse{}
+	var flatten func(c database.CommentResponse)
+	flatten = func(c database.CommentResponse) {
+		children := c.Replies
+		c.Replies = nil
 		comments = append(comments, c)
+		for _, child := range children {
+			flatten(child)
+		}
+	}
+	for _, i := range roots {
+		if nested {
+			comments = append(comments, build(i, 0, nil))
+		} else {
+			flatten(build(i, 0, nil))
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(comments)
+	_ = json.NewEncoder(w).Encode(database.CommentPage{Comments: comments, NextCursor: next})
 }
 
-// DeleteCommentHandler handles comment deletion (for comment author or admin)
-func DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// DeleteCommentHandler handles comment deletion (DELETE /api/comments/{id})
+func (app *App) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
-		return
-	}
-
-	commentIDStr := r.FormValue("comment_id")
-	commentID, err := strconv.Atoi(commentIDStr)
+	commentID, err := pathID(r)
 	if err != nil {
 		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
 		return
 	}
 
-	db := database.CreateTable()
-	defer db.Close()
-
-	// Check if user owns this comment
-	var commentUserID int
-	err = db.QueryRow("SELECT user_id FROM Comments WHERE comment_id = ?", commentID).Scan(&commentUserID)
+	// Check if user owns this comment or is a moderator
+	comment, err := app.Store.Comments.Get(r.Context(), commentID)
 	if err != nil {
 		http.Error(w, "Comment not found", http.StatusNotFound)
 		return
 	}
 
-	if commentUserID != userID {
+	if !canModify(r, comment.UserID) {
 		http.Error(w, "Unauthorized to delete this comment", http.StatusForbidden)
 		return
 	}
 
-	// Delete comment likes first (foreign key constraint)
-	db.Exec("DELETE FROM CommentLikes WHERE comment_id = ?", commentID)
-
-	// Delete the comment
-	_, err = db.Exec("DELETE FROM Comments WHERE comment_id = ?", commentID)
-	if err != nil {
+	// Move the comment to the trash; replies keep it as a placeholder
+	if err := app.Store.Comments.Delete(r.Context(), commentID, userID); err != nil {
 		http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
 		return
 	}
+	if comment.UserID != userID {
+		app.recordModeration(r, "delete", "comment", commentID, utils.TruncateText(comment.Content, 100))
+	}
+	app.publishCommentDeleted(r.Context(), comment.PostID, commentID)
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
 }
+
+// nextThreadCursor trims the threads fetched with pageParams to the number of
+// top-level comments the client asked for and returns the cursor of the
+// following page, or "" on the last one
+func nextThreadCursor(list []database.Comment, page store.Page) ([]database.Comment, string) {
+	var roots []database.Comment
+	for _, c := range list {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		}
+	}
+	roots, next := nextCursor(roots, page, store.CommentCursor)
+	if next == "" {
+		return list, ""
+	}
+
+	// Keep the threads of the remaining top-level comments; parents are
+	// listed before their replies
+	keep := make(map[int]bool, len(list))
+	for _, c := range roots {
+		keep[c.CommentID] = true
+	}
+	var trimmed []database.Comment
+	for _, c := range list {
+		if c.ParentID != nil {
+			keep[c.CommentID] = keep[*c.ParentID]
+		}
+		if keep[c.CommentID] {
+			trimmed = append(trimmed, c)
+		}
+	}
+	return trimmed, next
+}
+
+// threadComments arranges a post's comments, as listed oldest first, into
+// reply threads. It returns the indexes of the top-level comments and of
+// each comment's direct replies. Comments that are not visible are left
+// out and their replies attached to the closest visible ancestor.
+func threadComments(list []database.Comment, visible func(database.Comment) bool) (roots []int, replies map[int][]int) {
+	index := make(map[int]int, len(list))
+	for i, c := range list {
+		index[c.CommentID] = i
+	}
+
+	replies = make(map[int][]int)
+	for i, c := range list {
+		if !visible(c) {
+			continue
+		}
+		parent, ok := -1, false
+		// The step limit guards against a broken chain of parents looping
+		for p, steps := c.ParentID, 0; p != nil && steps < len(list); steps++ {
+			j, found := index[*p]
+			if !found {
+				break
+			}
+			if visible(list[j]) {
+				parent, ok = j, true
+				break
+			}
+			p = list[j].ParentID
+		}
+		if ok {
+			replies[parent] = append(replies[parent], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+	return roots, replies
+}
+
+// deletedWithReplies returns the deleted comments among a post's comments,
+// as listed oldest first, that still have a visible live reply somewhere
+// below them
+func deletedWithReplies(list []database.Comment, visible func(database.Comment) bool) map[int]bool {
+	// Replies are listed after their parents, so walking backwards settles
+	// every reply before its parent
+	shown := make(map[int]bool, len(list))
+	for i := len(list) - 1; i >= 0; i-- {
+		c := list[i]
+		if c.ParentID != nil && ((c.DeletedAt == nil && visible(c)) || shown[c.CommentID]) {
+			shown[*c.ParentID] = true
+		}
+	}
+
+	deleted := make(map[int]bool)
+	for _, c := range list {
+		if c.DeletedAt != nil && shown[c.CommentID] {
+			deleted[c.CommentID] = true
+		}
+	}
+	return deleted
+}
+
+// replyParent finds the comment being replied to among a post's comments,
+// the ID the reply is attached under and the depth it sits at. It is attached
+// under the comment itself, or the closest ancestor that keeps the reply
+// within maxDepth levels of nesting. The returned comment is nil when
+// parentID is not a comment on the post.
+func replyParent(list []database.Comment, parentID, maxDepth int) (*database.Comment, int, int) {
+	byID := make(map[int]*database.Comment, len(list))
+	for i := range list {
+		byID[list[i].CommentID] = &list[i]
+	}
+
+	parent := byID[parentID]
+	if parent == nil {
+		return nil, 0, 0
+	}
+
+	// chain runs from the parent up to its top-level comment
+	chain := []int{parentID}
+	for c := parent; c.ParentID != nil && len(chain) <= len(list); {
+		next := byID[*c.ParentID]
+		if next == nil {
+			break
+		}
+		chain = append(chain, next.CommentID)
+		c = next
+	}
+
+	// A reply to the parent would sit at depth len(chain)
+	if len(chain) > maxDepth {
+		return parent, chain[len(chain)-maxDepth], maxDepth
+	}
+	return parent, parentID, len(chain)
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"forum/internals/database"
+	"forum/internals/store"
+	"forum/internals/utils"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// SaveDraftHandler autosaves the new post form (POST /api/drafts). The first
+// save creates a draft and returns its id, which later saves pass back as
+// draft_id. Unfinished posts are fine: only the image and the length of the
+// content are checked, and categories that no longer exist are dropped.
+func (app *App) SaveDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	draftID := 0
+	if value := r.FormValue("draft_id"); value != "" {
+		var err error
+		if draftID, err = strconv.Atoi(value); err != nil {
+			http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if contentTooLong(r.FormValue("content")) {
+		http.Error(w, errContentTooLong.Error(), http.StatusBadRequest)
+		return
+	}
+
+	imageID, err := app.ownImage(r.Context(), userID, r.FormValue("image_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	categories, err := app.Store.Categories.List(r.Context(), true)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	var categoryIDs []int
+	for _, name := range r.Form["categories[]"] {
+		if i := slices.IndexFunc(categories, func(c database.Category) bool { return c.Name == name }); i >= 0 {
+			categoryIDs = append(categoryIDs, categories[i].CategoryID)
+		}
+	}
+
+	draft := &database.Draft{
+		DraftID: draftID,
+		UserID:  userID,
+		Title:   r.FormValue("title"),
+		Content: r.FormValue("content"),
+		ImageID: imageID,
+		Tags:    r.FormValue("tags"),
+	}
+	draftID, err = app.Store.Drafts.Save(r.Context(), draft, categoryIDs)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Draft not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to save draft", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "id": draftID})
+}
+
+// DraftsAPIHandler returns a page of the user's drafts, last saved first (GET /api/drafts)
+func (app *App) DraftsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := pageParams(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	list, err := app.Store.Drafts.ListByUser(r.Context(), currentUserID(r), page)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	list, next := nextCursor(list, page, store.DraftCursor)
+
+	drafts := []database.DraftResponse{}
+	for _, d := range list {
+		drafts = append(drafts, newDraftResponse(d))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"drafts": drafts, "nextCursor": next})
+}
+
+// DraftAPIHandler returns one of the user's drafts to continue editing (GET /api/drafts/{id})
+func (app *App) DraftAPIHandler(w http.ResponseWriter, r *http.Request) {
+	draft, ok := app.pathDraft(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newDraftResponse(*draft))
+}
+
+// DeleteDraftHandler discards one of the user's drafts (DELETE /api/drafts/{id})
+func (app *App) DeleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	draft, ok := app.pathDraft(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.Store.Drafts.Delete(r.Context(), draft.DraftID); err != nil {
+		http.Error(w, "Failed to delete draft", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// PublishDraftHandler publishes one of the user's drafts as a post, checking
+// it like the new post form does (POST /api/drafts/{id}/publish)
+func (app *App) PublishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	draft, ok := app.pathDraft(w, r)
+	if !ok {
+		return
+	}
+
+	post, categoryIDs, err := app.newPost(r.Context(), draft.UserID, postInput{
+		Title:      draft.Title,
+		Content:    draft.Content,
+		Categories: draft.Categories,
+		Tags:       draft.Tags,
+		Image:      draft.ImageFilename,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	postID, err := app.Store.Drafts.Publish(r.Context(), draft.DraftID, post, categoryIDs)
+	if err != nil {
+		http.Error(w, "Failed to publish draft", http.StatusInternalServerError)
+		return
+	}
+	app.CreateMentionNotifications(postID, nil, draft.UserID, currentUser(r).Username, post.Title, post.Content)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "postId": postID})
+}
+
+// pathDraft loads the draft named by the {id} path value, writing the error
+// response and returning false when it is missing or not the user's
+func (app *App) pathDraft(w http.ResponseWriter, r *http.Request) (*database.Draft, bool) {
+	draftID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid draft ID", http.StatusBadRequest)
+		return nil, false
+	}
+	return app.ownDraft(w, r, draftID)
+}
+
+// ownDraft loads one of the user's drafts, writing the error response and
+// returning false when it is missing or someone else's. Other users' drafts
+// are reported as missing, so their IDs give nothing away.
+func (app *App) ownDraft(w http.ResponseWriter, r *http.Request, draftID int) (*database.Draft, bool) {
+	draft, err := app.Store.Drafts.Get(r.Context(), draftID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && draft.UserID != currentUserID(r)) {
+		http.Error(w, "Draft not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return draft, true
+}
+
+func newDraftResponse(d database.Draft) database.DraftResponse {
+	return database.DraftResponse{
+		ID:         d.DraftID,
+		Title:      d.Title,
+		Content:    d.Content,
+		Categories: d.Categories,
+		Tags:       d.Tags,
+		ImageID:    d.ImageFilename,
+		ImageURL:   d.ImageURL,
+		UpdatedAt:  d.UpdatedAt,
+		TimeAgo:    utils.FormatTimeAgo(d.UpdatedAt),
+	}
+}
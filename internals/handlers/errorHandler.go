@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"forum/internals/utils"
-	"log"
 	"net/http"
 )
 
@@ -45,19 +44,6 @@ func ForbiddenHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// CustomErrorHandler is a middleware to catch errors and serve appropriate pages
-func CustomErrorHandler(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Printf("Panic occurred on %s %s: %v", r.Method, r.URL.Path, err)
-				InternalServerErrorHandler(w, r)
-			}
-		}()
-		next(w, r)
-	}
-}
-
 // ErrorResponse sends a JSON error response for API endpoints
 func ErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
@@ -3,87 +3,55 @@ package handlers
 import (
 	"encoding/json"
 	"forum/internals/database"
-	"forum/internals/utils"
+	"forum/internals/store"
 	"net/http"
-	"time"
 )
 
 // FilteredPostsHandler handles filtering posts by user's created posts and liked posts
-func FilteredPostsHandler(w http.ResponseWriter, r *http.Request) {
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
-		return
-	}
-
-	filter := r.URL.Query().Get("filter")
-	db := database.CreateTable()
-	defer db.Close()
-
-	var query string
-	var args []interface{}
+func (app *App) FilteredPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-	switch filter {
+	var filter store.PostFilter
+	switch r.URL.Query().Get("filter") {
 	case "my-posts":
-		// Get posts created by the user
-		query = `
-			SELECT p.post_id, p.title, p.content, u.username, p.creation_date,
-			       (SELECT COUNT(*) FROM Comments WHERE post_id = p.post_id) as comment_count,
-			       (SELECT COUNT(*) FROM LikesDislikes WHERE post_id = p.post_id AND vote = 1) as like_count
-			FROM Posts p 
-			JOIN Users u ON p.user_id = u.user_id
-			WHERE p.user_id = ?
-			ORDER BY p.creation_date DESC`
-		args = append(args, userID)
+		// Get posts created by the user, including hidden ones
+		filter.AuthorID = userID
+		filter.IncludeHidden = true
 
 	case "my-likes":
 		// Get posts liked by the user
-		query = `
-			SELECT p.post_id, p.title, p.content, u.username, p.creation_date,
-			       (SELECT COUNT(*) FROM Comments WHERE post_id = p.post_id) as comment_count,
-			       (SELECT COUNT(*) FROM LikesDislikes WHERE post_id = p.post_id AND vote = 1) as like_count
-			FROM Posts p 
-			JOIN Users u ON p.user_id = u.user_id
-			JOIN LikesDislikes ld ON p.post_id = ld.post_id
-			WHERE ld.user_id = ? AND ld.vote = 1
-			ORDER BY p.creation_date DESC`
-		args = append(args, userID)
+		filter.VotedBy = userID
+		filter.Vote = 1
 
 	default:
 		http.Error(w, "Invalid filter", http.StatusBadRequest)
 		return
 	}
 
-	rows, err := db.Query(query, args...)
+	page, err := pageParams(r, 20, 100)
 	if err != nil {
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
 
-	var posts []database.PostResponse
-	for rows.Next() {
-		var p database.PostResponse
-		var creationDate time.Time
-		err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.Author, &creationDate, &p.Comments, &p.Likes)
-		if err != nil {
-			continue
-		}
+	if err := postOrder(r, page, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		p.TimeAgo = utils.FormatTimeAgo(creationDate)
-		p.Excerpt = utils.TruncateText(p.Content, 150)
-		p.Tags = getPostTags(db, p.ID)
+	list, err := app.Store.Posts.List(r.Context(), filter, page)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	list, next := nextCursor(list, page, filter.Cursor)
 
-		posts = append(posts, p)
+	posts := []database.PostResponse{}
+	isUser := app.mentionedUsers(r.Context(), postContents(list)...)
+	for _, post := range list {
+		posts = append(posts, newPostResponse(post, isUser))
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(posts)
+	json.NewEncoder(w).Encode(database.PostPage{Posts: posts, NextCursor: next})
 }
@@ -1,22 +1,18 @@
 package handlers
 
 import (
-	"database/sql"
-	"forum/internals/database"
+	"errors"
+	"forum/internals/middleware"
+	"forum/internals/store"
 	"forum/internals/utils"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/google/uuid"
 )
 
-func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		// without POST, just show the forgot password page
-		http.Redirect(w, r, "/forgot-password.html", http.StatusSeeOther)
-		return
-	}
-
+func (app *App) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	email := strings.TrimSpace(r.FormValue("email"))
 	if !utils.IsValidEmail(email) {
 		// 404 if email is invalid
@@ -24,13 +20,9 @@ func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db := database.CreateTable()
-	defer db.Close()
-
 	// if user exists, generate reset token
-	var userID int
-	err := db.QueryRow("SELECT user_id FROM Users WHERE email = ?", email).Scan(&userID)
-	if err != nil && err != sql.ErrNoRows {
+	user, err := app.Store.Users.GetByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
@@ -39,11 +31,21 @@ func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	token := uuid.New().String()
 
 	// save the token in the database
-	_, _ = db.Exec("UPDATE Users SET reset_token = ? WHERE email = ?", token, email)
-
-	// send email (best-effort)
-	_ = SendResetEmail(email, token)
+	_ = app.Store.Users.SetResetToken(r.Context(), email, token)
+
+	// send email (best-effort); the log names the account, not the address
+	if err := app.SendResetEmail(email, token); err != nil {
+		userID := 0
+		if user != nil {
+			userID = user.UserID
+		}
+		slog.ErrorContext(r.Context(), "password reset email",
+			slog.String("request_id", middleware.RequestIDFrom(r.Context())),
+			slog.Int("user_id", userID),
+			slog.Any("error", err),
+		)
+	}
 
 	// redirect to success page
-	http.Redirect(w, r, "/forgot-password.html?sent=1", http.StatusSeeOther)
+	http.Redirect(w, r, "/forgot-password?sent=1", http.StatusSeeOther)
 }
@@ -3,44 +3,42 @@ package handlers
 import (
 	"context"
 	"encoding/json"
-	"forum/internals/database"
+	"errors"
+	"fmt"
+	"forum/internals/config"
+	"forum/internals/store"
 	"forum/internals/utils"
 	"io"
 	"log"
 	"net/http"
-	"fmt"
+	"time"
+
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
 )
 
-var githubOauthConfig = &oauth2.Config{
-	ClientID:     "Ov23liuXrs3tVsAW33pj",
-	ClientSecret: "705fd4bf329d7e3c58d8fc1fea4b88283db935d6",
-	RedirectURL:  "http://localhost:8080/auth/github/callback",
-	Scopes:       []string{"user:email"},
-	Endpoint:     github.Endpoint,
-}
-
-func GitHubLogin(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		url := githubOauthConfig.AuthCodeURL("state-token")
-		http.Redirect(w, r, url, http.StatusTemporaryRedirect)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// newGitHubOauthConfig builds the GitHub OAuth client from the configuration
+func newGitHubOauthConfig(cfg *config.Config) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     cfg.GitHub.ClientID,
+		ClientSecret: cfg.GitHub.ClientSecret,
+		RedirectURL:  cfg.URL("/auth/github/callback"),
+		Scopes:       []string{"user:email"},
+		Endpoint:     github.Endpoint,
 	}
 }
 
-func GitHubCallback(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		handleGitHubCallback(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func (app *App) GitHubLogin(w http.ResponseWriter, r *http.Request) {
+	if !app.Config.GitHub.Enabled() {
+		http.Error(w, "GitHub login is not configured", http.StatusServiceUnavailable)
+		return
 	}
+
+	url := app.githubOauthConfig.AuthCodeURL("state-token")
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-func handleGitHubCallback(w http.ResponseWriter, r *http.Request) {
+func (app *App) GitHubCallback(w http.ResponseWriter, r *http.Request) {
 	code := r.URL.Query().Get("code")
 	if code == "" {
 		http.Error(w, "No code in request", http.StatusBadRequest)
@@ -48,7 +46,7 @@ func handleGitHubCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Exchange the code for access token
-	token, err := githubOauthConfig.Exchange(context.Background(), code)
+	token, err := app.githubOauthConfig.Exchange(context.Background(), code)
 	if err != nil {
 		body, _ := io.ReadAll(r.Body)
 		log.Println("GitHub token exchange error:", err)
@@ -58,7 +56,7 @@ func handleGitHubCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get user data from GitHub
-	client := githubOauthConfig.Client(context.Background(), token)
+	client := app.githubOauthConfig.Client(context.Background(), token)
 	resp, err := client.Get("https://api.github.com/user")
 	if err != nil {
 		log.Println("Failed to get GitHub user:", err)
@@ -92,7 +90,7 @@ func handleGitHubCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Database operations with single-session enforcement
-	userID, isNewUser, err := createOrGetUser(githubUser.Login, githubUser.Email)
+	userID, isNewUser, err := app.createOrGetUser(githubUser.Login, githubUser.Email)
 	if err != nil {
 		log.Println("Database error creating/getting user:", err)
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
@@ -102,12 +100,11 @@ func handleGitHubCallback(w http.ResponseWriter, r *http.Request) {
 	if isNewUser {
 		title := "Welcome to Plant Talk! 🌱"
 		message := fmt.Sprintf("Welcome to our plant-loving community, %s! Start by creating your first post or exploring different plant categories. Happy growing!", githubUser.Login)
-		CreateNotification(userID, "system", title, message, nil, nil, nil)
+		app.CreateNotification(userID, "system", title, message, nil, nil, nil)
 	}
 
-
 	// Create session and redirect
-	if err := createUserSession(w, userID); err != nil {
+	if err := app.createUserSession(w, userID); err != nil {
 		log.Println("Failed to create session:", err)
 		http.Error(w, "Failed to create session", http.StatusInternalServerError)
 		return
@@ -152,41 +149,37 @@ func fetchGitHubUserEmail(client *http.Client) string {
 }
 
 // Separate function for user creation/retrieval
-func createOrGetUser(username, email string) (int, bool, error) {
-	db := database.CreateTable()
-	defer db.Close()
+func (app *App) createOrGetUser(username, email string) (int, bool, error) {
+	ctx := context.Background()
 
-	var userID int
-	err := db.QueryRow("SELECT user_id FROM Users WHERE email = ?", email).Scan(&userID)
-	if err != nil {
+	user, err := app.Store.Users.GetByEmail(ctx, email)
+	if errors.Is(err, store.ErrNotFound) {
 		// Create new user if not exists
-		res, err := db.Exec("INSERT INTO Users (username, email, password_hash) VALUES (?, ?, ?)",
-			username, email, "")
+		userID, err := app.Store.Users.Create(ctx, username, email, "")
 		if err != nil {
 			return 0, false, err
 		}
-		lastID, _ := res.LastInsertId()
-		userID = int(lastID)
 		return userID, true, nil
 	}
+	if err != nil {
+		return 0, false, err
+	}
 
-	return userID, false, nil 
+	return user.UserID, false, nil
 }
 
 // Separate function for session creation
-func createUserSession(w http.ResponseWriter, userID int) error {
-	db := database.CreateTable()
-	defer db.Close()
+func (app *App) createUserSession(w http.ResponseWriter, userID int) error {
+	ctx := context.Background()
 
 	// Cleanup old sessions for this user (single-session enforcement)
-	if _, err := db.Exec("DELETE FROM Sessions WHERE user_id = ?", userID); err != nil {
+	if err := app.Store.Sessions.DeleteForUser(ctx, userID); err != nil {
 		log.Printf("Warning: Failed to cleanup old sessions for user %d: %v\n", userID, err)
 	}
 
 	// Create new session
 	cookieValue := utils.GenerateCookieValue()
-	if _, err := db.Exec("INSERT INTO Sessions (user_id, cookie_value, expiration_date) VALUES (?, ?, datetime('now', '+7 days'))",
-		userID, cookieValue); err != nil {
+	if err := app.Store.Sessions.Create(ctx, userID, cookieValue, utils.GenerateCookieValue(), time.Now().Add(7*24*time.Hour)); err != nil {
 		return err
 	}
 
@@ -195,10 +188,10 @@ func createUserSession(w http.ResponseWriter, userID int) error {
 		Name:     "session",
 		Value:    cookieValue,
 		Path:     "/",
-		MaxAge:   60 * 60 * 24 * 7, // 7 days
-		HttpOnly: true,              // Prevent XSS attacks
+		MaxAge:   60 * 60 * 24 * 7,     // 7 days
+		HttpOnly: true,                 // Prevent XSS attacks
 		SameSite: http.SameSiteLaxMode, // CSRF protection
 	})
 
 	return nil
-}
\ No newline at end of file
+}
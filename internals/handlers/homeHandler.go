@@ -6,20 +6,19 @@ import (
 )
 
 // HomeHandler handles the main homepage route
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
-
-	if r.URL.Path != "/" {
-		NotFoundHandler(w, r)
-		return
-	}
-
-	if cookie, err := r.Cookie("session"); err == nil && utils.IsValidSession(cookie.Value) {
+func (app *App) HomeHandler(w http.ResponseWriter, r *http.Request) {
+	if currentUser(r) != nil {
 		utils.FileService("index-signed.html", w, nil)
 	} else {
 		utils.FileService("index-unsigned.html", w, nil)
 	}
 }
 
+// NewPostPageHandler serves the post creation form
+func NewPostPageHandler(w http.ResponseWriter, r *http.Request) {
+	utils.FileService("new-post.html", w, nil)
+}
+
 // ViewPostHandler serves the view-post page
 func ViewPostHandler(w http.ResponseWriter, r *http.Request) {
 	utils.FileService("view-post.html", w, nil)
@@ -44,17 +43,14 @@ func ForgotPasswordPageHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func ProfilePageHandler(w http.ResponseWriter, r *http.Request) {
-	if cookie, err := r.Cookie("session"); err == nil && utils.IsValidSession(cookie.Value) {
-		utils.FileService("profile.html", w, nil)
-	} else {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-	}
+	utils.FileService("profile.html", w, nil)
+}
+
+// UserPageHandler serves the page listing a user's posts, where mentions link to
+func UserPageHandler(w http.ResponseWriter, r *http.Request) {
+	utils.FileService("user.html", w, nil)
 }
 
 func NotificationsPageHandler(w http.ResponseWriter, r *http.Request) {
-	if cookie, err := r.Cookie("session"); err == nil && utils.IsValidSession(cookie.Value) {
-		utils.FileService("notifications.html", w, nil)
-	} else {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-	}
+	utils.FileService("notifications.html", w, nil)
 }
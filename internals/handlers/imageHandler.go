@@ -3,7 +3,6 @@ package handlers
 import (
 	"fmt"
 	"forum/internals/database"
-	"forum/internals/utils"
 	"image"
 	"image/gif"
 	"image/jpeg"
@@ -26,27 +25,11 @@ const (
 )
 
 // ImageUploadHandler handles image upload for posts
-func ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
-		return
-	}
+func (app *App) ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
 	// Parse multipart form (32MB max memory)
-	err = r.ParseMultipartForm(32 << 20)
+	err := r.ParseMultipartForm(32 << 20)
 	if err != nil {
 		http.Error(w, "Error parsing form", http.StatusBadRequest)
 		return
@@ -109,9 +92,6 @@ func ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save image info to database
-	db := database.CreateTable()
-	defer db.Close()
-
 	imageURL := "/frontend/uploads/images/" + filename
 	thumbnailURL := ""
 	if thumbnailPath != "" {
@@ -124,10 +104,16 @@ func ImageUploadHandler(w http.ResponseWriter, r *http.Request) {
 		imageType = "post" // Default to post image
 	}
 
-	_, err = db.Exec(`
-    INSERT INTO Images (user_id, filename, original_name, file_size, file_type, image_type, image_url, thumbnail_url)
-    VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-`, userID, filename, fileHeader.Filename, fileHeader.Size, fileType, imageType, imageURL, thumbnailURL)
+	err = app.Store.Images.Create(r.Context(), &database.Image{
+		UserID:       userID,
+		Filename:     filename,
+		OriginalName: fileHeader.Filename,
+		FileSize:     fileHeader.Size,
+		FileType:     fileType,
+		ImageType:    imageType,
+		ImageURL:     imageURL,
+		ThumbnailURL: thumbnailURL,
+	})
 
 	if err != nil {
 		// Clean up files on database error
@@ -259,20 +245,8 @@ func sanitizeFilename(filename string) string {
 }
 
 // DeleteImageHandler handles image deletion
-func DeleteImageHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
+func (app *App) DeleteImageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 	filename := r.FormValue("filename")
 
 	if filename == "" {
@@ -280,19 +254,15 @@ func DeleteImageHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db := database.CreateTable()
-	defer db.Close()
-
 	// Verify user owns this image
-	var imageUserID int
-	var imageURL, thumbnailURL string
-	err = db.QueryRow("SELECT user_id, image_url, thumbnail_url FROM Images WHERE filename = ?", filename).Scan(&imageUserID, &imageURL, &thumbnailURL)
+	img, err := app.Store.Images.GetByFilename(r.Context(), filename)
 	if err != nil {
 		http.Error(w, "Image not found", http.StatusNotFound)
 		return
 	}
+	imageURL, thumbnailURL := img.ImageURL, img.ThumbnailURL
 
-	if imageUserID != userID {
+	if img.UserID != userID {
 		http.Error(w, "Unauthorized to delete this image", http.StatusForbidden)
 		return
 	}
@@ -307,8 +277,8 @@ func DeleteImageHandler(w http.ResponseWriter, r *http.Request) {
 		os.Remove(filepath.Join(ThumbnailDir, thumbnailPath))
 	}
 
-	// Delete from database
-	_, err = db.Exec("DELETE FROM Images WHERE filename = ?", filename)
+	// Delete from database, detaching the image from any posts using it
+	err = app.Store.Images.Delete(r.Context(), filename)
 	if err != nil {
 		http.Error(w, "Error deleting image record", http.StatusInternalServerError)
 		return
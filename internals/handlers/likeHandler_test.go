@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// TestVoteUnavailable checks that votes on missing, trashed or hidden posts
+// and comments are refused before anything is stored or published
+func TestVoteUnavailable(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// setup returns the vote path and the post whose stream must stay quiet
+		setup func(app *testApp, author testUser) (path string, postID int)
+	}{
+		{"missing post", func(app *testApp, author testUser) (string, int) {
+			return "/api/posts/999/vote", 999
+		}},
+		{"trashed post", func(app *testApp, author testUser) (string, int) {
+			postID := app.newPost(t, author)
+			app.Store.Posts.Delete(ctx, postID, author.id)
+			return "/api/posts/" + strconv.Itoa(postID) + "/vote", postID
+		}},
+		{"hidden post", func(app *testApp, author testUser) (string, int) {
+			postID := app.newPost(t, author)
+			app.Store.Posts.SetHidden(ctx, postID, true)
+			return "/api/posts/" + strconv.Itoa(postID) + "/vote", postID
+		}},
+		{"missing comment", func(app *testApp, author testUser) (string, int) {
+			return "/api/comments/999/vote", app.newPost(t, author)
+		}},
+		{"trashed comment", func(app *testApp, author testUser) (string, int) {
+			postID := app.newPost(t, author)
+			commentID := app.newComment(t, author, postID)
+			app.Store.Comments.Delete(ctx, commentID, author.id)
+			return "/api/comments/" + strconv.Itoa(commentID) + "/vote", postID
+		}},
+		{"comment on trashed post", func(app *testApp, author testUser) (string, int) {
+			postID := app.newPost(t, author)
+			commentID := app.newComment(t, author, postID)
+			app.Store.Posts.Delete(ctx, postID, author.id)
+			return "/api/comments/" + strconv.Itoa(commentID) + "/vote", postID
+		}},
+		{"hidden comment", func(app *testApp, author testUser) (string, int) {
+			postID := app.newPost(t, author)
+			commentID := app.newComment(t, author, postID)
+			app.Store.Comments.SetHidden(ctx, commentID, true)
+			return "/api/comments/" + strconv.Itoa(commentID) + "/vote", postID
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+			alice, bob := app.newUser(t, "alice"), app.newUser(t, "bob")
+			path, postID := tt.setup(app, alice)
+
+			// Neither a visitor to the content nor its author may vote on it
+			for _, voter := range []testUser{bob, alice} {
+				if w := app.post(voter, path, url.Values{"vote": {"1"}}); w.Code != http.StatusNotFound {
+					t.Errorf("got status %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+				}
+			}
+			sub, events, err := app.Events.Subscribe(postTopic(postID), 0, eventHistory)
+			if err != nil {
+				t.Fatalf("subscribing: %v", err)
+			}
+			sub.Close()
+			if len(events) != 0 {
+				t.Errorf("published %d events, want none", len(events))
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"forum/internals/database"
+	"forum/internals/events"
+	"forum/internals/markdown"
+	"forum/internals/store"
+	"forum/internals/utils"
+	"net/http"
+)
+
+// The events streamed to the pages showing a post
+const (
+	eventCommentCreated = "comment-created"
+	eventCommentEdited  = "comment-edited"
+	eventCommentDeleted = "comment-deleted"
+	eventPostVotes      = "post-votes"
+	eventCommentVotes   = "comment-votes"
+)
+
+// liveComment is a new or edited comment as published on its post's stream.
+// Each viewer's stream fills in IsAuthor and CanEdit for them, and skips
+// hidden comments they may not see.
+type liveComment struct {
+	Comment database.CommentResponse `json:"comment"`
+	// CommentCount is the number of comments on the post after the change
+	CommentCount int `json:"commentCount"`
+
+	authorID int
+}
+
+// liveDeletion announces that a comment was moved to the trash
+type liveDeletion struct {
+	ID           int `json:"id"`
+	CommentCount int `json:"commentCount"`
+}
+
+// liveVotes carries the new vote counts of a post, or of one of its comments
+// when CommentID is set. Only the voter's own stream gets their vote.
+type liveVotes struct {
+	CommentID    int  `json:"commentId,omitempty"`
+	LikeCount    int  `json:"likeCount"`
+	DislikeCount int  `json:"dislikeCount"`
+	UserVote     *int `json:"userVote,omitempty"`
+
+	voterID int
+	vote    int
+}
+
+// postTopic is the event topic of a post's comments and votes
+func postTopic(postID int) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// PostStreamHandler streams the new, edited and deleted comments of a post
+// and its changing vote counts as Server-Sent Events (GET /api/posts/{id}/stream).
+// Viewers past Live.MaxPostViewers get a 503 and keep the page without live
+// updates.
+func (app *App) PostStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if app.Config.Live.MaxPostViewers == 0 {
+		http.Error(w, "Live updates are turned off", http.StatusNotFound)
+		return
+	}
+
+	postID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	post, err := app.Store.Posts.Get(r.Context(), postID)
+	if err == nil && post.Hidden && !canModify(r, post.UserID) {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "Post not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	app.streamEvents(w, r, stream{
+		topic:     postTopic(postID),
+		limit:     app.Config.Live.MaxPostViewers,
+		fullError: "Too many live viewers, try again later",
+		view: func(e events.Event) (events.Event, bool) {
+			return liveView(r, e)
+		},
+	})
+}
+
+// liveView adapts a post event to the viewer making the request
+func liveView(r *http.Request, e events.Event) (events.Event, bool) {
+	viewerID := currentUserID(r)
+
+	switch data := e.Data.(type) {
+	case liveComment:
+		if data.Comment.Hidden && !canModify(r, data.authorID) {
+			return e, false
+		}
+		data.Comment.IsAuthor = viewerID > 0 && viewerID == data.authorID
+		data.Comment.CanEdit = canModify(r, data.authorID)
+		e.Data = data
+	case liveVotes:
+		if viewerID > 0 && viewerID == data.voterID {
+			vote := data.vote
+			data.UserVote = &vote
+		}
+		e.Data = data
+	}
+	return e, true
+}
+
+// publishComment announces a comment just created or edited to the pages
+// showing its post; depth is where a new comment sits in its thread
+func (app *App) publishComment(ctx context.Context, eventName string, commentID, depth int) {
+	comment, err := app.Store.Comments.Get(ctx, commentID)
+	if err != nil {
+		return
+	}
+	post, err := app.Store.Posts.Get(ctx, comment.PostID)
+	if err != nil {
+		return
+	}
+
+	_ = app.Events.Publish(postTopic(comment.PostID), eventName, liveComment{
+		Comment: database.CommentResponse{
+			ID:           comment.CommentID,
+			PostID:       comment.PostID,
+			ParentID:     comment.ParentID,
+			Depth:        depth,
+			Author:       comment.Username,
+			Content:      comment.Content,
+			ContentHTML:  markdown.Render(comment.Content, app.mentionedUsers(ctx, comment.Content)),
+			TimeAgo:      utils.FormatTimeAgo(comment.CreationDate),
+			LikeCount:    comment.NbrLike,
+			DislikeCount: comment.NbrDislike,
+			Hidden:       comment.Hidden,
+			Edited:       comment.EditedAt != nil,
+			EditedAt:     comment.EditedAt,
+		},
+		CommentCount: post.Nbrcomments,
+		authorID:     comment.UserID,
+	})
+}
+
+// publishCommentDeleted announces a comment moved to the trash to the pages
+// showing its post
+func (app *App) publishCommentDeleted(ctx context.Context, postID, commentID int) {
+	post, err := app.Store.Posts.Get(ctx, postID)
+	if err != nil {
+		return
+	}
+	_ = app.Events.Publish(postTopic(postID), eventCommentDeleted, liveDeletion{
+		ID:           commentID,
+		CommentCount: post.Nbrcomments,
+	})
+}
+
+// publishVotes announces the vote counts of a post, or of one of its
+// comments, after a user voted on it
+func (app *App) publishVotes(postID, commentID, voterID int, stats database.LikeResponse) {
+	eventName := eventPostVotes
+	if commentID != 0 {
+		eventName = eventCommentVotes
+	}
+	_ = app.Events.Publish(postTopic(postID), eventName, liveVotes{
+		CommentID:    commentID,
+		LikeCount:    stats.LikeCount,
+		DislikeCount: stats.DislikeCount,
+		voterID:      voterID,
+		vote:         stats.UserVote,
+	})
+}
@@ -1,9 +1,9 @@
 package handlers
 
 import (
-	"fmt"
-	"forum/internals/database"
+	"forum/internals/middleware"
 	"forum/internals/utils"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
@@ -11,27 +11,25 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		// Check for success message from registration
-		successType := r.URL.Query().Get("success")
-		message := r.URL.Query().Get("message")
+// LoginPageHandler serves the login form
+func LoginPageHandler(w http.ResponseWriter, r *http.Request) {
+	// Check for success message from registration
+	successType := r.URL.Query().Get("success")
+	message := r.URL.Query().Get("message")
 
-		data := make(map[string]interface{})
+	data := make(map[string]interface{})
 
-		if successType == "registration" {
-			data["SuccessMessage"] = "Registration successful! Please log in with your new account."
-		} else if message != "" {
-			data["SuccessMessage"] = message
-		}
-
-		utils.FileService("login.html", w, data)
-		return
+	if successType == "registration" {
+		data["SuccessMessage"] = "Registration successful! Please log in with your new account."
+	} else if message != "" {
+		data["SuccessMessage"] = message
 	}
 
-	db := database.CreateTable()
-	defer db.Close()
+	utils.FileService("login.html", w, data)
+}
 
+// LoginHandler authenticates the submitted credentials
+func (app *App) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	emailOrUsername := strings.TrimSpace(r.FormValue("email"))
 	password := r.FormValue("password")
 
@@ -42,15 +40,8 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var userID int
-	var passwordHash string
-
-	err := db.QueryRow(
-		"SELECT user_id, password_hash FROM Users WHERE email = ? OR username = ?",
-		emailOrUsername, emailOrUsername,
-	).Scan(&userID, &passwordHash)
-
-	if err != nil || bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) != nil {
+	user, err := app.Store.Users.GetByLogin(r.Context(), emailOrUsername)
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
 		utils.FileService("login.html", w, map[string]interface{}{
 			"ErrorMessage": "Invalid email/username or password",
 		})
@@ -58,15 +49,26 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Cleanup old sessions for this user
-	_, err = db.Exec("DELETE FROM Sessions WHERE user_id = ?", userID)
+	userID := user.UserID
+	err = app.Store.Sessions.DeleteForUser(r.Context(), userID)
 	if err != nil {
-		fmt.Printf("Warning: Failed to cleanup old sessions for user %d: %v\n", userID, err)
+		slog.ErrorContext(r.Context(), "delete old sessions",
+			slog.String("request_id", middleware.RequestIDFrom(r.Context())),
+			slog.Int("user_id", userID),
+			slog.Any("error", err),
+		)
 	}
 
 	// Create secure session cookie
 	cookieValue := utils.GenerateCookieValue()
 	expiration := time.Now().Add(24 * time.Hour)
 
+	// Store session in database, with a fresh CSRF token
+	if err := app.Store.Sessions.Create(r.Context(), userID, cookieValue, utils.GenerateCookieValue(), expiration); err != nil {
+		InternalServerErrorHandler(w, r)
+		return
+	}
+
 	// Set session cookie with SameSite
 	http.SetCookie(w, &http.Cookie{
 		Name:     "session",
@@ -77,10 +79,6 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		SameSite: http.SameSiteLaxMode,
 	})
 
-	// Store session in database
-	database.Insert(db, "Sessions", "(user_id, cookie_value, expiration_date)", userID, cookieValue, expiration)
-
-
 	// Redirect to home
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -1,97 +1,78 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"forum/internals/database"
+	"forum/internals/events"
+	"forum/internals/markdown"
+	"forum/internals/middleware"
+	"forum/internals/store"
 	"forum/internals/utils"
+	"log/slog"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 )
 
 // NotificationsAPIHandler returns real user notifications from database
-func NotificationsAPIHandler(w http.ResponseWriter, r *http.Request) {
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+//
+// Both lists start with their newest notifications; ?status=unread or
+// ?status=read with a cursor from the response continues just that list.
+func (app *App) NotificationsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	// Check for pagination parameters
+	page, err := pageParams(r, 20, 50)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
 		return
 	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "unread" && status != "read" {
+		http.Error(w, "Status must be unread or read", http.StatusBadRequest)
 		return
 	}
-
-	// Check for pagination parameters
-	page := getIntParam(r, "page", 1)
-	limit := getIntParam(r, "limit", 20)
-	if limit > 50 {
-		limit = 50 // Prevent too large requests
+	if page.After != nil && status == "" {
+		http.Error(w, "A cursor needs a status", http.StatusBadRequest)
+		return
 	}
 
-	db := database.CreateTable()
-	defer db.Close()
+	response := database.NotificationResponse{
+		Unread: []database.Notification{},
+		Read:   []database.Notification{},
+	}
 
 	// Get unread notifications
-	unreadNotifications := getNotificationsWithPagination(db, userID, false, page, limit)
+	if status != "read" {
+		response.Unread, response.NextUnreadCursor = app.getNotificationsPage(r.Context(), userID, false, page)
+	}
 
 	// Get read notifications
-	readNotifications := getNotificationsWithPagination(db, userID, true, page, limit)
-
-	if unreadNotifications == nil {
-		unreadNotifications = make([]database.Notification, 0)
-	}
-	if readNotifications == nil {
-		readNotifications = make([]database.Notification, 0)
+	if status != "unread" {
+		response.Read, response.NextReadCursor = app.getNotificationsPage(r.Context(), userID, true, page)
 	}
 
-	response := database.NotificationResponse{Unread: unreadNotifications, Read: readNotifications}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(response)
 }
 
-// MarkNotificationReadHandler marks a notification as read
-func MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// MarkNotificationReadHandler marks a notification as read (POST /api/notifications/{id}/read)
+func (app *App) MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
-		return
-	}
-
-	notificationIDStr := r.FormValue("notification_id")
-	notificationID, err := strconv.Atoi(notificationIDStr)
+	notificationID, err := pathID(r)
 	if err != nil {
 		http.Error(w, "Invalid notification ID", http.StatusBadRequest)
 		return
 	}
 
-	db := database.CreateTable()
-	defer db.Close()
-
 	// Verify notification belongs to user before marking as read
-	var existingUserID int
-	var isRead bool
-	err = db.QueryRow("SELECT user_id, is_read FROM Notifications WHERE notification_id = ?", notificationID).Scan(&existingUserID, &isRead)
+	notification, err := app.Store.Notifications.Get(r.Context(), notificationID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "Notification not found", http.StatusNotFound)
 		} else {
 			http.Error(w, "Database error", http.StatusInternalServerError)
@@ -99,16 +80,17 @@ func MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if existingUserID != userID {
+	if notification.UserID != userID {
 		http.Error(w, "Unauthorized to modify this notification", http.StatusForbidden)
 		return
 	}
 
-	if !isRead {
-		if _, err := db.Exec("UPDATE Notifications SET is_read = 1 WHERE notification_id = ?", notificationID); err != nil {
+	if !notification.IsRead {
+		if err := app.Store.Notifications.MarkRead(r.Context(), notificationID); err != nil {
 			http.Error(w, "Failed to mark read", http.StatusInternalServerError)
 			return
 		}
+		app.publishUnreadCount(userID)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -116,101 +98,99 @@ func MarkNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // MarkAllNotificationsReadHandler marks all notifications as read for a user
-func MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
-		return
-	}
+func (app *App) MarkAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-	db := database.CreateTable()
-	defer db.Close()
-
-	if _, err := db.Exec("UPDATE Notifications SET is_read = 1 WHERE user_id = ? AND (is_read = 0 OR is_read IS NULL)", userID); err != nil {
+	if err := app.Store.Notifications.MarkAllRead(r.Context(), userID); err != nil {
 		http.Error(w, "Failed to mark all as read", http.StatusInternalServerError)
 		return
 	}
+	app.publishUnreadCount(userID)
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]bool{"success": true})
 }
 
-func setBusyTimeout(db *sql.DB) {
-	_, _ = db.Exec("PRAGMA busy_timeout=5000;")
-}
-
-func execWithRetry(db *sql.DB, query string, args ...any) (sql.Result, error) {
-	var lastErr error
-	for i := 0; i < 5; i++ {
-		res, err := db.Exec(query, args...)
-		if err == nil {
-			return res, nil
-		}
-		msg := err.Error()
-		if strings.Contains(msg, "database is locked") || strings.Contains(msg, "busy") {
-			// exponential-ish backoff: 100ms, 200ms, 400ms, 800ms, 1200ms
-			time.Sleep(time.Duration(100*(1<<min(i, 3))) * time.Millisecond)
-			lastErr = err
-			continue
-		}
-		return nil, err
-	}
-	if lastErr == nil {
-		lastErr = errors.New("execWithRetry: unknown error")
-	}
-	return nil, lastErr
-}
-
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 // CreateNotification creates a new notification for a user
-func CreateNotification(userID int, notificationType, title, message string, relatedPostID, relatedCommentID, relatedUserID *int) error {
+func (app *App) CreateNotification(userID int, notificationType, title, message string, relatedPostID, relatedCommentID, relatedUserID *int) error {
 	// Do not create notification for user's own actions
 	if userID == 0 || (relatedUserID != nil && *relatedUserID == userID) {
 		return nil
 	}
 
-	db := database.CreateTable()
-	defer db.Close()
-
-	setBusyTimeout(db)
+	n := &database.Notification{
+		UserID:           userID,
+		Type:             notificationType,
+		Title:            title,
+		Message:          message,
+		RelatedPostID:    relatedPostID,
+		RelatedCommentID: relatedCommentID,
+		RelatedUserID:    relatedUserID,
+	}
 
 	//Check if notification already exists
-	if skipDuplicateNotification(db, userID, notificationType, relatedPostID, relatedCommentID, relatedUserID) {
+	ctx := context.Background()
+	if dup, err := app.Store.Notifications.HasRecent(ctx, n, time.Hour); err == nil && dup {
 		return nil
 	}
 
-	_, err := execWithRetry(db, `
-		INSERT INTO Notifications (user_id, type, title, message, related_post_id, related_comment_id, related_user_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		userID, notificationType, title, message, relatedPostID, relatedCommentID, relatedUserID)
-	return err
+	if err := app.Store.Notifications.Create(ctx, n); err != nil {
+		return err
+	}
+	app.publishNotification(n.NotificationID)
+	return nil
+}
+
+// NotificationStreamHandler streams the user's new notifications and unread
+// count as Server-Sent Events (GET /api/notifications/stream), starting with
+// the current count. Streams past Live.MaxUserStreams for one user get a 429.
+func (app *App) NotificationStreamHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+	app.streamEvents(w, r, stream{
+		topic:      notificationTopic(userID),
+		limit:      app.Config.Live.MaxUserStreams,
+		fullStatus: http.StatusTooManyRequests,
+		fullError:  "Too many open notification streams",
+		initial: func() ([]events.Event, error) {
+			count, err := app.Store.Notifications.UnreadCount(r.Context(), userID)
+			if err != nil {
+				return nil, err
+			}
+			return []events.Event{{Name: "count", Data: map[string]int{"count": count}}}, nil
+		},
+	})
+}
+
+// notificationTopic is the event topic of a user's notifications
+func notificationTopic(userID int) string {
+	return fmt.Sprintf("notifications:%d", userID)
 }
 
-func CreateCommentNotification(postID int, commentID int, commenterID int, commenterUsername string, postTitle string) {
-	db := database.CreateTable()
-	defer db.Close()
+// publishNotification pushes a new notification, then the unread count, to
+// the pages its user has open
+func (app *App) publishNotification(notificationID int) {
+	n, err := app.Store.Notifications.Get(context.Background(), notificationID)
+	if err != nil {
+		return
+	}
+	n.TimeAgo = utils.FormatTimeAgo(n.CreationDate)
+	_ = app.Events.Publish(notificationTopic(n.UserID), "notification", n)
+	app.publishUnreadCount(n.UserID)
+}
 
+// publishUnreadCount pushes a user's unread notification count to their open pages
+func (app *App) publishUnreadCount(userID int) {
+	count, err := app.Store.Notifications.UnreadCount(context.Background(), userID)
+	if err != nil {
+		return
+	}
+	_ = app.Events.Publish(notificationTopic(userID), "count", map[string]int{"count": count})
+}
+
+func (app *App) CreateCommentNotification(postID int, commentID int, commenterID int, commenterUsername string, postTitle string) {
 	//Get the author of the post
-	var postAuthorID int
-	if err := db.QueryRow("SELECT user_id FROM Posts WHERE post_id = ?", postID).Scan(&postAuthorID); err != nil {
+	postAuthorID, err := app.postAuthorID(postID)
+	if err != nil {
 		return
 	}
 	if postAuthorID == commenterID {
@@ -220,16 +200,13 @@ func CreateCommentNotification(postID int, commentID int, commenterID int, comme
 	title := "New Comment!"
 	message := fmt.Sprintf("%s commented on your post '%s'", commenterUsername, utils.TruncateText(postTitle, 50))
 
-	_ = CreateNotification(postAuthorID, "comment", title, message, &postID, &commentID, &commenterID)
+	_ = app.CreateNotification(postAuthorID, "comment", title, message, &postID, &commentID, &commenterID)
 }
 
-func CreateLikeNotification(postID int, likerID int, likerUsername string, postTitle string) {
-	db := database.CreateTable()
-	defer db.Close()
-
+func (app *App) CreateLikeNotification(postID int, likerID int, likerUsername string, postTitle string) {
 	// Get the author of the post
-	var postAuthorID int
-	if err := db.QueryRow("SELECT user_id FROM Posts WHERE post_id = ?", postID).Scan(&postAuthorID); err != nil {
+	postAuthorID, err := app.postAuthorID(postID)
+	if err != nil {
 		return
 	}
 	if postAuthorID == likerID {
@@ -239,17 +216,14 @@ func CreateLikeNotification(postID int, likerID int, likerUsername string, postT
 	title := "New Like!"
 	message := fmt.Sprintf("%s liked your post '%s'", likerUsername, utils.TruncateText(postTitle, 50))
 
-	_ = CreateNotification(postAuthorID, "like", title, message, &postID, nil, &likerID)
+	_ = app.CreateNotification(postAuthorID, "like", title, message, &postID, nil, &likerID)
 }
 
 // CreateDislikeNotification creates notification for post dislikes
-func CreateDislikeNotification(postID int, dislikerID int, dislikerUsername string, postTitle string) {
-	db := database.CreateTable()
-	defer db.Close()
-
+func (app *App) CreateDislikeNotification(postID int, dislikerID int, dislikerUsername string, postTitle string) {
 	// Get the author of the post
-	var postAuthorID int
-	if err := db.QueryRow("SELECT user_id FROM Posts WHERE post_id = ?", postID).Scan(&postAuthorID); err != nil {
+	postAuthorID, err := app.postAuthorID(postID)
+	if err != nil {
 		return
 	}
 	if postAuthorID == dislikerID {
@@ -259,15 +233,12 @@ func CreateDislikeNotification(postID int, dislikerID int, dislikerUsername stri
 	title := "Someone disagreed with your post"
 	message := fmt.Sprintf("%s disliked your post '%s'", dislikerUsername, utils.TruncateText(postTitle, 50))
 
-	_ = CreateNotification(postAuthorID, "dislike", title, message, &postID, nil, &dislikerID)
+	_ = app.CreateNotification(postAuthorID, "dislike", title, message, &postID, nil, &dislikerID)
 }
 
-func CreateCommentLikeNotification(commentID, likerID int, likerUsername, postTitle string, postID int) {
-	db := database.CreateTable()
-	defer db.Close()
-
-	var commentAuthorID int
-	if err := db.QueryRow("SELECT user_id FROM Comments WHERE comment_id = ?", commentID).Scan(&commentAuthorID); err != nil {
+func (app *App) CreateCommentLikeNotification(commentID, likerID int, likerUsername, postTitle string, postID int) {
+	commentAuthorID, err := app.commentAuthorID(commentID)
+	if err != nil {
 		return
 	}
 	if commentAuthorID == likerID {
@@ -276,16 +247,13 @@ func CreateCommentLikeNotification(commentID, likerID int, likerUsername, postTi
 
 	title := "Your comment got a like!"
 	message := fmt.Sprintf("%s liked your comment on '%s'", likerUsername, utils.TruncateText(postTitle, 50))
-	_ = CreateNotification(commentAuthorID, "like", title, message, &postID, &commentID, &likerID)
+	_ = app.CreateNotification(commentAuthorID, "like", title, message, &postID, &commentID, &likerID)
 }
 
 // CreateCommentDislikeNotification creates notification for comment dislikes
-func CreateCommentDislikeNotification(commentID, dislikerID int, dislikerUsername, postTitle string, postID int) {
-	db := database.CreateTable()
-	defer db.Close()
-
-	var commentAuthorID int
-	if err := db.QueryRow("SELECT user_id FROM Comments WHERE comment_id = ?", commentID).Scan(&commentAuthorID); err != nil {
+func (app *App) CreateCommentDislikeNotification(commentID, dislikerID int, dislikerUsername, postTitle string, postID int) {
+	commentAuthorID, err := app.commentAuthorID(commentID)
+	if err != nil {
 		return
 	}
 	if commentAuthorID == dislikerID {
@@ -294,19 +262,20 @@ func CreateCommentDislikeNotification(commentID, dislikerID int, dislikerUsernam
 
 	title := "Someone disagreed with your comment"
 	message := fmt.Sprintf("%s disliked your comment on '%s'", dislikerUsername, utils.TruncateText(postTitle, 50))
-	_ = CreateNotification(commentAuthorID, "dislike", title, message, &postID, &commentID, &dislikerID)
+	_ = app.CreateNotification(commentAuthorID, "dislike", title, message, &postID, &commentID, &dislikerID)
 }
 
 // CreateFollowupCommentNotifications notifies ALL previous commenters on a post (except author & current commenter)
 // This implements "watching" functionality - once you comment on a post, you follow that discussion
-func CreateFollowupCommentNotifications(postID, commentID, commenterID int, commenterUsername, postTitle string) {
-	db := database.CreateTable()
-	defer db.Close()
-
+func (app *App) CreateFollowupCommentNotifications(r *http.Request, postID, commentID, commenterID int, commenterUsername, postTitle string) {
 	// 1) Find the post author (they don't get notified here, they get separate notification)
-	var postAuthorID int
-	if err := db.QueryRow("SELECT user_id FROM Posts WHERE post_id = ?", postID).Scan(&postAuthorID); err != nil {
-		fmt.Printf("[FollowupNotify] post=%d: cannot load postAuthorID: %v\n", postID, err)
+	postAuthorID, err := app.postAuthorID(postID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "load post author for followup notifications",
+			slog.String("request_id", middleware.RequestIDFrom(r.Context())),
+			slog.Int("post_id", postID),
+			slog.Any("error", err),
+		)
 		return
 	}
 
@@ -315,35 +284,25 @@ func CreateFollowupCommentNotifications(postID, commentID, commenterID int, comm
 	//    - NOT the current commenter
 	//    - NOT the post author
 	//    DISTINCT ensures 1 notification per user even if they have multiple comments
-	rows, err := db.Query(`
-		SELECT DISTINCT c.user_id
-		FROM Comments AS c
-		WHERE c.post_id = ?
-		  AND c.user_id != ?
-		  AND c.user_id != ?
-	`, postID, commenterID, postAuthorID)
+	watchers, err := app.Store.Comments.Commenters(context.Background(), postID, commenterID, postAuthorID)
 	if err != nil {
-		fmt.Printf("[FollowupNotify] post=%d: query watchers failed: %v\n", postID, err)
+		slog.ErrorContext(r.Context(), "list post commenters",
+			slog.String("request_id", middleware.RequestIDFrom(r.Context())),
+			slog.Int("post_id", postID),
+			slog.Any("error", err),
+		)
 		return
 	}
-	defer rows.Close()
 
 	title := "New activity on a post you commented"
 	msg := fmt.Sprintf("%s also commented on '%s'", commenterUsername, utils.TruncateText(postTitle, 50))
-	var count int
-
-	for rows.Next() {
-		var watcherID int
-		if err := rows.Scan(&watcherID); err != nil {
-			fmt.Printf("[FollowupNotify] post=%d: scan watcher err: %v\n", postID, err)
-			continue
-		}
 
+	for _, watcherID := range watchers {
 		// 3) Send notification to each watcher
 		//    related_post_id = postID
 		//    related_comment_id = the NEW comment (for anti-spam uniqueness)
 		//    related_user_id = commenterID (who made the new comment)
-		err := CreateNotification(
+		err := app.CreateNotification(
 			watcherID,
 			"comment",
 			title,
@@ -353,24 +312,21 @@ func CreateFollowupCommentNotifications(postID, commentID, commenterID int, comm
 			&commenterID,
 		)
 		if err != nil {
-			fmt.Printf("[FollowupNotify] post=%d newComment=%d -> watcher=%d ERROR sending: %v\n",
-				postID, commentID, watcherID, err)
-			continue
+			slog.ErrorContext(r.Context(), "notify post commenter",
+				slog.String("request_id", middleware.RequestIDFrom(r.Context())),
+				slog.Int("post_id", postID),
+				slog.Int("comment_id", commentID),
+				slog.Int("user_id", watcherID),
+				slog.Any("error", err),
+			)
 		}
-		count++
-	}
-
-	if count == 0 {
 	}
 }
 
 // CreateDirectReplyNotification notifies the author of a parent comment when someone replies directly to them
-func CreateDirectReplyNotification(parentCommentID, newCommentID, replierID int, replierUsername, postTitle string, postID int) {
-	db := database.CreateTable()
-	defer db.Close()
-
-	var parentAuthorID int
-	if err := db.QueryRow("SELECT user_id FROM Comments WHERE comment_id = ?", parentCommentID).Scan(&parentAuthorID); err != nil {
+func (app *App) CreateDirectReplyNotification(parentCommentID, newCommentID, replierID int, replierUsername, postTitle string, postID int) {
+	parentAuthorID, err := app.commentAuthorID(parentCommentID)
+	if err != nil {
 		return
 	}
 
@@ -381,127 +337,120 @@ func CreateDirectReplyNotification(parentCommentID, newCommentID, replierID int,
 
 	title := "New reply to your comment"
 	message := fmt.Sprintf("%s replied to your comment on '%s'", replierUsername, utils.TruncateText(postTitle, 50))
-	_ = CreateNotification(parentAuthorID, "comment", title, message, &postID, &newCommentID, &replierID)
+	_ = app.CreateNotification(parentAuthorID, "comment", title, message, &postID, &newCommentID, &replierID)
 }
 
-// Helper function to get notifications from database
-func getNotificationsWithPagination(db *sql.DB, userID int, isRead bool, page, limit int) []database.Notification {
-	list := make([]database.Notification, 0, limit)
-
-	offset := (page - 1) * limit
-	if offset < 0 {
-		offset = 0
-	}
-
-	var rows *sql.Rows
-	var err error
-
-	if isRead {
-		rows, err = db.Query(`
-            SELECT notification_id, user_id, type, title, message,
-                   related_post_id, related_comment_id, related_user_id,
-                   COALESCE(is_read, 0) AS is_read, creation_date
-            FROM Notifications
-            WHERE user_id = ? AND is_read = 1
-            ORDER BY creation_date DESC
-            LIMIT ? OFFSET ?`,
-			userID, limit, offset)
-	} else {
-		rows, err = db.Query(`
-            SELECT notification_id, user_id, type, title, message,
-                   related_post_id, related_comment_id, related_user_id,
-                   COALESCE(is_read, 0) AS is_read, creation_date
-            FROM Notifications
-            WHERE user_id = ? AND (is_read = 0 OR is_read IS NULL)
-            ORDER BY creation_date DESC
-            LIMIT ? OFFSET ?`,
-			userID, limit, offset)
+// maxMentions caps how many users one post or comment can notify by @mentioning them
+const maxMentions = 10
+
+// CreateMentionNotifications notifies the users @mentioned in a post, or in
+// one of its comments when commentID is set. Users mentioned in it before are
+// skipped, so an edit only notifies the names it adds.
+func (app *App) CreateMentionNotifications(postID int, commentID *int, authorID int, authorUsername, postTitle, content string) {
+	names := markdown.Mentions(content)
+	if len(names) > maxMentions {
+		names = names[:maxMentions]
+	}
+	if len(names) == 0 {
+		return
 	}
 
+	ctx := context.Background()
+	users, err := app.Store.Users.ListByUsernames(ctx, names)
 	if err != nil {
-		return list
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var n database.Notification
-		if err := rows.Scan(
-			&n.NotificationID,
-			&n.UserID,
-			&n.Type,
-			&n.Title,
-			&n.Message,
-			&n.RelatedPostID,
-			&n.RelatedCommentID,
-			&n.RelatedUserID,
-			&n.IsRead,
-			&n.CreationDate,
-		); err != nil {
-			continue
+		return
+	}
+	var userIDs []int
+	for _, u := range users {
+		if u.UserID != authorID {
+			userIDs = append(userIDs, u.UserID)
 		}
-		n.TimeAgo = utils.FormatTimeAgo(n.CreationDate)
-		list = append(list, n)
 	}
-	return list
-}
 
-func skipDuplicateNotification(db *sql.DB, userID int, notificationType string, relatedPostID, relatedCommentID, relatedUserID *int) bool {
-	// Check if a similar notification already exists
-	query := `
-	SELECT COUNT(*) FROM Notifications
-	WHERE user_id = ? AND type = ? AND related_post_id = ? AND related_comment_id = ? AND related_user_id = ?
-	AND creation_date > datetime('now', '-1 hour')`
+	targetType, targetID := "post", postID
+	message := fmt.Sprintf("%s mentioned you in '%s'", authorUsername, utils.TruncateText(postTitle, 50))
+	if commentID != nil {
+		targetType, targetID = "comment", *commentID
+		message = fmt.Sprintf("%s mentioned you in a comment on '%s'", authorUsername, utils.TruncateText(postTitle, 50))
+	}
+	added, err := app.Store.Mentions.Add(ctx, targetType, targetID, userIDs)
+	if err != nil {
+		return
+	}
 
-	var count int
-	err := db.QueryRow(query, userID, notificationType, relatedPostID, relatedCommentID, relatedUserID).Scan(&count)
+	for _, userID := range added {
+		_ = app.CreateNotification(userID, "mention", "You were mentioned", message, &postID, commentID, &authorID)
+	}
+}
+
+// getNotificationsPage returns a page of read or unread notifications and the cursor of the next page
+func (app *App) getNotificationsPage(ctx context.Context, userID int, isRead bool, page store.Page) ([]database.Notification, string) {
+	list, err := app.Store.Notifications.List(ctx, userID, isRead, page)
 	if err != nil {
-		return false
+		return make([]database.Notification, 0), ""
+	}
+	list, next := nextCursor(list, page, store.NotificationCursor)
+	for i := range list {
+		list[i].TimeAgo = utils.FormatTimeAgo(list[i].CreationDate)
+	}
+	if list == nil {
+		list = make([]database.Notification, 0)
 	}
+	return list, next
+}
 
-	return count > 0
+// postAuthorID returns the user who wrote a post
+func (app *App) postAuthorID(postID int) (int, error) {
+	post, err := app.Store.Posts.Get(context.Background(), postID)
+	if err != nil {
+		return 0, err
+	}
+	return post.UserID, nil
+}
 
+// commentAuthorID returns the user who wrote a comment
+func (app *App) commentAuthorID(commentID int) (int, error) {
+	comment, err := app.Store.Comments.Get(context.Background(), commentID)
+	if err != nil {
+		return 0, err
+	}
+	return comment.UserID, nil
 }
 
 // GetUnreadNotificationCount returns the count of unread notifications for a user
-func GetUnreadNotificationCount(userID int) int {
-	db := database.CreateTable()
-	defer db.Close()
-
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM Notifications WHERE user_id = ? AND (is_read = 0 OR is_read = false OR is_read IS NULL)", userID).Scan(&count)
+func (app *App) GetUnreadNotificationCount(r *http.Request, userID int) int {
+	count, err := app.Store.Notifications.UnreadCount(r.Context(), userID)
 	if err != nil {
-		fmt.Printf("Error getting notification count for user %d: %v\n", userID, err)
+		slog.ErrorContext(r.Context(), "count unread notifications",
+			slog.String("request_id", middleware.RequestIDFrom(r.Context())),
+			slog.Int("user_id", userID),
+			slog.Any("error", err),
+		)
 		return 0
 	}
 	return count
 }
 
-func NotificationCountHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
+func (app *App) NotificationCountHandler(w http.ResponseWriter, r *http.Request) {
+	// Visitors simply have no notifications
+	userID := currentUserID(r)
+	if userID == 0 {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]int{"count": 0})
 		return
 	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	count := GetUnreadNotificationCount(userID)
+	count := app.GetUnreadNotificationCount(r, userID)
 
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(map[string]int{"count": count})
 }
 
 // Delete old notifications older than 30 days
-func DeleteOldNotifications() {
-	db := database.CreateTable()
-	defer db.Close()
-
-	_, err := db.Exec(`
-		DELETE FROM Notifications
-		WHERE is_read = TRUE AND creation_date < datetime('now', '-30 days')
-		`)
-	if err != nil {
-		fmt.Printf("Error deleting old notifications: %v\n", err)
+func (app *App) DeleteOldNotifications() {
+	// This runs outside any request, so its log has no request ID
+	ctx := context.Background()
+	if err := app.Store.Notifications.DeleteReadBefore(ctx, time.Now().AddDate(0, 0, -30)); err != nil {
+		slog.ErrorContext(ctx, "delete old notifications", slog.Any("error", err))
 	}
 }
 
@@ -519,31 +468,12 @@ func getIntParam(r *http.Request, param string, defaultValue int) int {
 	return value
 }
 
-func SystemNotification(userIDs []int, title, message string) error {
-	db := database.CreateTable()
-	defer db.Close()
-
-	tx, err := db.Begin()
-	if err != nil {
-		return err
-	}
-	defer tx.Rollback()
-
-	smtm, err := tx.Prepare(`
-		INSERT INTO Notifications (user_id, type, title, message)
-		VALUES (?, 'system', ?, ?)
-	`)
-	if err != nil {
+func (app *App) SystemNotification(userIDs []int, title, message string) error {
+	if err := app.Store.Notifications.CreateSystem(context.Background(), userIDs, title, message); err != nil {
 		return err
 	}
-	defer smtm.Close()
-
 	for _, userID := range userIDs {
-		_, err = smtm.Exec(userID, title, message)
-		if err != nil {
-			return err
-		}
+		app.publishUnreadCount(userID)
 	}
-
-	return tx.Commit()
+	return nil
 }
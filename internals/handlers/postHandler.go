@@ -1,325 +1,222 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"forum/internals/database"
+	"forum/internals/markdown"
+	"forum/internals/store"
 	"forum/internals/utils"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
-	"time"
 )
 
-// CreatePostHandler handles post creation
-func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		utils.FileService("new-post.html", w, nil)
-		return
-	}
+// CreatePostHandler handles post creation. A post published from a form
+// that autosaved a draft (draft_id) replaces that draft.
+func (app *App) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-	// Check if user is logged in
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	// Parse form data
+	err := r.ParseMultipartForm(32 << 20) // 32MB max memory
+	if err != nil {
+		BadRequestHandler(w, r)
 		return
 	}
 
-	// Get user ID from session
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		UnauthorizedHandler(w, r)
+	post, categoryIDs, err := app.newPost(r.Context(), userID, postInput{
+		Title:      r.FormValue("title"),
+		Content:    r.FormValue("content"),
+		Categories: r.Form["categories[]"],
+		Tags:       r.FormValue("tags"),
+		Image:      r.FormValue("image_id"),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Parse form data
-	err = r.ParseMultipartForm(32 << 20) // 32MB max memory
+	// Insert post and its categories with optional image
+	var postID int
+	if draftID, _ := strconv.Atoi(r.FormValue("draft_id")); draftID != 0 {
+		if _, ok := app.ownDraft(w, r, draftID); !ok {
+			return
+		}
+		postID, err = app.Store.Drafts.Publish(r.Context(), draftID, post, categoryIDs)
+	} else {
+		postID, err = app.Store.Posts.Create(r.Context(), post, categoryIDs)
+	}
 	if err != nil {
-		BadRequestHandler(w, r)
+		http.Error(w, "Failed to create post", http.StatusInternalServerError)
 		return
 	}
+	app.CreateMentionNotifications(postID, nil, userID, currentUser(r).Username, post.Title, post.Content)
 
-	title := strings.TrimSpace(r.FormValue("title"))
-	content := strings.TrimSpace(r.FormValue("content"))
+	// Redirect to the new post or home page
+	http.Redirect(w, r, fmt.Sprintf("/view-post?id=%d", postID), http.StatusSeeOther)
+}
 
-	categoryNames := r.Form["categories[]"]
-	imageIDStr := r.FormValue("image_id")
+// postInput is a post as submitted for publishing, from the new post form or
+// from a draft
+type postInput struct {
+	Title      string
+	Content    string
+	Categories []string
+	// Tags is the comma-separated tag field
+	Tags string
+	// Image is the filename of an image the writer uploaded, or ""
+	Image string
+}
 
+// newPost checks a post about to be published and returns it with the IDs of
+// its categories. The error is meant for the writer.
+func (app *App) newPost(ctx context.Context, userID int, in postInput) (*database.Post, []int, error) {
+	title := strings.TrimSpace(in.Title)
+	content := strings.TrimSpace(in.Content)
 	if title == "" || content == "" {
-		utils.FileService("new-post.html", w, map[string]interface{}{"Error": "All fields are required"})
-		return
-	}
-
-	if len(categoryNames) == 0 {
-		utils.FileService("new-post.html", w, map[string]interface{}{
-			"Error": "Please select at least one category",
-		})
-		return
+		return nil, nil, errors.New("All fields are required")
 	}
-
-	// Validate categories and get their IDs
-	db := database.CreateTable()
-	defer db.Close()
-
-	var categoryIDs []int
-	for _, categoryName := range categoryNames {
-		var categoryID int
-		err = db.QueryRow("SELECT category_id FROM Categories WHERE name = ?", categoryName).Scan(&categoryID)
-		if err != nil {
-			utils.FileService("new-post.html", w, map[string]interface{}{
-				"Error": fmt.Sprintf("Invalid category: %s", categoryName),
-			})
-			return
-		}
-		categoryIDs = append(categoryIDs, categoryID)
+	if contentTooLong(content) {
+		return nil, nil, errContentTooLong
 	}
 
-	// Validate image ID if provided
-	var imageID *int
-	if imageIDStr != "" {
-		// Verify the image exists and belongs to this user
-		var imageUserID int
-		var imageFilename string
-		err = db.QueryRow("SELECT user_id, filename FROM Images WHERE filename = ?", imageIDStr).Scan(&imageUserID, &imageFilename)
-		if err != nil {
-			utils.FileService("new-post.html", w, map[string]interface{}{"Error": "Invalid image selected"})
-			return
-		}
-		if imageUserID != userID {
-			utils.FileService("new-post.html", w, map[string]interface{}{"Error": "You can only use your own images"})
-			return
-		}
-
-		// Get the actual image ID
-		var actualImageID int
-		err = db.QueryRow("SELECT image_id FROM Images WHERE filename = ?", imageIDStr).Scan(&actualImageID)
-		if err == nil {
-			imageID = &actualImageID
-		}
+	if len(in.Categories) == 0 {
+		return nil, nil, errors.New("Please select at least one category")
 	}
 
-	// Start transaction for post creation
-	tx, err := db.Begin()
+	// Validate categories and get their IDs
+	categoryIDs, err := ValidateCategories(ctx, app.Store.Categories, in.Categories, nil)
 	if err != nil {
-		InternalServerErrorHandler(w, r)
-		return
+		return nil, nil, err
 	}
-	defer tx.Rollback()
 
-	// Insert post into database with optional image
-	var result sql.Result
-	if imageID != nil {
-		result, err = db.Exec("INSERT INTO Posts (user_id, title, content, image_id) VALUES (?, ?, ?, ?)", userID, title, content, *imageID)
-	} else {
-		result, err = db.Exec("INSERT INTO Posts (user_id, title, content) VALUES (?, ?, ?)", userID, title, content)
+	tags, err := parseTags(in.Tags, app.Config.Tags.MaxPerPost)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	// Validate image ID if provided
+	imageID, err := app.ownImage(ctx, userID, in.Image)
 	if err != nil {
-		http.Error(w, "Failed to create post", http.StatusInternalServerError)
-		return
+		return nil, nil, err
 	}
 
-	postID, _ := result.LastInsertId()
+	post := &database.Post{UserID: userID, Title: title, Content: content, ImageID: imageID, Tags: tags}
+	return post, categoryIDs, nil
+}
 
-	// Associate post with all selected categories
-	for _, categoryID := range categoryIDs {
-		_, err = tx.Exec("INSERT INTO PostCategories (post_id, category_id) VALUES (?, ?)",
-			postID, categoryID)
-		if err != nil {
-			http.Error(w, "Failed to associate categories", http.StatusInternalServerError)
-			return
-		}
+// ownImage looks up an image the user uploaded by filename, returning nil
+// when filename is empty. The error is meant for the user.
+func (app *App) ownImage(ctx context.Context, userID int, filename string) (*int, error) {
+	if filename == "" {
+		return nil, nil
 	}
-
-	// Commit transaction
-	err = tx.Commit()
+	// Verify the image exists and belongs to this user
+	img, err := app.Store.Images.GetByFilename(ctx, filename)
 	if err != nil {
-		http.Error(w, "Failed to save post", http.StatusInternalServerError)
-		return
+		return nil, errors.New("Invalid image selected")
 	}
-
-	// Redirect to the new post or home page
-	http.Redirect(w, r, fmt.Sprintf("/view-post?id=%d", postID), http.StatusSeeOther)
+	if img.UserID != userID {
+		return nil, errors.New("You can only use your own images")
+	}
+	return &img.ImageID, nil
 }
 
-// RemovePostFromCategories removes all category associations for a post
-func RemovePostFromCategories(db *sql.DB, postID int) error {
-	_, err := db.Exec("DELETE FROM PostCategories WHERE post_id = ?", postID)
-	return err
-}
+// ValidateCategories checks that every category name exists and returns
+// their IDs. Archived categories take no new posts, so they are only accepted
+// when listed in kept, the categories an edited post already has.
+func ValidateCategories(ctx context.Context, categories store.CategoryStore, categoryNames, kept []string) ([]int, error) {
+	list, err := categories.List(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("database error while validating categories: %v", err)
+	}
 
-// ValidateCategories checks if all provided category names exist in the database
-func ValidateCategories(db *sql.DB, categoryNames []string) ([]int, error) {
 	var categoryIDs []int
-
 	for _, name := range categoryNames {
-		var categoryID int
-		err := db.QueryRow("SELECT category_id FROM Categories WHERE name = ?", name).Scan(&categoryID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				return nil, fmt.Errorf("category '%s' does not exist", name)
-			}
-			return nil, fmt.Errorf("database error while validating category '%s': %v", name, err)
+		i := slices.IndexFunc(list, func(c database.Category) bool { return c.Name == name })
+		if i < 0 || (list[i].Archived && !slices.Contains(kept, name)) {
+			return nil, fmt.Errorf("Invalid category: %s", name)
 		}
-		categoryIDs = append(categoryIDs, categoryID)
+		categoryIDs = append(categoryIDs, list[i].CategoryID)
 	}
 
 	return categoryIDs, nil
 }
 
-// PostsAPIHandler returns posts as JSON for dynamic loading (index.html)
-func PostsAPIHandler(w http.ResponseWriter, r *http.Request) {
+// PostsAPIHandler returns a page of posts as JSON for dynamic loading (index.html).
+// ?filter=categories, tag or author with ?value= narrows the list, ?sort= and
+// ?window= order it as in postOrder, and ?limit= (20 by default, at most 100)
+// and ?cursor= page it; nextCursor is left out on the last page.
+func (app *App) PostsAPIHandler(w http.ResponseWriter, r *http.Request) {
 
-	db := database.CreateTable()
-	defer db.Close()
+	viewerID := currentUserID(r)
 
-	var currentUserID int
-	if cookie, err := r.Cookie("session"); err == nil && utils.IsValidSession(cookie.Value) {
-		currentUserID = utils.GetUserIDFromSession(cookie.Value)
+	var filter store.PostFilter
+	switch r.URL.Query().Get("filter") {
+	case "categories":
+		filter.Category = r.URL.Query().Get("value")
+	case "tag":
+		filter.Tag = normalizeTag(r.URL.Query().Get("value"))
+	case "author":
+		filter.Author = r.URL.Query().Get("value")
 	}
+	filter.IncludeHidden = canModerate(r)
 
-	filter := r.URL.Query().Get("filter")
+	page, err := pageParams(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 
-	var query string
-	var args []interface{}
+	if err := postOrder(r, page, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	switch filter {
-	case "categories":
-		categoryValue := r.URL.Query().Get("value")
-		query = `
-			SELECT p.post_id, p.title, p.content, u.username, p.creation_date,
-			       (SELECT COUNT(*) FROM Comments WHERE post_id = p.post_id) as comment_count,
-			       (SELECT COUNT(*) FROM LikesDislikes WHERE post_id = p.post_id AND vote = 1) as like_count,
-				   	(SELECT COUNT(*) FROM LikesDislikes WHERE post_id = p.post_id AND vote = -1) as dislike_count,
-
-			       i.image_url, i.thumbnail_url
-			FROM Posts p 
-			JOIN Users u ON p.user_id = u.user_id
-			LEFT JOIN Images i ON p.image_id = i.image_id
-			JOIN PostCategories pc ON p.post_id = pc.post_id
-			JOIN Categories c ON pc.category_id = c.category_id
-			WHERE c.name = ?
-			ORDER BY p.creation_date DESC`
-		args = append(args, categoryValue)
-	default:
-		query = `
-			SELECT p.post_id, p.title, p.content, u.username, p.creation_date,
-			       (SELECT COUNT(*) FROM Comments WHERE post_id = p.post_id) as comment_count,
-			       (SELECT COUNT(*) FROM LikesDislikes WHERE post_id = p.post_id AND vote = 1) as like_count,
-				   	(SELECT COUNT(*) FROM LikesDislikes WHERE post_id = p.post_id AND vote = -1) as dislike_count,
-			       i.image_url, i.thumbnail_url
-			FROM Posts p 
-			JOIN Users u ON p.user_id = u.user_id
-			LEFT JOIN Images i ON p.image_id = i.image_id
-			ORDER BY p.creation_date DESC`
-	}
-
-	rows, err := db.Query(query, args...)
+	list, err := app.Store.Posts.List(r.Context(), filter, page)
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var posts []database.PostResponse
-	for rows.Next() {
-		var p database.PostResponse
-		var creationDate time.Time
-		var imageURL, thumbnailURL sql.NullString
-
-		err := rows.Scan(&p.ID, &p.Title, &p.Content, &p.Author, &creationDate, &p.Comments, &p.Likes, &p.Dislikes, &imageURL, &thumbnailURL)
-		if err != nil {
-			continue
-		}
-
-		p.TimeAgo = utils.FormatTimeAgo(creationDate)
-		p.Excerpt = utils.TruncateText(p.Content, 150)
-		p.Tags = getPostTags(db, p.ID)
+	list, next := nextCursor(list, page, filter.Cursor)
 
-		// Add image URLs if available
-		if imageURL.Valid {
-			p.ImageURL = imageURL.String
-		}
-		if thumbnailURL.Valid {
-			p.ThumbnailURL = thumbnailURL.String
-		}
+	posts := []database.PostResponse{}
+	isUser := app.mentionedUsers(r.Context(), postContents(list)...)
+	for _, post := range list {
+		p := newPostResponse(post, isUser)
 
 		// Get user's vote status if logged in
-		if currentUserID > 0 {
-			var userVote int
-			err := db.QueryRow("SELECT vote FROM LikesDislikes WHERE post_id = ? AND user_id = ?", p.ID, currentUserID).Scan(&userVote)
-			if err != nil {
-				userVote = 0 // No vote
-			}
-			p.UserVote = userVote
+		if viewerID > 0 {
+			p.UserVote, _ = app.Store.Votes.PostVote(r.Context(), p.ID, viewerID)
 		}
 
 		posts = append(posts, p)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(posts)
+	json.NewEncoder(w).Encode(database.PostPage{Posts: posts, NextCursor: next})
 }
 
-// SinglePostAPIHandler returns a single post by ID (view-post.html)
-func SinglePostAPIHandler(w http.ResponseWriter, r *http.Request) {
-
-	// Extract post ID from URL path or query parameter
-	postIDStr := r.URL.Query().Get("id")
-	if postIDStr == "" {
-		// Try to extract from path if using /api/post/{id} pattern
-		parts := strings.Split(r.URL.Path, "/")
-		if len(parts) >= 3 {
-			postIDStr = parts[len(parts)-1]
-		}
-	}
-
-	if postIDStr == "" {
-		BadRequestHandler(w, r)
-		return
-	}
-
-	postID, err := strconv.Atoi(postIDStr)
+// SinglePostAPIHandler returns a single post by ID (GET /api/posts/{id})
+func (app *App) SinglePostAPIHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := pathID(r)
 	if err != nil {
 		BadRequestHandler(w, r)
 		return
 	}
 
 	// Get current user ID if logged in (for vote status)
-	var currentUserID int
-	if cookie, err := r.Cookie("session"); err == nil && utils.IsValidSession(cookie.Value) {
-		currentUserID = utils.GetUserIDFromSession(cookie.Value)
-	}
-
-	db := database.CreateTable()
-	defer db.Close()
-
-	// Query for single post with image information
-	query := `
-        SELECT p.post_id, p.title, p.content, u.username, p.creation_date, p.user_id,
-               (SELECT COUNT(*) FROM Comments WHERE post_id = p.post_id) as comment_count,
-               (SELECT COUNT(*) FROM LikesDislikes WHERE post_id = p.post_id AND vote = 1) as like_count,
-			   (SELECT COUNT(*) FROM LikesDislikes WHERE post_id = p.post_id AND vote = -1) as dislike_count,
-               i.image_url, i.thumbnail_url
-        FROM Posts p 
-        JOIN Users u ON p.user_id = u.user_id
-        LEFT JOIN Images i ON p.image_id = i.image_id
-        WHERE p.post_id = ?`
-
-	var post database.PostResponse
-	var creationDate time.Time
-	var imageURL, thumbnailURL *string
-	var postAuthorID int
-
-	err = db.QueryRow(query, postID).Scan(
-		&post.ID, &post.Title, &post.Content, &post.Author,
-		&creationDate, &postAuthorID, &post.Comments, &post.Likes, &post.Dislikes,
-		&imageURL, &thumbnailURL,
-	)
+	viewerID := currentUserID(r)
 
+	stored, err := app.Store.Posts.Get(r.Context(), postID)
+	if err == nil && stored.Hidden && !canModify(r, stored.UserID) {
+		err = store.ErrNotFound
+	}
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "Post not found", http.StatusNotFound)
 		} else {
 			http.Error(w, "Database error", http.StatusInternalServerError)
@@ -328,35 +225,26 @@ func SinglePostAPIHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Format the post data
-	post.TimeAgo = utils.FormatTimeAgo(creationDate)
-	post.Tags = getPostTags(db, post.ID)
-
-	// Add image URLs if available
-	if imageURL != nil {
-		post.ImageURL = *imageURL
-	}
-	if thumbnailURL != nil {
-		post.ThumbnailURL = *thumbnailURL
-	}
+	post := newPostResponse(*stored, app.mentionedUsers(r.Context(), stored.Content))
 
 	// Get user's vote status if logged in
 	var userVote int
-	if currentUserID > 0 {
-		err := db.QueryRow("SELECT vote FROM LikesDislikes WHERE post_id = ? AND user_id = ?", postID, currentUserID).Scan(&userVote)
-		if err != nil {
-			userVote = 0
-		}
+	if viewerID > 0 {
+		userVote, _ = app.Store.Votes.PostVote(r.Context(), postID, viewerID)
 	}
 
-	// Check if current user is the author
-	isAuthor := currentUserID > 0 && currentUserID == postAuthorID
+	// Check if current user is the author, or may edit the post anyway
+	isAuthor := viewerID > 0 && viewerID == stored.UserID
+	canEdit := canModify(r, stored.UserID)
 
 	response := map[string]interface{}{
 		"id":           post.ID,
 		"title":        post.Title,
 		"content":      post.Content,
+		"contentHtml":  post.ContentHTML,
 		"author":       post.Author,
 		"timeAgo":      post.TimeAgo,
+		"categories":   post.Categories,
 		"tags":         post.Tags,
 		"comments":     post.Comments,
 		"likes":        post.Likes,
@@ -365,256 +253,193 @@ func SinglePostAPIHandler(w http.ResponseWriter, r *http.Request) {
 		"thumbnailUrl": post.ThumbnailURL,
 		"userVote":     userVote,
 		"isAuthor":     isAuthor,
+		"canEdit":      canEdit,
+		"hidden":       stored.Hidden,
+		"edited":       post.Edited,
+		"editedAt":     post.EditedAt,
+		"canModerate":  canModerate(r),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-func EditPostHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodGet {
-		postID := r.FormValue("post_id")
-		title := strings.TrimSpace(r.FormValue("title"))
-		content := strings.TrimSpace(r.FormValue("content"))
-		categoryNames := r.Form["categories[]"]
-
-		if title == "" || content == "" {
-			http.Error(w, "Title and content required", http.StatusBadRequest)
-			return
-		}
-
-		if len(categoryNames) == 0 {
-			http.Error(w, "At least one category is required", http.StatusBadRequest)
-			return
-		}
-
-		// Check authentication and ownership
-		cookie, err := r.Cookie("session")
-		if err != nil || !utils.IsValidSession(cookie.Value) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		userID := utils.GetUserIDFromSession(cookie.Value)
-
-		// Get post and verify ownership
-		db := database.CreateTable()
-		defer db.Close()
-
-		var authorID int
-		err = db.QueryRow("SELECT user_id FROM Posts WHERE post_id = ?", postID).Scan(&authorID)
-		if err != nil || authorID != userID {
-			http.Error(w, "Unauthorized", http.StatusForbidden)
-			return
-		}
+// EditPostHandler updates a post's title, content, categories and tags (PATCH /api/posts/{id})
+func (app *App) EditPostHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	title := strings.TrimSpace(r.FormValue("title"))
+	content := strings.TrimSpace(r.FormValue("content"))
+	categoryNames := r.Form["categories[]"]
 
-		// Validate categories and get their IDs
-		var categoryIDs []int
-		for _, categoryName := range categoryNames {
-			var categoryID int
-			err = db.QueryRow("SELECT category_id FROM Categories WHERE name = ?", categoryName).Scan(&categoryID)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Invalid category: %s", categoryName), http.StatusBadRequest)
-				return
-			}
-			categoryIDs = append(categoryIDs, categoryID)
-		}
+	if title == "" || content == "" {
+		http.Error(w, "Title and content required", http.StatusBadRequest)
+		return
+	}
+	if contentTooLong(content) {
+		http.Error(w, errContentTooLong.Error(), http.StatusBadRequest)
+		return
+	}
 
-		// Start transaction for atomic updates
-		tx, err := db.Begin()
-		if err != nil {
-			http.Error(w, "Failed to start transaction", http.StatusInternalServerError)
-			return
-		}
-		defer tx.Rollback()
+	if len(categoryNames) == 0 {
+		http.Error(w, "At least one category is required", http.StatusBadRequest)
+		return
+	}
 
-		// Update post title and content
-		_, err = tx.Exec("UPDATE Posts SET title = ?, content = ? WHERE post_id = ?", title, content, postID)
-		if err != nil {
-			http.Error(w, "Failed to update post", http.StatusInternalServerError)
-			return
-		}
+	userID := currentUserID(r)
 
-		// Delete existing post-category associations
-		_, err = tx.Exec("DELETE FROM PostCategories WHERE post_id = ?", postID)
-		if err != nil {
-			http.Error(w, "Failed to update categories", http.StatusInternalServerError)
-			return
-		}
+	// Get post and verify the user is its author or a moderator
+	post, err := app.Store.Posts.Get(r.Context(), postID)
+	if err != nil || !canModify(r, post.UserID) {
+		http.Error(w, "Unauthorized", http.StatusForbidden)
+		return
+	}
 
-		// Insert new post-category associations
-		for _, categoryID := range categoryIDs {
-			_, err = tx.Exec("INSERT INTO PostCategories (post_id, category_id) VALUES (?, ?)", postID, categoryID)
-			if err != nil {
-				http.Error(w, "Failed to update categories", http.StatusInternalServerError)
-				return
-			}
-		}
+	// Validate categories and get their IDs
+	categoryIDs, err := ValidateCategories(r.Context(), app.Store.Categories, categoryNames, post.Categories)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		// Commit transaction
-		err = tx.Commit()
-		if err != nil {
-			http.Error(w, "Failed to save changes", http.StatusInternalServerError)
+	// Tags left out of the request stay as they are
+	var tags []string
+	if _, ok := r.Form["tags"]; ok {
+		if tags, err = parseTags(r.FormValue("tags"), app.Config.Tags.MaxPerPost); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-
-		// Return JSON response for API calls
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]bool{"success": true})
 	}
-}
 
-// DeletePostHandler handles post deletion
-func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// Update title, content, categories and tags in one transaction
+	if err := app.Store.Posts.Update(r.Context(), postID, userID, title, content, categoryIDs, tags); err != nil {
+		http.Error(w, "Failed to update post", http.StatusInternalServerError)
 		return
 	}
+	if post.UserID != userID {
+		app.recordModeration(r, "edit", "post", postID, post.Title)
+	}
+	app.CreateMentionNotifications(postID, nil, userID, currentUser(r).Username, title, content)
 
-	postID := r.FormValue("post_id")
-	if postID == "" {
-		http.Error(w, "Post ID required", http.StatusBadRequest)
+	// Return the tags as stored, after normalization and merged names
+	updated, err := app.Store.Posts.Get(r.Context(), postID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
 
-	// Check authentication and ownership
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	// Return JSON response for API calls
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":     true,
+		"tags":        updated.Tags,
+		"contentHtml": markdown.Render(updated.Content, app.mentionedUsers(r.Context(), updated.Content)),
+	})
+}
+
+// DeletePostHandler handles post deletion (DELETE /api/posts/{id})
+func (app *App) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
 		return
 	}
 
-	userID := utils.GetUserIDFromSession(cookie.Value)
-
-	db := database.CreateTable()
-	defer db.Close()
+	userID := currentUserID(r)
 
-	var authorID int
-	err = db.QueryRow("SELECT user_id FROM Posts WHERE post_id = ?", postID).Scan(&authorID)
+	post, err := app.Store.Posts.Get(r.Context(), postID)
 	if err != nil {
 		http.Error(w, "Post not found", http.StatusNotFound)
 		return
 	}
 
-	if authorID != userID {
+	if !canModify(r, post.UserID) {
 		http.Error(w, "Unauthorized", http.StatusForbidden)
 		return
 	}
 
-	// Delete related data first (foreign key constraints)
-	db.Exec("DELETE FROM LikesDislikes WHERE post_id = ?", postID)
-	db.Exec("DELETE FROM CommentLikes WHERE comment_id IN (SELECT comment_id FROM Comments WHERE post_id = ?)", postID)
-	db.Exec("DELETE FROM Comments WHERE post_id = ?", postID)
-	db.Exec("DELETE FROM PostCategories WHERE post_id = ?", postID)
-	db.Exec("DELETE FROM Notifications WHERE related_post_id = ?", postID)
-
-	// Delete the post
-	_, err = db.Exec("DELETE FROM Posts WHERE post_id = ?", postID)
-	if err != nil {
+	// Move the post to the trash, where its author can restore it for a while
+	if err := app.Store.Posts.Delete(r.Context(), postID, userID); err != nil {
 		http.Error(w, "Failed to delete post", http.StatusInternalServerError)
 		return
 	}
+	if post.UserID != userID {
+		app.recordModeration(r, "delete", "post", postID, post.Title)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]bool{"success": true})
 }
 
-// EditCommentHandler handles comment editing
-func EditCommentHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	commentID := r.FormValue("comment_id")
+// EditCommentHandler handles comment editing (PATCH /api/comments/{id})
+func (app *App) EditCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := pathID(r)
 	content := strings.TrimSpace(r.FormValue("content"))
 
-	if commentID == "" || content == "" {
+	if err != nil || content == "" {
 		http.Error(w, "Comment ID and content required", http.StatusBadRequest)
 		return
 	}
-
-	// Check authentication and ownership
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	if contentTooLong(content) {
+		http.Error(w, errContentTooLong.Error(), http.StatusBadRequest)
 		return
 	}
 
-	userID := utils.GetUserIDFromSession(cookie.Value)
+	userID := currentUserID(r)
 
-	db := database.CreateTable()
-	defer db.Close()
-
-	var authorID int
-	err = db.QueryRow("SELECT user_id FROM Comments WHERE comment_id = ?", commentID).Scan(&authorID)
+	comment, err := app.Store.Comments.Get(r.Context(), commentID)
 	if err != nil {
 		http.Error(w, "Comment not found", http.StatusNotFound)
 		return
 	}
 
-	if authorID != userID {
+	if !canModify(r, comment.UserID) {
 		http.Error(w, "Unauthorized", http.StatusForbidden)
 		return
 	}
 
 	// Update comment
-	_, err = db.Exec("UPDATE Comments SET content = ? WHERE comment_id = ?", content, commentID)
-	if err != nil {
+	if err := app.Store.Comments.Update(r.Context(), commentID, userID, content); err != nil {
 		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
 		return
 	}
+	if comment.UserID != userID {
+		app.recordModeration(r, "edit", "comment", commentID, utils.TruncateText(comment.Content, 100))
+	}
+	app.CreateMentionNotifications(comment.PostID, &commentID, userID, currentUser(r).Username, comment.PostTitle, content)
+	app.publishComment(r.Context(), eventCommentEdited, commentID, 0)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "contentHtml": markdown.Render(content, app.mentionedUsers(r.Context(), content))})
 }
 
 // GetUserImagesHandler returns images uploaded by a user
-func GetUserImagesHandler(w http.ResponseWriter, r *http.Request) {
-	// Check authentication
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
-		return
-	}
-
-	db := database.CreateTable()
-	defer db.Close()
+func (app *App) GetUserImagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
 	// Get user's uploaded images
-	rows, err := db.Query(`
-		SELECT image_id, filename, original_name, file_size, file_type, 
-		       image_url, thumbnail_url, upload_date
-		FROM Images 
-		WHERE user_id = ? And image_type = 'post'
-		ORDER BY upload_date DESC`, userID)
+	list, err := app.Store.Images.ListByUser(r.Context(), userID, "post")
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
 
 	var images []database.ImageResponse
-	for rows.Next() {
-		var img database.ImageResponse
-		var uploadDate time.Time
-
-		err := rows.Scan(&img.ID, &img.Filename, &img.OriginalName, &img.FileSize,
-			&img.FileType, &img.ImageURL, &img.ThumbnailURL, &uploadDate)
-		if err != nil {
-			continue
-		}
-
-		img.UploadDate = uploadDate.Format("2006-01-02 15:04:05")
-		img.FileSizeFormatted = formatFileSize(img.FileSize)
-
-		images = append(images, img)
+	for _, img := range list {
+		images = append(images, database.ImageResponse{
+			ID:                img.ImageID,
+			Filename:          img.Filename,
+			OriginalName:      img.OriginalName,
+			FileSize:          img.FileSize,
+			FileSizeFormatted: formatFileSize(img.FileSize),
+			FileType:          img.FileType,
+			ImageURL:          img.ImageURL,
+			ThumbnailURL:      img.ThumbnailURL,
+			UploadDate:        img.UploadDate.Format("2006-01-02 15:04:05"),
+		})
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"encoding/json"
+	"forum/internals/markdown"
+	"net/http"
+)
+
+// MarkdownPreviewHandler renders post or comment content the way it will be
+// shown once published, for the editor preview (POST /api/markdown/preview)
+func (app *App) MarkdownPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	content := r.FormValue("content")
+	if contentTooLong(content) {
+		http.Error(w, errContentTooLong.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"html": markdown.Render(content, app.mentionedUsers(r.Context(), content))})
+}
@@ -1,87 +1,48 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
 	"forum/internals/database"
+	"forum/internals/store"
 	"forum/internals/utils"
 	"net/http"
 	"strings"
-	"time"
 )
 
-func ProfileHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Redirect(w, r, "/login", http.StatusSeeOther)
-		return
-	}
-
-	switch r.Method {
-	case http.MethodGet:
-		utils.FileService("profile.html", w, nil)
-	case http.MethodPost:
-		updateProfile(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
+func (app *App) ProfileAPIHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-func ProfileAPIHandler(w http.ResponseWriter, r *http.Request) {
-	cookie, err := r.Cookie("session")
-	if err != nil || !utils.IsValidSession(cookie.Value) {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	userID := utils.GetUserIDFromSession(cookie.Value)
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
+	profile, err := app.Store.Users.Profile(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
 		return
 	}
 
-	switch r.Method {
-	case http.MethodGet:
-		db := database.CreateTable()
-		defer db.Close()
-
-		profile := getUserProfile(db, userID)
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(profile)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(profile)
 }
 
-func updateProfile(w http.ResponseWriter, r *http.Request) {
-	userID := utils.GetUserIDFromSession(getCookieValue(r))
-	if userID == 0 {
-		http.Error(w, "Invalid session", http.StatusUnauthorized)
-		return
-	}
-
-	db := database.CreateTable()
-	defer db.Close()
+// UpdateProfileHandler saves the profile form (POST /profile)
+func (app *App) UpdateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
 	newUsername := strings.TrimSpace(r.FormValue("username"))
 	newBio := strings.TrimSpace(r.FormValue("bio"))
 
 	if newUsername != "" {
-		var exists int
-		_ = db.QueryRow("SELECT COUNT(*) FROM Users WHERE username = ? AND user_id != ?", newUsername, userID).Scan(&exists)
-		if exists > 0 {
+		taken, _ := app.Store.Users.UsernameTaken(r.Context(), newUsername, userID)
+		if taken {
 			http.Error(w, "Username already taken", http.StatusBadRequest)
 			return
 		}
-		if _, err := db.Exec("UPDATE Users SET username = ? WHERE user_id = ?", newUsername, userID); err != nil {
+		if err := app.Store.Users.UpdateUsername(r.Context(), userID, newUsername); err != nil {
 			http.Error(w, "Failed to update username", http.StatusInternalServerError)
 			return
 		}
 	}
 
 	if newBio != "" {
-		if _, err := db.Exec("UPDATE Users SET bio = ? WHERE user_id = ?", newBio, userID); err != nil {
+		if err := app.Store.Users.UpdateBio(r.Context(), userID, newBio); err != nil {
 			http.Error(w, "Failed to update bio", http.StatusInternalServerError)
 			return
 		}
@@ -95,275 +56,99 @@ func updateProfile(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func getUserProfile(db *sql.DB, userID int) database.UserProfile {
-	var profile database.UserProfile
-	var bio string
+// /api/user/posts
+func (app *App) UserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-	// Date from SQL
-	err := db.QueryRow(`
-		SELECT 
-			u.user_id,
-			u.username,
-			u.email,
-			COALESCE( strftime('%Y-%m-%d', u.registration_date), '' ) AS join_date,
-			COALESCE(u.bio, '')
-		FROM Users u
-		WHERE u.user_id = ?
-	`, userID).Scan(&profile.UserID, &profile.Username, &profile.Email, &profile.JoinDate, &bio)
+	app.writeUserPosts(w, r, store.PostFilter{AuthorID: userID, IncludeHidden: true})
+}
 
+// /api/user/comments
+func (app *App) UserCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	page, err := pageParams(r, 20, 100)
 	if err != nil {
-		return profile
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
 	}
-	profile.Bio = bio
 
-	// Counts
-	_ = db.QueryRow(`SELECT COUNT(*) FROM Posts WHERE user_id = ?`, userID).Scan(&profile.PostCount)
-	_ = db.QueryRow(`SELECT COUNT(*) FROM Comments WHERE user_id = ?`, userID).Scan(&profile.CommentCount)
-
-	// Likes Given / Received
-	_ = db.QueryRow(`SELECT COUNT(*) FROM LikesDislikes WHERE user_id = ? AND vote = 1`, userID).Scan(&profile.LikesGiven)
-	_ = db.QueryRow(`
-		SELECT COUNT(*)
-		FROM LikesDislikes ld 
-		JOIN Posts p ON ld.post_id = p.post_id 
-		WHERE p.user_id = ? AND ld.vote = 1
-	`, userID).Scan(&profile.LikesReceived)
-
-	// Dislikes Given / Received
-	_ = db.QueryRow(`SELECT COUNT(*) FROM LikesDislikes WHERE user_id = ? AND vote = -1`, userID).Scan(&profile.DislikesGiven)
-	_ = db.QueryRow(`
-		SELECT COUNT(*)
-		FROM LikesDislikes ld 
-		JOIN Posts p ON ld.post_id = p.post_id 
-		WHERE p.user_id = ? AND ld.vote = -1
-	`, userID).Scan(&profile.DislikesReceived)
-
-	// Profile image
-	var thumbnailURL string
-	_ = db.QueryRow(`
-		SELECT thumbnail_url 
-		FROM Images 
-		WHERE user_id = ? AND image_type = 'profile'
-		ORDER BY upload_date DESC 
-		LIMIT 1
-	`, userID).Scan(&thumbnailURL)
-	profile.ProfileImage = thumbnailURL
-
-	return profile
-}
-
-func getCookieValue(r *http.Request) string {
-	cookie, err := r.Cookie("session")
+	comments, err := app.Store.Comments.ListByUser(r.Context(), userID, page)
 	if err != nil {
-		return ""
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
 	}
-	return cookie.Value
-}
-
-// /api/user/posts
-func UserPostsHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		userID := utils.GetUserIDFromSession(getCookieValue(r))
-		if userID == 0 {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		db := database.CreateTable()
-		defer db.Close()
-
-		rows, err := db.Query(`
-			SELECT post_id, title, content, creation_date 
-			FROM Posts 
-			WHERE user_id = ? 
-			ORDER BY creation_date DESC
-		`, userID)
-		if err != nil {
-			http.Error(w, "Database query failed", http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
-
-		var posts []database.PostResponse
-		for rows.Next() {
-			var post database.PostResponse
-			var created time.Time
-			if err := rows.Scan(&post.ID, &post.Title, &post.Content, &created); err != nil {
-				continue
-			}
-			post.TimeAgo = utils.FormatTimeAgo(created)
-			if len(post.Content) > 160 {
-				post.Excerpt = post.Content[:160] + "…"
-			} else {
-				post.Excerpt = post.Content
-			}
-			posts = append(posts, post)
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(posts)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	comments, next := nextCursor(comments, page, store.CommentCursor)
+
+	type CommentItem struct {
+		ID      int    `json:"id"`
+		PostID  int    `json:"postId"`
+		Title   string `json:"title"`
+		Content string `json:"content"`
+		TimeAgo string `json:"timeAgo"`
 	}
-}
-
-// /api/user/comments
-func UserCommentsHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		userID := utils.GetUserIDFromSession(getCookieValue(r))
-		if userID == 0 {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		db := database.CreateTable()
-		defer db.Close()
-
-		rows, err := db.Query(`
-			SELECT c.comment_id, c.post_id, p.title, c.content, c.creation_date
-			FROM Comments c
-			JOIN Posts p ON p.post_id = c.post_id
-			WHERE c.user_id = ?
-			ORDER BY c.creation_date DESC
-		`, userID)
-		if err != nil {
-			http.Error(w, "Database query failed", http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
-
-		type CommentItem struct {
-			ID      int    `json:"id"`
-			PostID  int    `json:"postId"`
-			Title   string `json:"title"`
-			Content string `json:"content"`
-			TimeAgo string `json:"timeAgo"`
-		}
-
-		var out []CommentItem
-		for rows.Next() {
-			var it CommentItem
-			var created time.Time
-			if err := rows.Scan(&it.ID, &it.PostID, &it.Title, &it.Content, &created); err != nil {
-				continue
-			}
-			it.TimeAgo = utils.FormatTimeAgo(created)
-			out = append(out, it)
-		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(out)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	out := []CommentItem{}
+	for _, c := range comments {
+		out = append(out, CommentItem{
+			ID:      c.CommentID,
+			PostID:  c.PostID,
+			Title:   c.PostTitle,
+			Content: c.Content,
+			TimeAgo: utils.FormatTimeAgo(c.CreationDate),
+		})
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"comments": out, "nextCursor": next})
 }
 
 // /api/user/likes
-func UserLikesHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		cookie, err := r.Cookie("session")
-		if err != nil || !utils.IsValidSession(cookie.Value) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		userID := utils.GetUserIDFromSession(cookie.Value)
-		if userID == 0 {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-
-		db := database.CreateTable()
-		defer db.Close()
-
-		rows, err := db.Query(`
-			SELECT p.post_id, p.title, p.content, p.creation_date
-			FROM LikesDislikes ld
-			JOIN Posts p ON p.post_id = ld.post_id
-			WHERE ld.user_id = ? AND ld.vote = 1
-			ORDER BY p.creation_date DESC
-		`, userID)
-		if err != nil {
-			http.Error(w, "Database query failed", http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
+func (app *App) UserLikesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-		var liked []database.PostResponse
-		for rows.Next() {
-			var pr database.PostResponse
-			var created time.Time
-			if err := rows.Scan(&pr.ID, &pr.Title, &pr.Content, &created); err != nil {
-				continue
-			}
-			pr.TimeAgo = utils.FormatTimeAgo(created)
-			if len(pr.Content) > 160 {
-				pr.Excerpt = pr.Content[:160] + "…"
-			} else {
-				pr.Excerpt = pr.Content
-			}
-			liked = append(liked, pr)
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(liked)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
+	app.writeUserPosts(w, r, store.PostFilter{VotedBy: userID, Vote: 1})
 }
 
 // /api/user/dislikes
-func UserDislikesHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		cookie, err := r.Cookie("session")
-		if err != nil || !utils.IsValidSession(cookie.Value) {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		userID := utils.GetUserIDFromSession(cookie.Value)
-		if userID == 0 {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+func (app *App) UserDislikesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
 
-		db := database.CreateTable()
-		defer db.Close()
+	app.writeUserPosts(w, r, store.PostFilter{VotedBy: userID, Vote: -1})
+}
 
-		rows, err := db.Query(`
-			SELECT p.post_id, p.title, p.content, p.creation_date
-			FROM LikesDislikes ld
-			JOIN Posts p ON p.post_id = ld.post_id
-			WHERE ld.user_id = ? AND ld.vote = -1
-			ORDER BY p.creation_date DESC
-		`, userID)
-		if err != nil {
-			http.Error(w, "Database query failed", http.StatusInternalServerError)
-			return
-		}
-		defer rows.Close()
+// writeUserPosts writes a page of the posts matching filter for the profile page lists
+func (app *App) writeUserPosts(w http.ResponseWriter, r *http.Request, filter store.PostFilter) {
+	page, err := pageParams(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 
-		var disliked []database.PostResponse
-		for rows.Next() {
-			var pr database.PostResponse
-			var created time.Time
-			if err := rows.Scan(&pr.ID, &pr.Title, &pr.Content, &created); err != nil {
-				continue
-			}
-			pr.TimeAgo = utils.FormatTimeAgo(created)
-			if len(pr.Content) > 160 {
-				pr.Excerpt = pr.Content[:160] + "…"
-			} else {
-				pr.Excerpt = pr.Content
-			}
-			disliked = append(disliked, pr)
-		}
+	if err := postOrder(r, page, &filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(disliked)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	list, err := app.Store.Posts.List(r.Context(), filter, page)
+	if err != nil {
+		http.Error(w, "Database query failed", http.StatusInternalServerError)
+		return
+	}
+	list, next := nextCursor(list, page, filter.Cursor)
+
+	posts := []database.PostResponse{}
+	isUser := app.mentionedUsers(r.Context(), postContents(list)...)
+	for _, p := range list {
+		post := newPostResponse(p, isUser)
+		if len(post.Content) > 160 {
+			post.Excerpt = post.Content[:160] + "…"
+		} else {
+			post.Excerpt = post.Content
+		}
+		posts = append(posts, post)
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(database.PostPage{Posts: posts, NextCursor: next})
 }
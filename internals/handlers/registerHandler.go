@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"fmt"
-	"forum/internals/database"
 	"forum/internals/utils"
 	"net/http"
 	"strings"
@@ -10,15 +9,13 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		utils.FileService("register.html", w, nil)
-		return
-	}
-
-	db := database.CreateTable()
-	defer db.Close()
+// RegisterPageHandler serves the registration form
+func RegisterPageHandler(w http.ResponseWriter, r *http.Request) {
+	utils.FileService("register.html", w, nil)
+}
 
+// RegisterHandler creates an account from the submitted form
+func (app *App) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	username := strings.TrimSpace(r.FormValue("username"))
 	email := strings.TrimSpace(r.FormValue("email"))
 	pass := r.FormValue("password")
@@ -60,28 +57,27 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check for duplicates
-	var exists int
-	err := db.QueryRow("SELECT COUNT(*) FROM Users WHERE email = ?", email).Scan(&exists)
+	exists, err := app.Store.Users.EmailExists(r.Context(), email)
 	if err != nil {
 		formData["Message"] = "Database error occurred"
 		utils.FileService("register.html", w, formData)		
 		return
 	}
 
-	if exists > 0 {
+	if exists {
 		formData["Message"] = "This email is already registered"
 		utils.FileService("register.html", w, formData)
 		return
 	}
 
-	err = db.QueryRow("SELECT COUNT(*) FROM Users WHERE username = ?", username).Scan(&exists)
+	exists, err = app.Store.Users.UsernameTaken(r.Context(), username, 0)
 	if err != nil {
 		formData["Message"] = "Database error occurred"
 		utils.FileService("register.html", w, formData)
 		return
 	}
 
-	if exists > 0 {
+	if exists {
 		formData["Message"] = "This username is already taken"
 		utils.FileService("register.html", w, formData)
 		return
@@ -95,7 +91,7 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = db.Exec("INSERT INTO Users (username, email, password_hash) VALUES (?, ?, ?)", username, email, string(hash))
+	newUserID, err := app.Store.Users.Create(r.Context(), username, email, string(hash))
 	if err != nil {
 		formData["Message"] = "Failed to create user account"
 		utils.FileService("register.html", w, formData)
@@ -103,13 +99,11 @@ func RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create welcome notification
-	var newUserID int
-	err = db.QueryRow("SELECT user_id FROM Users WHERE email = ?", email).Scan(&newUserID)
-	if err == nil && newUserID > 0 {
+	if newUserID > 0 {
 		title := "Welcome to Plant Talk! 🌱"
 		message := fmt.Sprintf("Welcome to our plant-loving community, %s! Start by creating your first post or exploring different plant categories. Happy growing!", username)
 
-		CreateNotification(newUserID, "system", title, message, nil, nil, nil)
+		app.CreateNotification(newUserID, "system", title, message, nil, nil, nil)
 	}
 
 	http.Redirect(w, r, "/login?success=registration", http.StatusSeeOther)
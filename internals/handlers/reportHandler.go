@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum/internals/database"
+	"forum/internals/middleware"
+	"forum/internals/store"
+	"forum/internals/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// reportReasons are the reasons a report can be filed for
+var reportReasons = map[string]string{
+	"spam":           "spam",
+	"abuse":          "abusive content",
+	"misinformation": "wrong or harmful advice",
+	"other":          "another reason",
+}
+
+// CreateReportHandler flags a post, comment or user for the moderators (POST /api/reports)
+func (app *App) CreateReportHandler(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r)
+
+	targetType := r.FormValue("targetType")
+	targetID, err := strconv.Atoi(r.FormValue("targetId"))
+	if err != nil || targetID <= 0 {
+		http.Error(w, "Invalid target ID", http.StatusBadRequest)
+		return
+	}
+	reason := r.FormValue("reason")
+	if _, ok := reportReasons[reason]; !ok {
+		http.Error(w, "Reason must be spam, abuse, misinformation or other", http.StatusBadRequest)
+		return
+	}
+	details := strings.TrimSpace(r.FormValue("details"))
+	if len(details) > 1000 {
+		http.Error(w, "Details must be at most 1000 characters", http.StatusBadRequest)
+		return
+	}
+
+	content, ownerID, err := app.reportTarget(r, targetType, targetID)
+	if err != nil || content == nil {
+		http.Error(w, "Reported content not found", http.StatusNotFound)
+		return
+	}
+	if ownerID == userID {
+		http.Error(w, "You cannot report yourself", http.StatusBadRequest)
+		return
+	}
+
+	id, err := app.Store.Reports.Create(r.Context(), &database.Report{
+		ReporterID: userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Reason:     reason,
+		Details:    details,
+	})
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "You already reported this", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create report", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "id": id})
+}
+
+// ReportsQueueHandler lists the open reports with the reported content (GET /api/reports)
+func (app *App) ReportsQueueHandler(w http.ResponseWriter, r *http.Request) {
+	page := getIntParam(r, "page", 1)
+	limit := getIntParam(r, "limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	list, err := app.Store.Reports.ListOpen(r.Context(), limit, (page-1)*limit)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	reports := []database.ReportResponse{}
+	for _, report := range list {
+		// Content that is already gone is shown as null
+		content, _, err := app.reportTarget(r, report.TargetType, report.TargetID)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+
+		reports = append(reports, database.ReportResponse{
+			ID:         report.ReportID,
+			Reporter:   report.ReporterName,
+			TargetType: report.TargetType,
+			TargetID:   report.TargetID,
+			Reason:     report.Reason,
+			Details:    report.Details,
+			TimeAgo:    utils.FormatTimeAgo(report.CreationDate),
+			Content:    content,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
+
+// ResolveReportHandler closes every open report on the reported content with
+// one action (POST /api/reports/{id}/resolve): dismiss, hide, delete or warn
+func (app *App) ResolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	reportID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid report ID", http.StatusBadRequest)
+		return
+	}
+	action := r.FormValue("action")
+
+	report, err := app.Store.Reports.Get(r.Context(), reportID)
+	if err != nil {
+		http.Error(w, "Report not found", http.StatusNotFound)
+		return
+	}
+	if report.Status != "open" {
+		http.Error(w, "Report is already resolved", http.StatusConflict)
+		return
+	}
+
+	content, ownerID, err := app.reportTarget(r, report.TargetType, report.TargetID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if content == nil && action != "dismiss" {
+		http.Error(w, "Reported content no longer exists", http.StatusConflict)
+		return
+	}
+
+	// Notifications link to the reported post or comment while it still exists
+	var postID, commentID *int
+	if content != nil && content.PostID != 0 && action != "delete" {
+		postID = &content.PostID
+		if report.TargetType == "comment" {
+			commentID = &report.TargetID
+		}
+	}
+
+	// Apply the action to the reported content
+	switch action {
+	case "dismiss":
+	case "hide", "delete":
+		if report.TargetType == "user" {
+			http.Error(w, "Users can only be warned", http.StatusBadRequest)
+			return
+		}
+		if err := app.moderateTarget(r, report.TargetType, report.TargetID, action); err != nil {
+			http.Error(w, "Failed to "+action+" content", http.StatusInternalServerError)
+			return
+		}
+	case "warn":
+		message := fmt.Sprintf("A moderator reviewed a report about your %s and is warning you about %s. Please keep the forum friendly and helpful.",
+			report.TargetType, reportReasons[report.Reason])
+		app.notify(r, ownerID, "Warning from the moderators", message, postID, commentID)
+	default:
+		http.Error(w, "Action must be dismiss, hide, delete or warn", http.StatusBadRequest)
+		return
+	}
+
+	resolved, err := app.Store.Reports.Resolve(r.Context(), report.TargetType, report.TargetID, action, currentUserID(r))
+	if err != nil {
+		http.Error(w, "Failed to resolve report", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "report_"+action, report.TargetType, report.TargetID,
+		fmt.Sprintf("%d report(s), reason: %s", len(resolved), report.Reason))
+
+	// Let every reporter know their report was handled
+	outcome := map[string]string{
+		"dismiss": "no rule was broken, so it stays as it is",
+		"hide":    "it has been hidden",
+		"delete":  "it has been removed",
+		"warn":    "its author has been warned",
+	}[action]
+	for _, rp := range resolved {
+		message := fmt.Sprintf("Thanks for your report about a %s: a moderator reviewed it and %s.", rp.TargetType, outcome)
+		app.notify(r, rp.ReporterID, "Your report was reviewed", message, postID, commentID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "resolved": len(resolved)})
+}
+
+// reportTarget loads the reported post, comment or user for the moderation
+// queue together with its owner; content is nil when the target is gone
+func (app *App) reportTarget(r *http.Request, targetType string, targetID int) (*database.ReportContent, int, error) {
+	var err error
+	switch targetType {
+	case "post":
+		var post *database.Post
+		if post, err = app.Store.Posts.Get(r.Context(), targetID); err == nil {
+			return &database.ReportContent{
+				Author: post.Username,
+				Title:  post.Title,
+				Text:   post.Content,
+				PostID: post.PostID,
+				Hidden: post.Hidden,
+			}, post.UserID, nil
+		}
+	case "comment":
+		var comment *database.Comment
+		if comment, err = app.Store.Comments.Get(r.Context(), targetID); err == nil {
+			return &database.ReportContent{
+				Author: comment.Username,
+				Title:  comment.PostTitle,
+				Text:   comment.Content,
+				PostID: comment.PostID,
+				Hidden: comment.Hidden,
+			}, comment.UserID, nil
+		}
+	case "user":
+		var profile *database.UserProfile
+		if profile, err = app.Store.Users.Profile(r.Context(), targetID); err == nil {
+			return &database.ReportContent{Author: profile.Username, Text: profile.Bio}, profile.UserID, nil
+		}
+	default:
+		err = store.ErrNotFound
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, 0, nil
+	}
+	return nil, 0, err
+}
+
+// moderateTarget hides or deletes a reported post or comment
+func (app *App) moderateTarget(r *http.Request, targetType string, targetID int, action string) error {
+	switch {
+	case targetType == "post" && action == "hide":
+		return app.Store.Posts.SetHidden(r.Context(), targetID, true)
+	case targetType == "post" && action == "delete":
+		return app.Store.Posts.Delete(r.Context(), targetID, currentUserID(r))
+	case targetType == "comment" && action == "hide":
+		return app.Store.Comments.SetHidden(r.Context(), targetID, true)
+	default:
+		return app.Store.Comments.Delete(r.Context(), targetID, currentUserID(r))
+	}
+}
+
+// notify sends a system notification, optionally linked to a post or comment
+func (app *App) notify(r *http.Request, userID int, title, message string, postID, commentID *int) {
+	if err := app.CreateNotification(userID, "system", title, message, postID, commentID, nil); err != nil {
+		slog.ErrorContext(r.Context(), "notify user",
+			slog.String("request_id", middleware.RequestIDFrom(r.Context())),
+			slog.Int("user_id", userID),
+			slog.Any("error", err),
+		)
+	}
+}
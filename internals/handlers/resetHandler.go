@@ -2,38 +2,33 @@ package handlers
 
 import (
 	"bytes"
-	"database/sql"
+	"errors"
 	"fmt"
-	"forum/internals/database"
+	"forum/internals/store"
 	"forum/internals/utils"
 	"html/template"
 	"net/http"
 	"net/smtp"
+	"net/url"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
-	// GET: serve the reset form with token from the URL (?token=...)
-	if r.Method == http.MethodGet {
-		token := strings.TrimSpace(r.URL.Query().Get("token"))
-		if token == "" {
-			// Show initial reset request form
-			utils.FileService("request-reset.html", w, nil)
-			return
-		}
-		// Show password reset form with token
-		utils.FileService("add-newpassword.html", w, map[string]interface{}{"Token": token})
-		return
-	}
-
-	// POST: perform the reset
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// ResetPasswordPageHandler serves the reset form with token from the URL (?token=...)
+func ResetPasswordPageHandler(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimSpace(r.URL.Query().Get("token"))
+	if token == "" {
+		// Show initial reset request form
+		utils.FileService("request-reset.html", w, nil)
 		return
 	}
+	// Show password reset form with token
+	utils.FileService("add-newpassword.html", w, map[string]interface{}{"Token": token})
+}
 
+// ResetPasswordHandler performs the reset
+func (app *App) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	token := strings.TrimSpace(r.FormValue("token"))
 	newPassword := r.FormValue("newPassword")
 	confirm := r.FormValue("confirmPassword")
@@ -47,14 +42,9 @@ func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	db := database.CreateTable()
-	defer db.Close()
-
 	// find user by token and get current password
-	var userID int
-	var currentHashedPassword string
-	err := db.QueryRow("SELECT user_id, password_hash FROM Users WHERE reset_token = ?", token).Scan(&userID, &currentHashedPassword)
-	if err == sql.ErrNoRows {
+	user, err := app.Store.Users.GetByResetToken(r.Context(), token)
+	if errors.Is(err, store.ErrNotFound) {
 		showErrorOnPage(w, token, "Invalid or expired reset link")
 		return
 	} else if err != nil {
@@ -62,6 +52,7 @@ func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	userID, currentHashedPassword := user.UserID, user.PasswordHash
 	if currentHashedPassword == "" {
 		showErrorOnPage(w, token, "User password not found")
 		return
@@ -81,24 +72,25 @@ func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 		showErrorOnPage(w, token, "Failed to process password")
 		return
 	}
-	_, err = db.Exec("UPDATE Users SET password_hash = ?, reset_token = NULL WHERE user_id = ?", string(hashed), userID)
+	err = app.Store.Users.ResetPassword(r.Context(), userID, string(hashed))
 	if err != nil {
 		showErrorOnPage(w, token, "Failed to update password")
 		return
 	}
 
-	http.Redirect(w, r, "/login.html?message=password_reset_success", http.StatusSeeOther)
+	http.Redirect(w, r, "/login?message=password_reset_success", http.StatusSeeOther)
 }
 
-func SendResetEmail(toEmail, token string) error {
+func (app *App) SendResetEmail(toEmail, token string) error {
 
-	from := "plant.talk2025@gmail.com"
-	password := "niicnftnethvawxf"
-	smtpHost := "smtp.gmail.com"
-	smtpPort := "587"
+	smtpConfig := app.Config.SMTP
+	if !smtpConfig.Enabled() {
+		return fmt.Errorf("email delivery is not configured")
+	}
+	from := smtpConfig.From
 
 	// Reset link
-	resetLink := fmt.Sprintf("http://localhost:8080/reset-password?token=%s", token)
+	resetLink := app.Config.URL("/reset-password?token=" + url.QueryEscape(token))
 
 	// Parse and execute the HTML template
 	tmpl, err := template.ParseFiles("frontend/templates/email-reset.html")
@@ -135,11 +127,14 @@ func SendResetEmail(toEmail, token string) error {
 	message.WriteString("\r\n")
 	message.Write(body.Bytes())
 
-	// SMTP Auth
-	auth := smtp.PlainAuth("", from, password, smtpHost)
+	// SMTP Auth (skipped for relays that accept unauthenticated mail)
+	var auth smtp.Auth
+	if smtpConfig.Username != "" {
+		auth = smtp.PlainAuth("", smtpConfig.Username, smtpConfig.Password, smtpConfig.Host)
+	}
 
 	// Send email
-	err = smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{toEmail}, message.Bytes())
+	err = smtp.SendMail(smtpConfig.Host+":"+smtpConfig.Port, auth, from, []string{toEmail}, message.Bytes())
 	if err != nil {
 		return fmt.Errorf("failed to send email: %v", err)
 	}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"forum/internals/database"
+	"forum/internals/store"
+	"forum/internals/utils"
+	"net/http"
+	"slices"
+	"strconv"
+)
+
+// PostRevisionsHandler lists every version of an edited post, the original
+// first (GET /api/posts/{id}/revisions)
+func (app *App) PostRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	post, ok := app.visiblePost(w, r)
+	if !ok {
+		return
+	}
+	revisions, err := app.Store.Revisions.ListPost(r.Context(), post.PostID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeRevisions(w, revisions)
+}
+
+// PostRevisionDiffHandler compares two versions of a post given by revision
+// ID as ?from= and ?to=, by default the last one with the one before it
+// (GET /api/posts/{id}/revisions/diff)
+func (app *App) PostRevisionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	post, ok := app.visiblePost(w, r)
+	if !ok {
+		return
+	}
+	revisions, err := app.Store.Revisions.ListPost(r.Context(), post.PostID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeRevisionDiff(w, r, revisions)
+}
+
+// RevertPostHandler restores the title, content and categories of an earlier
+// version of a post, recorded as a new revision by the moderator; posts in
+// the trash are not found (POST /api/posts/{id}/revisions/{rev}/revert)
+func (app *App) RevertPostHandler(w http.ResponseWriter, r *http.Request) {
+	post, ok := app.visiblePost(w, r)
+	if !ok {
+		return
+	}
+	postID := post.PostID
+	revision, ok := pathRevision(w, r, app.Store.Revisions.GetPost, postID)
+	if !ok {
+		return
+	}
+
+	// Categories deleted since then are left out
+	list, err := app.Store.Categories.List(r.Context(), true)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	var categoryIDs []int
+	for _, c := range list {
+		if slices.Contains(revision.Categories, c.Name) {
+			categoryIDs = append(categoryIDs, c.CategoryID)
+		}
+	}
+	if len(categoryIDs) == 0 {
+		http.Error(w, "The categories of this revision no longer exist", http.StatusConflict)
+		return
+	}
+
+	if err := app.Store.Posts.Update(r.Context(), postID, currentUserID(r), revision.Title, revision.Content, categoryIDs, nil); err != nil {
+		http.Error(w, "Failed to revert post", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "revert", "post", postID, "revision "+strconv.Itoa(revision.RevisionID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// CommentRevisionsHandler lists every version of an edited comment, the
+// original first (GET /api/comments/{id}/revisions)
+func (app *App) CommentRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	comment, ok := app.visibleComment(w, r)
+	if !ok {
+		return
+	}
+	revisions, err := app.Store.Revisions.ListComment(r.Context(), comment.CommentID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeRevisions(w, revisions)
+}
+
+// CommentRevisionDiffHandler compares two versions of a comment like
+// PostRevisionDiffHandler (GET /api/comments/{id}/revisions/diff)
+func (app *App) CommentRevisionDiffHandler(w http.ResponseWriter, r *http.Request) {
+	comment, ok := app.visibleComment(w, r)
+	if !ok {
+		return
+	}
+	revisions, err := app.Store.Revisions.ListComment(r.Context(), comment.CommentID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	writeRevisionDiff(w, r, revisions)
+}
+
+// RevertCommentHandler restores the content of an earlier version of a
+// comment, unless it is in the trash (POST /api/comments/{id}/revisions/{rev}/revert)
+func (app *App) RevertCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment, ok := app.visibleComment(w, r)
+	if !ok {
+		return
+	}
+	commentID := comment.CommentID
+	revision, ok := pathRevision(w, r, app.Store.Revisions.GetComment, commentID)
+	if !ok {
+		return
+	}
+
+	if err := app.Store.Comments.Update(r.Context(), commentID, currentUserID(r), revision.Content); err != nil {
+		http.Error(w, "Failed to revert comment", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "revert", "comment", commentID, "revision "+strconv.Itoa(revision.RevisionID))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// visiblePost loads the post named by the {id} path value, writing the error
+// response and returning false when it is missing or hidden from the user
+func (app *App) visiblePost(w http.ResponseWriter, r *http.Request) (*database.Post, bool) {
+	postID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return nil, false
+	}
+	post, err := app.Store.Posts.Get(r.Context(), postID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && post.Hidden && !canModify(r, post.UserID)) {
+		http.Error(w, "Post not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return post, true
+}
+
+// visibleComment loads the comment named by the {id} path value like visiblePost
+func (app *App) visibleComment(w http.ResponseWriter, r *http.Request) (*database.Comment, bool) {
+	commentID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return nil, false
+	}
+	comment, err := app.Store.Comments.Get(r.Context(), commentID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && comment.Hidden && !canModify(r, comment.UserID)) {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return comment, true
+}
+
+// pathRevision loads the revision named by the {rev} path value with get,
+// writing the error response and returning false unless it is a revision of
+// targetID
+func pathRevision(w http.ResponseWriter, r *http.Request, get func(ctx context.Context, revisionID int) (*database.Revision, error), targetID int) (*database.Revision, bool) {
+	revisionID, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil {
+		http.Error(w, "Invalid revision ID", http.StatusBadRequest)
+		return nil, false
+	}
+	revision, err := get(r.Context(), revisionID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && revision.TargetID != targetID) {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return revision, true
+}
+
+func newRevisionResponse(rev database.Revision, number int) database.RevisionResponse {
+	return database.RevisionResponse{
+		ID:         rev.RevisionID,
+		Number:     number,
+		Editor:     rev.Editor,
+		Title:      rev.Title,
+		Content:    rev.Content,
+		Categories: rev.Categories,
+		CreatedAt:  rev.CreationDate,
+		TimeAgo:    utils.FormatTimeAgo(rev.CreationDate),
+	}
+}
+
+func writeRevisions(w http.ResponseWriter, revisions []database.Revision) {
+	list := []database.RevisionResponse{}
+	for i, rev := range revisions {
+		list = append(list, newRevisionResponse(rev, i+1))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// writeRevisionDiff writes the line diffs of the title and content between
+// the ?from= and ?to= revisions, with the categories added and removed
+func writeRevisionDiff(w http.ResponseWriter, r *http.Request, revisions []database.Revision) {
+	if len(revisions) < 2 {
+		http.Error(w, "Not edited", http.StatusNotFound)
+		return
+	}
+
+	// Versions are found by revision ID, defaulting to the last two
+	find := func(param string, fallback int) int {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			return fallback
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return -1
+		}
+		return slices.IndexFunc(revisions, func(rev database.Revision) bool { return rev.RevisionID == id })
+	}
+	to := find("to", len(revisions)-1)
+	from := find("from", to-1)
+	if to < 0 || from < 0 {
+		http.Error(w, "Revision not found", http.StatusNotFound)
+		return
+	}
+	before, after := revisions[from], revisions[to]
+
+	added, removed := []string{}, []string{}
+	for _, name := range after.Categories {
+		if !slices.Contains(before.Categories, name) {
+			added = append(added, name)
+		}
+	}
+	for _, name := range before.Categories {
+		if !slices.Contains(after.Categories, name) {
+			removed = append(removed, name)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":              newRevisionResponse(before, from+1),
+		"to":                newRevisionResponse(after, to+1),
+		"title":             utils.DiffLines(before.Title, after.Title),
+		"content":           utils.DiffLines(before.Content, after.Content),
+		"categoriesAdded":   added,
+		"categoriesRemoved": removed,
+	})
+}
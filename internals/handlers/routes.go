@@ -2,121 +2,251 @@ package handlers
 
 import (
 	"fmt"
+	"forum/internals/middleware"
+	"forum/internals/roles"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"time"
 )
 
-func SetupRoutes() {
-	// Authentication routes
-	wrapHandler("/login",  LoginHandler)
-	wrapHandler("/login.html",  LoginHandler)
-	wrapHandler("/register",  RegisterHandler)
-	wrapHandler("/register.html",  RegisterHandler)
-	wrapHandler("/logout",  LogoutHandler)
-
-	// Post routes
-	wrapHandler("/new-post",  CreatePostHandler)
-	wrapHandler("/new-post.html",  CreatePostHandler)
-	wrapHandler("/api/posts",  PostsAPIHandler)
-	wrapHandler("/api/posts/edit",  EditPostHandler)
-	wrapHandler("/api/posts/delete",  DeletePostHandler)
-
-	// Single post view
-	wrapHandler("/view-post",  ViewPostHandler)
-	wrapHandler("/view-post.html",  ViewPostHandler)
-	wrapHandler("/api/post",  SinglePostAPIHandler) // API for single post, Since Go doesn't support URL parameters like /api/post/{id} natively
-
-	// Comment routes
-	wrapHandler("/api/comments/create",  CreateCommentHandler)
-	wrapHandler("/api/comments",  CommentsAPIHandler)
-	wrapHandler("/api/comments/edit",  EditCommentHandler)
-	wrapHandler("/api/comments/delete",  DeleteCommentHandler)
-
-	// Like/Dislike routes
-	wrapHandler("/api/posts/like",  LikePostHandler)
-	wrapHandler("/api/comments/like",  LikeCommentHandler)
+// Routes registers every route on a new ServeMux and returns it
+func (app *App) Routes() http.Handler {
+	mux := http.NewServeMux()
+
+	// handle registers a route behind the per-route middlewares given;
+	// every route knows who the visitor is and checks CSRF tokens on
+	// state-changing requests, auth marks the ones that need a login
+	handle := func(pattern string, handler http.HandlerFunc, middlewares ...middleware.Middleware) {
+		middlewares = append([]middleware.Middleware{middleware.OptionalAuth(app.Store.Sessions), middleware.CSRF}, middlewares...)
+		mux.Handle(pattern, middleware.Chain(middlewares...)(handler))
+	}
+	auth := middleware.RequireAuth
+	can := middleware.RequirePermission
+
+	// limit throttles a route with its configured rate limit, per key
+	limit := func(name string, keys ...middleware.RateKey) middleware.Middleware {
+		l := app.Config.RateLimits.Routes[name]
+		return middleware.RateLimit(app.Store.RateLimits, name, l.Requests, time.Duration(l.Window), keys...)
+	}
+	byIP, byUser := middleware.ByIP, middleware.ByUser
+	commentLimit := limit("comment", byUser)
+	voteLimit := limit("vote", byUser)
+
+	// Authentication routes. Login and password resets are also limited per
+	// email address, which stops password guessing spread over many IPs but
+	// lets anyone who burns an account's budget lock it out of logging in
+	// for the window; raise or turn off the limit if that hurts more
+	handle("GET /login", LoginPageHandler)
+	handle("POST /login", app.LoginHandler, limit("login", byIP, middleware.ByFormField("email")))
+	handle("GET /register", RegisterPageHandler)
+	handle("POST /register", app.RegisterHandler, limit("register", byIP))
+	handle("POST /logout", app.LogoutHandler)
+
+	// Post pages
+	handle("GET /new-post", NewPostPageHandler)
+	handle("POST /new-post", app.CreatePostHandler, auth, limit("post", byUser))
+	handle("GET /view-post", ViewPostHandler)
+
+	// Post API
+	handle("GET /api/posts", app.PostsAPIHandler)
+	handle("GET /api/posts/filtered", app.FilteredPostsHandler, auth)
+	handle("GET /api/posts/{id}", app.SinglePostAPIHandler)
+	handle("PATCH /api/posts/{id}", app.EditPostHandler, auth)
+	handle("DELETE /api/posts/{id}", app.DeletePostHandler, auth)
+	handle("POST /api/posts/{id}/vote", app.LikePostHandler, auth, voteLimit)
+	handle("POST /api/markdown/preview", app.MarkdownPreviewHandler, auth, limit("preview", byUser))
+
+	// Drafts API
+	handle("GET /api/drafts", app.DraftsAPIHandler, auth)
+	handle("POST /api/drafts", app.SaveDraftHandler, auth, limit("draft", byUser))
+	handle("GET /api/drafts/{id}", app.DraftAPIHandler, auth)
+	handle("DELETE /api/drafts/{id}", app.DeleteDraftHandler, auth)
+	handle("POST /api/drafts/{id}/publish", app.PublishDraftHandler, auth, limit("post", byUser))
+
+	// Comment API
+	handle("GET /api/posts/{id}/comments", app.CommentsAPIHandler)
+	handle("POST /api/posts/{id}/comments", app.CreateCommentHandler, auth, commentLimit)
+	handle("GET /api/posts/{id}/stream", app.PostStreamHandler)
+	handle("PATCH /api/comments/{id}", app.EditCommentHandler, auth)
+	handle("DELETE /api/comments/{id}", app.DeleteCommentHandler, auth)
+	handle("POST /api/comments/{id}/vote", app.LikeCommentHandler, auth, voteLimit)
+
+	// Revision history
+	handle("GET /api/posts/{id}/revisions", app.PostRevisionsHandler)
+	handle("GET /api/posts/{id}/revisions/diff", app.PostRevisionDiffHandler)
+	handle("POST /api/posts/{id}/revisions/{rev}/revert", app.RevertPostHandler, auth, can(roles.ModerateContent))
+	handle("GET /api/comments/{id}/revisions", app.CommentRevisionsHandler)
+	handle("GET /api/comments/{id}/revisions/diff", app.CommentRevisionDiffHandler)
+	handle("POST /api/comments/{id}/revisions/{rev}/revert", app.RevertCommentHandler, auth, can(roles.ModerateContent))
+
+	// Trash
+	handle("GET /api/trash/posts", app.TrashPostsHandler, auth)
+	handle("GET /api/trash/comments", app.TrashCommentsHandler, auth)
+	handle("POST /api/posts/{id}/restore", app.RestorePostHandler, auth)
+	handle("POST /api/comments/{id}/restore", app.RestoreCommentHandler, auth)
+
+	// Legacy query-string and form routes used by older pages
+	handle("GET /api/post", legacyRedirect("id", "/api/posts/%s"))
+	handle("GET /api/comments", legacyRedirect("post_id", "/api/posts/%s/comments"))
+	handle("POST /api/posts/edit", legacyFormID("post_id", app.EditPostHandler), auth)
+	handle("POST /api/posts/delete", legacyFormID("post_id", app.DeletePostHandler), auth)
+	handle("POST /api/posts/like", legacyFormID("post_id", app.LikePostHandler), auth, voteLimit)
+	handle("POST /api/comments/create", legacyFormID("post_id", app.CreateCommentHandler), auth, commentLimit)
+	handle("POST /api/comments/edit", legacyFormID("comment_id", app.EditCommentHandler), auth)
+	handle("POST /api/comments/delete", legacyFormID("comment_id", app.DeleteCommentHandler), auth)
+	handle("POST /api/comments/like", legacyFormID("comment_id", app.LikeCommentHandler), auth, voteLimit)
+	handle("POST /api/notifications/mark-read", legacyFormID("notification_id", app.MarkNotificationReadHandler), auth)
+
+	// Admin and moderation API
+	handle("GET /api/admin/users", app.AdminUsersHandler, auth, can(roles.ManageUsers))
+	handle("PATCH /api/admin/users/{id}/role", app.SetUserRoleHandler, auth, can(roles.ManageUsers))
+	handle("POST /api/admin/categories", app.CreateCategoryHandler, auth, can(roles.ManageCategories))
+	handle("PATCH /api/admin/categories/{id}", app.UpdateCategoryHandler, auth, can(roles.ManageCategories))
+	handle("POST /api/admin/categories/reorder", app.ReorderCategoriesHandler, auth, can(roles.ManageCategories))
+	handle("POST /api/admin/categories/{id}/merge", app.MergeCategoryHandler, auth, can(roles.ManageCategories))
+	handle("POST /api/admin/categories/{id}/archive", app.ArchiveCategoryHandler(true), auth, can(roles.ManageCategories))
+	handle("POST /api/admin/categories/{id}/unarchive", app.ArchiveCategoryHandler(false), auth, can(roles.ManageCategories))
+	handle("DELETE /api/admin/categories/{id}", app.DeleteCategoryHandler, auth, can(roles.ManageCategories))
+	handle("GET /api/moderation/log", app.ModerationLogHandler, auth, can(roles.ViewModerationLog))
+
+	// Reports and the moderation queue
+	handle("POST /api/reports", app.CreateReportHandler, auth, limit("report", byUser))
+	handle("GET /api/reports", app.ReportsQueueHandler, auth, can(roles.ModerateContent))
+	handle("POST /api/reports/{id}/resolve", app.ResolveReportHandler, auth, can(roles.ModerateContent))
 
 	// User data routes
-	http.HandleFunc("/api/user/posts",  UserPostsHandler)
-
-	wrapHandler("/api/user/comments",  UserCommentsHandler)
-	wrapHandler("/api/user/likes",  UserLikesHandler)
-	wrapHandler("/api/user/dislikes", UserDislikesHandler)
-
+	handle("GET /api/user/posts", app.UserPostsHandler, auth)
+	handle("GET /api/user/comments", app.UserCommentsHandler, auth)
+	handle("GET /api/user/likes", app.UserLikesHandler, auth)
+	handle("GET /api/user/dislikes", app.UserDislikesHandler, auth)
 
 	// Google OAuth routes
-	http.HandleFunc("/auth/google",  GoogleLogin)
-	http.HandleFunc("/auth/google/callback",  GoogleCallback)
+	handle("GET /auth/google", app.GoogleLogin)
+	handle("GET /auth/google/callback", app.GoogleCallback)
 
 	// GitHub OAuth routes
-	http.HandleFunc("/auth/github",  GitHubLogin)
-	http.HandleFunc("/auth/github/callback",  GitHubCallback)
+	handle("GET /auth/github", app.GitHubLogin)
+	handle("GET /auth/github/callback", app.GitHubCallback)
 
-	// Category routes
-	wrapHandler("/api/categories",  CategoriesAPIHandler)
-	wrapHandler("/categories",  CategoriesPageHandler)
-	wrapHandler("/categories.html",  CategoriesPageHandler)
+	// Tags
+	handle("GET /api/tags", app.TagsAPIHandler)
+	handle("GET /api/tags/{name}", app.TagAPIHandler)
+	handle("POST /api/tags/{name}/merge", app.MergeTagHandler, auth, can(roles.ModerateContent))
+	handle("GET /tags/{name}", TagPageHandler)
+
+	// Search
+	handle("GET /api/search", app.SearchAPIHandler)
+	handle("GET /search", SearchPageHandler)
 
-	// Forgot - Reset Password routs
-	wrapHandler("/forgot-password",  ForgotPasswordHandler)
-	wrapHandler("/forgot-password.html",  ForgotPasswordPageHandler)
-	wrapHandler("/reset-password",  ResetPasswordHandler)
-	wrapHandler("/add-newpassword.html",  ShowResetFormHandler)
+	// Category routes
+	handle("GET /api/categories", app.CategoriesAPIHandler)
+	handle("GET /categories", CategoriesPageHandler)
 
-	// Filter routes (for authenticated users)
-	wrapHandler("/api/posts/filtered",  FilteredPostsHandler)
+	// Forgot - Reset Password routes
+	handle("GET /forgot-password", ForgotPasswordPageHandler)
+	handle("POST /forgot-password", app.ForgotPasswordHandler, limit("forgot-password", byIP, middleware.ByFormField("email")))
+	handle("GET /reset-password", ResetPasswordPageHandler)
+	handle("POST /reset-password", app.ResetPasswordHandler, limit("reset-password", byIP))
 
-	// Auth status check - ONLY ONE REGISTRATION
-	wrapHandler("/api/auth/status",  AuthStatusHandler)
+	// Auth status check
+	handle("GET /api/auth/status", app.AuthStatusHandler)
 
 	// Notifications API
-	wrapHandler("/api/notifications", NotificationsAPIHandler)
-	wrapHandler("/api/notifications/mark-read", MarkNotificationReadHandler)
-	wrapHandler("/api/notifications/mark-all-read",  MarkAllNotificationsReadHandler)
-	wrapHandler("/api/notifications/count",  NotificationCountHandler)
+	handle("GET /api/notifications", app.NotificationsAPIHandler, auth)
+	handle("GET /api/notifications/count", app.NotificationCountHandler)
+	handle("POST /api/notifications/{id}/read", app.MarkNotificationReadHandler, auth)
+	handle("POST /api/notifications/mark-all-read", app.MarkAllNotificationsReadHandler, auth)
+	handle("GET /api/notifications/stream", app.NotificationStreamHandler, auth)
 
 	// Image upload and management
-	wrapHandler("/api/upload-image",  ImageUploadHandler)
-	http.HandleFunc("/upload-image",  ImageUploadHandler)
-	wrapHandler("/api/delete-image",  DeleteImageHandler)
-	wrapHandler("/api/user-images",  GetUserImagesHandler)
+	handle("POST /api/upload-image", app.ImageUploadHandler, auth)
+	handle("POST /upload-image", app.ImageUploadHandler, auth)
+	handle("POST /api/delete-image", app.DeleteImageHandler, auth)
+	handle("GET /api/user-images", app.GetUserImagesHandler, auth)
 
 	// Profile routes
-	wrapHandler("/api/user/profile",  ProfileAPIHandler)
-	wrapHandler("/profile",  ProfileHandler)
-	wrapHandler("/profile.html",  ProfilePageHandler)
+	handle("GET /api/user/profile", app.ProfileAPIHandler, auth)
+	handle("GET /profile", ProfilePageHandler, auth)
+	handle("POST /profile", app.UpdateProfileHandler, auth)
+	handle("GET /users/{name}", UserPageHandler)
 
-	// Notifications routes
-	wrapHandler("/notifications", NotificationsPageHandler)
-	wrapHandler("/notifications.html", NotificationsPageHandler)
+	// Notifications page
+	handle("GET /notifications", NotificationsPageHandler, auth)
 
 	// Static pages
-	wrapHandler("/about", AboutHandler)
-	wrapHandler("/about.html", AboutHandler)
-	wrapHandler("/terms", TermsHandler)
-	wrapHandler("/terms.html", TermsHandler)
+	handle("GET /about", AboutHandler)
+	handle("GET /terms", TermsHandler)
+
+	// Old .html addresses redirect to their clean paths
+	for page, path := range map[string]string{
+		"/login.html":           "/login",
+		"/register.html":        "/register",
+		"/new-post.html":        "/new-post",
+		"/view-post.html":       "/view-post",
+		"/categories.html":      "/categories",
+		"/forgot-password.html": "/forgot-password",
+		"/add-newpassword.html": "/reset-password",
+		"/profile.html":         "/profile",
+		"/notifications.html":   "/notifications",
+		"/search.html":          "/search",
+		"/about.html":           "/about",
+		"/terms.html":           "/terms",
+	} {
+		handle("GET "+page, redirectTo(path))
+	}
 
 	// Error routes
-	wrapHandler("/400", BadRequestHandler)
-	wrapHandler("/404", NotFoundHandler)
-	wrapHandler("/500", InternalServerErrorHandler)
+	handle("GET /400", BadRequestHandler)
+	handle("GET /404", NotFoundHandler)
+	handle("GET /500", InternalServerErrorHandler)
 
 	// Static files (CSS, images, JavaScript)
 	fs := http.FileServer(http.Dir("frontend/"))
-	http.Handle("/frontend/", http.StripPrefix("/frontend/", fs))
+	mux.Handle("GET /frontend/", http.StripPrefix("/frontend/", fs))
+
+	// Homepage, and the 404 page for any other GET; other methods on
+	// unknown paths get the mux's automatic 405
+	handle("GET /{$}", app.HomeHandler)
+	handle("GET /", NotFoundHandler)
+
+	// Every request gets an ID, an access log line and panic recovery
+	logger := slog.Default()
+	return middleware.Chain(
+		middleware.RequestID,
+		middleware.Logger(logger),
+		middleware.Recover(logger, InternalServerErrorHandler),
+	)(mux)
+}
+
+// redirectTo permanently redirects to path, keeping the query string
+func redirectTo(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		target := path
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	}
+}
 
-	// Homepage last to avoid conflicts
-	wrapHandler("/", HomeHandler)
+// legacyRedirect redirects an old ?param= route to its path-parameter form
+func legacyRedirect(param, format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get(param)
+		if id == "" {
+			http.Error(w, "Missing "+param, http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf(format, url.PathEscape(id)), http.StatusPermanentRedirect)
+	}
 }
 
-// wrapHandler wraps handlers with error handling
-func wrapHandler(path string, handler http.HandlerFunc) {
-	http.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				fmt.Printf("Panic on %s: %v\n", path, err)
-				InternalServerErrorHandler(w, r)
-			}
-		}()
+// legacyFormID serves an old form endpoint by copying the ID field into
+// the {id} path value the handler reads
+func legacyFormID(field string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.SetPathValue("id", r.FormValue(field))
 		handler(w, r)
-	})
+	}
 }
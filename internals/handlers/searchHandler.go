@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"forum/internals/database"
+	"forum/internals/store"
+	"forum/internals/utils"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxSearchTerms caps the words of a search query
+const maxSearchTerms = 10
+
+// marks turns the match markers of the store into HTML once the text is escaped
+var marks = strings.NewReplacer(store.MarkStart, "<mark>", store.MarkEnd, "</mark>")
+
+// SearchPageHandler serves the search results page
+func SearchPageHandler(w http.ResponseWriter, r *http.Request) {
+	utils.FileService("search.html", w, nil)
+}
+
+// SearchAPIHandler runs a full-text search (GET /api/search). It takes the
+// words to find in ?q=, ?type=posts or comments, the ?category=, ?author=,
+// ?from= and ?to= (YYYY-MM-DD, both included) filters, and ?limit= and ?cursor=.
+func (app *App) SearchAPIHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	q := store.SearchQuery{
+		Terms:    searchTerms(query.Get("q")),
+		Category: query.Get("category"),
+		Author:   strings.TrimSpace(query.Get("author")),
+	}
+	if len(q.Terms) == 0 {
+		http.Error(w, "Missing search query", http.StatusBadRequest)
+		return
+	}
+
+	var err error
+	if q.From, err = searchDate(query.Get("from")); err != nil {
+		http.Error(w, "from must be a date like 2006-01-02", http.StatusBadRequest)
+		return
+	}
+	if q.To, err = searchDate(query.Get("to")); err != nil {
+		http.Error(w, "to must be a date like 2006-01-02", http.StatusBadRequest)
+		return
+	}
+	if !q.To.IsZero() {
+		// The whole day given is included
+		q.To = q.To.Add(24 * time.Hour)
+	}
+
+	page, err := pageParams(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	var hits []database.SearchHit
+	resultType := query.Get("type")
+	switch resultType {
+	case "", "posts":
+		resultType = "post"
+		hits, err = app.Store.Search.Posts(r.Context(), q, page)
+	case "comments":
+		resultType = "comment"
+		hits, err = app.Store.Search.Comments(r.Context(), q, page)
+	default:
+		http.Error(w, "type must be posts or comments", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	hits, next := nextCursor(hits, page, store.SearchCursor)
+
+	results := []database.SearchResult{}
+	for _, hit := range hits {
+		results = append(results, database.SearchResult{
+			Type:      resultType,
+			PostID:    hit.PostID,
+			CommentID: hit.CommentID,
+			Title:     marks.Replace(html.EscapeString(hit.Title)),
+			Snippet:   marks.Replace(html.EscapeString(hit.Snippet)),
+			Author:    hit.Username,
+			TimeAgo:   utils.FormatTimeAgo(hit.CreationDate),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(database.SearchPage{Results: results, NextCursor: next})
+}
+
+// searchTerms splits a search query into words the way the search index
+// does, at anything but letters and digits
+func searchTerms(q string) []string {
+	terms := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// searchDate parses a date filter, the zero time when it is empty
+func searchDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -1,32 +1,208 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"fmt"
+	"forum/internals/database"
+	"forum/internals/markdown"
+	"forum/internals/middleware"
+	"forum/internals/roles"
+	"forum/internals/store"
 	"forum/internals/utils"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
-// getPostTags returns the category tags for a given post
-func getPostTags(db *sql.DB, postID int) []string {
-	rows, err := db.Query(`
-		SELECT c.name FROM Categories c
-		JOIN PostCategories pc ON c.category_id = pc.category_id
-		WHERE pc.post_id = ?`, postID)
+// maxContentLength caps the length, in characters, of post and comment content
+const maxContentLength = 20000
+
+// errContentTooLong is the error for content over maxContentLength
+var errContentTooLong = fmt.Errorf("Content must be at most %d characters", maxContentLength)
+
+// contentTooLong reports whether post or comment content is over maxContentLength
+func contentTooLong(content string) bool {
+	return utf8.RuneCountInString(content) > maxContentLength
+}
+
+// maxLinkedMentions caps how many @mentioned names of one post or comment
+// are looked up to be linked; the others are shown as plain text
+const maxLinkedMentions = 50
+
+// mentionedUsers looks up, in one query, the users @mentioned in any of
+// contents, and returns the check markdown.Render needs to link only them.
+// Names it could not look up are left unlinked.
+func (app *App) mentionedUsers(ctx context.Context, contents ...string) func(name string) bool {
+	var names []string
+	seen := map[string]bool{}
+	for _, content := range contents {
+		if !strings.Contains(content, "@") {
+			continue
+		}
+		mentions := markdown.Mentions(content)
+		for _, name := range mentions[:min(len(mentions), maxLinkedMentions)] {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	known := map[string]bool{}
+	if len(names) > 0 {
+		users, _ := app.Store.Users.ListByUsernames(ctx, names)
+		for _, u := range users {
+			known[u.Username] = true
+		}
+	}
+	return func(name string) bool { return known[name] }
+}
+
+// postContents lists the content of each post, for mentionedUsers
+func postContents(posts []database.Post) []string {
+	contents := make([]string, len(posts))
+	for i, p := range posts {
+		contents[i] = p.Content
+	}
+	return contents
+}
+
+// newPostResponse converts a stored post into the JSON shape used by the
+// frontend; isUser comes from mentionedUsers
+func newPostResponse(p database.Post, isUser func(name string) bool) database.PostResponse {
+	return database.PostResponse{
+		ID:           p.PostID,
+		Title:        p.Title,
+		Content:      p.Content,
+		ContentHTML:  markdown.Render(p.Content, isUser),
+		Author:       p.Username,
+		TimeAgo:      utils.FormatTimeAgo(p.CreationDate),
+		Categories:   p.Categories,
+		Tags:         p.Tags,
+		Comments:     p.Nbrcomments,
+		Likes:        p.Nbrlike,
+		Dislikes:     p.Nbrdislike,
+		Excerpt:      utils.TruncateText(p.Content, 150),
+		ImageURL:     p.ImageURL,
+		ThumbnailURL: p.ThumbnailURL,
+		Hidden:       p.Hidden,
+		Edited:       p.EditedAt != nil,
+		EditedAt:     p.EditedAt,
+	}
+}
+
+// currentUser returns the user resolved by the auth middleware, or nil for visitors
+func currentUser(r *http.Request) *middleware.CurrentUser {
+	return middleware.UserFrom(r.Context())
+}
+
+// currentUserID returns the ID of the logged in user, or 0 for visitors
+func currentUserID(r *http.Request) int {
+	if user := currentUser(r); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// canModerate reports whether the current user may moderate anyone's content
+func canModerate(r *http.Request) bool {
+	user := currentUser(r)
+	return user != nil && roles.Can(user.Role, roles.ModerateContent)
+}
+
+// canModify reports whether the current user may edit or delete content
+// owned by ownerID: its author, or anyone allowed to moderate content
+func canModify(r *http.Request, ownerID int) bool {
+	return (ownerID != 0 && currentUserID(r) == ownerID) || canModerate(r)
+}
+
+// recordModeration adds an action of the current user to the moderation log
+func (app *App) recordModeration(r *http.Request, action, targetType string, targetID int, details string) {
+	err := app.Store.ModerationLog.Record(r.Context(), &database.ModerationAction{
+		ActorID:    currentUserID(r),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+	})
 	if err != nil {
-		return []string{}
+		slog.ErrorContext(r.Context(), "record moderation action",
+			slog.String("request_id", middleware.RequestIDFrom(r.Context())),
+			slog.String("action", action),
+			slog.String("target_type", targetType),
+			slog.Int("target_id", targetID),
+			slog.Any("error", err),
+		)
 	}
-	defer rows.Close()
+}
+
+// pathID parses the {id} wildcard of the matched route
+func pathID(r *http.Request) (int, error) {
+	return strconv.Atoi(r.PathValue("id"))
+}
 
-	var tags []string
-	for rows.Next() {
-		var tag string
-		rows.Scan(&tag)
-		tags = append(tags, tag)
+// pageParams reads the ?limit= and ?cursor= of a paginated listing. The page
+// asks the store for one row more than the client wants, which nextCursor
+// uses to tell whether another page follows.
+func pageParams(r *http.Request, defaultLimit, maxLimit int) (store.Page, error) {
+	limit := min(max(getIntParam(r, "limit", defaultLimit), 1), maxLimit)
+	page := store.Page{Limit: limit + 1}
+
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		cursor, err := store.DecodeCursor(token)
+		if err != nil {
+			return page, err
+		}
+		page.After = cursor
 	}
-	return tags
+	return page, nil
 }
 
-// getUsernameFromSession returns the username for a given session cookie
-func GetUsernameFromSession(cookieValue string) string {
-	return utils.GetUsernameFromSession(cookieValue)
+// nextCursor trims a listing fetched with pageParams to the size the client
+// asked for and returns the cursor of the following page, or "" on the last one
+func nextCursor[T any](list []T, page store.Page, position func(T) store.Cursor) ([]T, string) {
+	if len(list) < page.Limit {
+		return list, ""
+	}
+	list = list[:page.Limit-1]
+	return list, position(list[len(list)-1]).Encode()
 }
 
+// postWindows are the time windows a post listing can be limited to
+var postWindows = map[string]time.Duration{
+	"day":   24 * time.Hour,
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// postOrder applies the ?sort= (new, top, hot or comments) and ?window= (day,
+// week, month or all) of a post listing to filter. Later pages rank posts as
+// of the time the first page was served, which their cursor carries.
+func postOrder(r *http.Request, page store.Page, filter *store.PostFilter) error {
+	filter.Sort = store.PostSort(r.URL.Query().Get("sort"))
+	if filter.Sort == "" {
+		filter.Sort = store.SortNew
+	}
+	if !filter.Sort.Valid() {
+		return errors.New("sort must be new, top, hot or comments")
+	}
+
+	filter.At = time.Now()
+	if page.After != nil && page.After.At != 0 {
+		filter.At = time.Unix(page.After.At, 0)
+	}
+
+	window := r.URL.Query().Get("window")
+	if window != "" && window != "all" {
+		d, ok := postWindows[window]
+		if !ok {
+			return errors.New("window must be day, week, month or all")
+		}
+		filter.Since = filter.At.Add(-d)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum/internals/events"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// streamHeartbeat is how often an idle stream sends a comment line, so
+	// proxies and browsers keep the connection open
+	streamHeartbeat = 25 * time.Second
+	// streamRetry is how long a browser waits before reconnecting a stream
+	streamRetry = 3 * time.Second
+)
+
+// stream describes an event stream served by streamEvents
+type stream struct {
+	topic string
+	// limit caps how many clients may stream the topic at once, 0 for no cap;
+	// the others get fullStatus (503 Service Unavailable if unset) and fullError
+	limit      int
+	fullStatus int
+	fullError  string
+	// initial returns the events every client starts with, such as the
+	// current state; it runs once subscribed, so no change is lost in between
+	initial func() ([]events.Event, error)
+	// view adapts an event to the client, or reports false to skip it; nil
+	// sends every event as published
+	view func(events.Event) (events.Event, bool)
+}
+
+// streamEvents sends the events of a topic to the client as Server-Sent
+// Events until it disconnects or the hub closes. A client reconnecting with
+// Last-Event-ID first gets the events it missed, then every client gets the
+// initial events of the stream.
+func (app *App) streamEvents(w http.ResponseWriter, r *http.Request, s stream) {
+	lastID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	sub, missed, err := app.Events.Subscribe(s.topic, lastID, s.limit)
+	if errors.Is(err, events.ErrFull) {
+		status := s.fullStatus
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Retry-After", "60")
+		http.Error(w, s.fullError, status)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer sub.Close()
+
+	var current []events.Event
+	if s.initial != nil {
+		if current, err = s.initial(); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep reverse proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	send := func(e events.Event) {
+		if s.view != nil {
+			var ok bool
+			if e, ok = s.view(e); !ok {
+				return
+			}
+		}
+		writeEvent(w, e)
+	}
+
+	rc := http.NewResponseController(w)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+	for _, e := range missed {
+		send(e)
+	}
+	for _, e := range current {
+		writeEvent(w, e)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			// A closed channel means the hub dropped this stream or shut
+			// down; the browser reconnects and catches up if it can
+			if !ok {
+				return
+			}
+			send(e)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes one event, its data encoded as JSON, in the
+// text/event-stream format; events without an ID, like a stream's initial
+// state, are not replayed
+func writeEvent(w http.ResponseWriter, e events.Event) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return
+	}
+	if e.ID != 0 {
+		fmt.Fprintf(w, "id: %d\n", e.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Name, data)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum/internals/database"
+	"forum/internals/store"
+	"forum/internals/utils"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTagLength caps the length of a normalized tag name
+const maxTagLength = 30
+
+// normalizeTag turns a tag as typed into its stored form: lowercase words
+// joined by dashes, so "#Monstera Deliciosa" becomes "monstera-deliciosa"
+func normalizeTag(tag string) string {
+	return utils.Slugify(tag)
+}
+
+// parseTags reads a comma-separated tag list, normalizing and deduplicating
+// the tags and checking them against the per-post cap. It never returns nil,
+// so an empty list clears the tags of an edited post.
+func parseTags(value string, maxPerPost int) ([]string, error) {
+	tags := []string{}
+	for _, raw := range strings.Split(value, ",") {
+		tag := normalizeTag(raw)
+		if tag == "" || slices.Contains(tags, tag) {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("Tags must be at most %d characters", maxTagLength)
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxPerPost {
+		return nil, fmt.Errorf("A post can have at most %d tags", maxPerPost)
+	}
+	return tags, nil
+}
+
+// TagPageHandler serves the page listing the posts of a tag
+func TagPageHandler(w http.ResponseWriter, r *http.Request) {
+	utils.FileService("tag.html", w, nil)
+}
+
+// TagsAPIHandler suggests tags for autocomplete (GET /api/tags): the tags
+// starting with ?q=, or every tag without it, most used first, at most ?limit=
+func (app *App) TagsAPIHandler(w http.ResponseWriter, r *http.Request) {
+	limit := min(max(getIntParam(r, "limit", 10), 1), 50)
+
+	list, err := app.Store.Tags.List(r.Context(), normalizeTag(r.URL.Query().Get("q")), limit)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	tags := []database.TagResponse{}
+	for _, t := range list {
+		tags = append(tags, database.TagResponse{Name: t.Name, PostCount: t.PostCount})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+// TagAPIHandler returns a tag with its usage count (GET /api/tags/{name}).
+// The name of a merged tag returns the tag it was merged into.
+func (app *App) TagAPIHandler(w http.ResponseWriter, r *http.Request) {
+	tag, err := app.Store.Tags.Get(r.Context(), normalizeTag(r.PathValue("name")))
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(database.TagResponse{Name: tag.Name, PostCount: tag.PostCount})
+}
+
+// MergeTagHandler merges a tag into the synonym given as into, e.g.
+// "monstera" into "monstera-deliciosa"; posts later tagged with the merged
+// name get the other tag (POST /api/tags/{name}/merge)
+func (app *App) MergeTagHandler(w http.ResponseWriter, r *http.Request) {
+	from, err := app.Store.Tags.Get(r.Context(), normalizeTag(r.PathValue("name")))
+	if err != nil {
+		http.Error(w, "Tag not found", http.StatusNotFound)
+		return
+	}
+	into, err := app.Store.Tags.Get(r.Context(), normalizeTag(r.FormValue("into")))
+	if err != nil {
+		http.Error(w, "Target tag not found", http.StatusNotFound)
+		return
+	}
+	if from.TagID == into.TagID {
+		http.Error(w, "A tag cannot be merged into itself", http.StatusBadRequest)
+		return
+	}
+
+	if err := app.Store.Tags.Merge(r.Context(), from.TagID, into.TagID); err != nil {
+		http.Error(w, "Failed to merge tags", http.StatusInternalServerError)
+		return
+	}
+	app.recordModeration(r, "merge", "tag", from.TagID, from.Name+" -> "+into.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "tag": into.Name})
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"forum/internals/database"
+	"forum/internals/store"
+	"forum/internals/utils"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// TrashPostsHandler lists the user's deleted posts that can still be
+// restored, last deleted first (GET /api/trash/posts)
+func (app *App) TrashPostsHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := pageParams(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	list, err := app.Store.Trash.Posts(r.Context(), currentUserID(r), app.trashCutoff(), page)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	list, next := nextCursor(list, page, store.TrashPostCursor)
+
+	items := []database.TrashItemResponse{}
+	for _, p := range list {
+		items = append(items, app.newTrashItem(r, "post", p.PostID, p.PostID, p.Title, p.Content, p.UserID, p.DeletedBy, *p.DeletedAt))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items, "nextCursor": next})
+}
+
+// TrashCommentsHandler lists the user's deleted comments that can still be
+// restored, last deleted first (GET /api/trash/comments)
+func (app *App) TrashCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := pageParams(r, 20, 100)
+	if err != nil {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+
+	list, err := app.Store.Trash.Comments(r.Context(), currentUserID(r), app.trashCutoff(), page)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	list, next := nextCursor(list, page, store.TrashCommentCursor)
+
+	items := []database.TrashItemResponse{}
+	for _, c := range list {
+		items = append(items, app.newTrashItem(r, "comment", c.CommentID, c.PostID, c.PostTitle, c.Content, c.UserID, c.DeletedBy, *c.DeletedAt))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"items": items, "nextCursor": next})
+}
+
+// RestorePostHandler takes a post out of the trash (POST /api/posts/{id}/restore)
+func (app *App) RestorePostHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	// Other users' trash is reported as empty
+	post, err := app.Store.Trash.Post(r.Context(), postID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !canModify(r, post.UserID)) {
+		http.Error(w, "Post not in the trash", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !app.checkRestore(w, r, post.UserID, post.DeletedBy, *post.DeletedAt) {
+		return
+	}
+
+	if err := app.Store.Posts.Restore(r.Context(), postID); err != nil {
+		http.Error(w, "Failed to restore post", http.StatusInternalServerError)
+		return
+	}
+	if post.UserID != currentUserID(r) {
+		app.recordModeration(r, "restore", "post", postID, post.Title)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// RestoreCommentHandler takes a comment out of the trash (POST /api/comments/{id}/restore)
+func (app *App) RestoreCommentHandler(w http.ResponseWriter, r *http.Request) {
+	commentID, err := pathID(r)
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := app.Store.Trash.Comment(r.Context(), commentID)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && !canModify(r, comment.UserID)) {
+		http.Error(w, "Comment not in the trash", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !app.checkRestore(w, r, comment.UserID, comment.DeletedBy, *comment.DeletedAt) {
+		return
+	}
+
+	if err := app.Store.Comments.Restore(r.Context(), commentID); err != nil {
+		http.Error(w, "Failed to restore comment", http.StatusInternalServerError)
+		return
+	}
+	if comment.UserID != currentUserID(r) {
+		app.recordModeration(r, "restore", "comment", commentID, utils.TruncateText(comment.Content, 100))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// PurgeTrash removes the content deleted longer ago than the retention
+// window, right away and then every purge interval until ctx is done
+func (app *App) PurgeTrash(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(app.Config.Trash.PurgeInterval))
+	defer ticker.Stop()
+
+	for {
+		// The purge runs outside any request, so its log has no request ID
+		cutoff := app.trashCutoff()
+		if err := app.Store.Trash.Purge(ctx, cutoff); err != nil {
+			slog.ErrorContext(ctx, "purge trash",
+				slog.Time("cutoff", cutoff),
+				slog.Any("error", err),
+			)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trashCutoff is the deletion time before which content can no longer be restored
+func (app *App) trashCutoff() time.Time {
+	return time.Now().Add(-time.Duration(app.Config.Trash.Retention))
+}
+
+// canRestore reports whether the user may take content out of the trash:
+// moderators always can, authors only what they deleted themselves
+func canRestore(r *http.Request, ownerID, deletedBy int) bool {
+	return canModerate(r) || (ownerID == currentUserID(r) && deletedBy == ownerID)
+}
+
+// checkRestore writes the error response and returns false when content
+// in the trash was deleted too long ago or is not the user's to restore
+func (app *App) checkRestore(w http.ResponseWriter, r *http.Request, ownerID, deletedBy int, deletedAt time.Time) bool {
+	if deletedAt.Before(app.trashCutoff()) {
+		http.Error(w, "Deleted too long ago to be restored", http.StatusGone)
+		return false
+	}
+	if !canRestore(r, ownerID, deletedBy) {
+		http.Error(w, "Only a moderator can restore this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (app *App) newTrashItem(r *http.Request, itemType string, id, postID int, title, content string, ownerID, deletedBy int, deletedAt time.Time) database.TrashItemResponse {
+	return database.TrashItemResponse{
+		Type:        itemType,
+		ID:          id,
+		PostID:      postID,
+		Title:       title,
+		Excerpt:     utils.TruncateText(content, 150),
+		DeletedAt:   deletedAt,
+		DeletedAgo:  utils.FormatTimeAgo(deletedAt),
+		PurgeAt:     deletedAt.Add(time.Duration(app.Config.Trash.Retention)),
+		ByModerator: deletedBy != ownerID,
+		CanRestore:  canRestore(r, ownerID, deletedBy),
+	}
+}
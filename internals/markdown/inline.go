@@ -0,0 +1,438 @@
+package markdown
+
+import (
+	"html"
+	"strings"
+)
+
+// inline renders the inline Markdown of one paragraph or heading. Searches
+// that found nothing are remembered, so text full of unmatched delimiters
+// still renders in linear time.
+type inline struct {
+	b *strings.Builder
+	s string
+	// links is false inside link text, where links cannot nest
+	links bool
+	// noCode and noCloser hold, per backtick count or delimiter run, the
+	// earliest position from which no closing run was found
+	noCode   map[int]int
+	noCloser map[string]int
+	// brackets pairs the index of each [ with its ], found on first use
+	brackets map[int]int
+	// targets speeds up reading link targets, built on first use
+	targets *targetIndex
+}
+
+func renderInline(s string) string {
+	var b strings.Builder
+	writeInline(&b, s, true)
+	return b.String()
+}
+
+func writeInline(b *strings.Builder, s string, links bool) {
+	p := &inline{b: b, s: s, links: links, noCode: map[int]int{}, noCloser: map[string]int{}}
+	p.render()
+}
+
+func (p *inline) render() {
+	s := p.s
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			p.b.WriteString("<br>\n")
+			i += 2
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			p.b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+		case c == '`':
+			i = p.code(i)
+		case c == '*' || c == '_' || c == '~':
+			i = p.emphasis(i)
+		case c == '[' && p.links:
+			i = p.link(i, i)
+		case c == '!' && p.links && i+1 < len(s) && s[i+1] == '[':
+			// Images are linked to rather than shown
+			i = p.link(i, i+1)
+		case c == '<' && p.links:
+			i = p.autolink(i)
+		case c == 'h' && p.links && (i == 0 || !isWordByte(s[i-1])) &&
+			(strings.HasPrefix(s[i:], "http://") || strings.HasPrefix(s[i:], "https://")):
+			i = p.bareLink(i)
+		case c == '@' && p.links && (i == 0 || !isWordByte(s[i-1])):
+			i = p.mention(i)
+		case c == '\n':
+			p.b.WriteString("<br>\n")
+			i++
+		default:
+			p.b.WriteString(html.EscapeString(s[i : i+1]))
+			i++
+		}
+	}
+}
+
+// code renders the code span opened by the backticks at i, or the backticks
+// as text when they are never closed
+func (p *inline) code(i int) int {
+	n := runLength(p.s, i)
+	end := p.codeEnd(i+n, n)
+	if end < 0 {
+		p.b.WriteString(p.s[i : i+n])
+		return i + n
+	}
+
+	code := strings.ReplaceAll(p.s[i+n:end], "\n", " ")
+	if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+		code = code[1 : len(code)-1]
+	}
+	p.b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+	return end + n
+}
+
+// codeEnd finds the next run of exactly n backticks from the given position,
+// or returns -1
+func (p *inline) codeEnd(from, n int) int {
+	if failed, ok := p.noCode[n]; ok && from >= failed {
+		return -1
+	}
+	for j := from; j < len(p.s); {
+		if p.s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(p.s, j)
+		if m == n {
+			return j
+		}
+		j += m
+	}
+	p.noCode[n] = from
+	return -1
+}
+
+// emphasis renders *em*, **strong**, ***both*** (or with _) and ~~del~~ from
+// the delimiter run at i. An opener must touch the text it wraps and a closer
+// the text before it; _ only works at word boundaries, so snake_case is safe.
+func (p *inline) emphasis(i int) int {
+	s := p.s
+	c := s[i]
+	n := runLength(s, i)
+	delim := s[i : i+n]
+
+	opens := i+n < len(s) && !isSpace(s[i+n]) && (c != '_' || i == 0 || !isWordByte(s[i-1]))
+	if c == '~' {
+		opens = opens && n == 2
+	} else {
+		opens = opens && n <= 3
+	}
+	if !opens {
+		p.b.WriteString(delim)
+		return i + n
+	}
+	end := p.closer(i+n, delim)
+	if end < 0 {
+		p.b.WriteString(delim)
+		return i + n
+	}
+
+	open, close := "<em>", "</em>"
+	switch {
+	case c == '~':
+		open, close = "<del>", "</del>"
+	case n == 2:
+		open, close = "<strong>", "</strong>"
+	case n == 3:
+		open, close = "<em><strong>", "</strong></em>"
+	}
+	p.b.WriteString(open)
+	writeInline(p.b, s[i+n:end], p.links)
+	p.b.WriteString(close)
+	return end + n
+}
+
+// closer finds where the delimiter run opened before from is closed, skipping
+// escapes and code spans, or returns -1
+func (p *inline) closer(from int, delim string) int {
+	if failed, ok := p.noCloser[delim]; ok && from >= failed {
+		return -1
+	}
+	s, c := p.s, delim[0]
+	for j := from; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+		case '`':
+			n := runLength(s, j)
+			if end := p.codeEnd(j+n, n); end >= 0 {
+				j = end + n
+			} else {
+				j += n
+			}
+		case c:
+			n := runLength(s, j)
+			if n == len(delim) && j > from && !isSpace(s[j-1]) && (c != '_' || j+n == len(s) || !isWordByte(s[j+n])) {
+				return j
+			}
+			j += n
+		default:
+			j++
+		}
+	}
+	p.noCloser[delim] = from
+	return -1
+}
+
+// link renders [text](url "title") with its [ at open, starting at i (the !
+// of an image). Links to unsafe URLs keep only their text; anything that is
+// not a complete link is written as text.
+func (p *inline) link(i, open int) int {
+	if p.brackets == nil {
+		p.brackets = p.matchBrackets()
+	}
+	end, ok := p.brackets[open]
+	if !ok {
+		p.b.WriteString(p.s[i : open+1])
+		return open + 1
+	}
+	dest, title, next, ok := p.linkTarget(end + 1)
+	if !ok {
+		p.b.WriteString(p.s[i : open+1])
+		return open + 1
+	}
+
+	href := safeURL(dest)
+	if href != "" {
+		p.b.WriteString(`<a href="` + html.EscapeString(href) + `"`)
+		if title != "" {
+			p.b.WriteString(` title="` + html.EscapeString(title) + `"`)
+		}
+		p.b.WriteString(` rel="nofollow ugc">`)
+	}
+	writeInline(p.b, p.s[open+1:end], false)
+	if href != "" {
+		p.b.WriteString("</a>")
+	}
+	return next
+}
+
+// matchBrackets pairs every [ with the ] closing it in one pass, ignoring
+// escaped brackets and those inside code spans
+func (p *inline) matchBrackets() map[int]int {
+	pairs := map[int]int{}
+	var open []int
+	s := p.s
+	for j := 0; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			n := runLength(s, j)
+			if end := p.codeEnd(j+n, n); end >= 0 {
+				j = end + n
+			} else {
+				j += n
+			}
+			continue
+		case '[':
+			open = append(open, j)
+		case ']':
+			if len(open) > 0 {
+				pairs[open[len(open)-1]] = j
+				open = open[:len(open)-1]
+			}
+		}
+		j++
+	}
+	return pairs
+}
+
+// linkTarget reads the (url "title") part of a link starting at i, returning
+// the position after it. Its searches are looked up in the target index, so
+// text full of broken links still renders in linear time.
+func (p *inline) linkTarget(i int) (dest, title string, next int, ok bool) {
+	s := p.s
+	if i >= len(s) || s[i] != '(' {
+		return "", "", 0, false
+	}
+	if p.targets == nil {
+		p.targets = newTargetIndex(s)
+	}
+	t := p.targets
+	open := i
+	i = t.nonSpace[i+1]
+
+	// The URL is either in <angle brackets> or runs to a space or to the )
+	// closing the link, with parentheses inside it balanced
+	if i < len(s) && s[i] == '<' {
+		close := t.angle[i+1]
+		if close == len(s) || s[close] != '>' {
+			return "", "", 0, false
+		}
+		dest, i = s[i+1:close], close+1
+	} else {
+		stop := t.space[i]
+		if end := t.closer[open]; end >= 0 && end < stop {
+			stop = end
+		}
+		dest, i = s[i:stop], stop
+	}
+
+	// A title must be set apart from the URL
+	afterDest := i
+	i = t.nonSpace[i]
+	if i < len(s) && (s[i] == '"' || s[i] == '\'') && i > afterDest {
+		close := t.doubleQuote[i+1]
+		if s[i] == '\'' {
+			close = t.singleQuote[i+1]
+		}
+		if close == len(s) {
+			return "", "", 0, false
+		}
+		title, i = s[i+1:close], t.nonSpace[close+1]
+	}
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	// Unescaping only once the link is whole keeps failed ones cheap
+	return unescape(dest), unescape(title), i + 1, true
+}
+
+// targetIndex holds, for every position of a paragraph, the next position
+// holding each character link targets look for, and for every ( the )
+// matching it (-1 if none)
+type targetIndex struct {
+	closer                   []int
+	space, nonSpace, angle   []int
+	doubleQuote, singleQuote []int
+}
+
+func newTargetIndex(s string) *targetIndex {
+	t := &targetIndex{
+		closer:      make([]int, len(s)),
+		space:       nextIndex(s, func(c byte) bool { return c == ' ' || c == '\n' }),
+		nonSpace:    nextIndex(s, func(c byte) bool { return c != ' ' && c != '\n' }),
+		angle:       nextIndex(s, func(c byte) bool { return c == '>' || c == '\n' }),
+		doubleQuote: nextIndex(s, func(c byte) bool { return c == '"' }),
+		singleQuote: nextIndex(s, func(c byte) bool { return c == '\'' }),
+	}
+
+	// Pair parentheses, skipping escaped ones
+	var open []int
+	for i := 0; i < len(s); i++ {
+		t.closer[i] = -1
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				t.closer[i] = -1
+			}
+		case '(':
+			open = append(open, i)
+		case ')':
+			if len(open) > 0 {
+				t.closer[open[len(open)-1]] = i
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	return t
+}
+
+// nextIndex lists, for every position of s and the one past its end, the
+// first position from there whose byte matches, or len(s)
+func nextIndex(s string, match func(byte) bool) []int {
+	next := make([]int, len(s)+1)
+	next[len(s)] = len(s)
+	for i := len(s) - 1; i >= 0; i-- {
+		if match(s[i]) {
+			next[i] = i
+		} else {
+			next[i] = next[i+1]
+		}
+	}
+	return next
+}
+
+// autolink renders <https://...> as a link, or the < as text
+func (p *inline) autolink(i int) int {
+	end := strings.IndexAny(p.s[i+1:], "<> \n")
+	if end > 0 && p.s[i+1+end] == '>' {
+		target := p.s[i+1 : i+1+end]
+		if strings.Contains(target, ":") && safeURL(target) != "" {
+			p.writeLink(target)
+			return i + end + 2
+		}
+	}
+	p.b.WriteString("&lt;")
+	return i + 1
+}
+
+// bareLink renders a URL written out in the text as a link; punctuation
+// after it, or a ) it does not open, is left out of it
+func (p *inline) bareLink(i int) int {
+	end := i
+	for end < len(p.s) && !strings.ContainsRune(" \n<", rune(p.s[end])) {
+		end++
+	}
+	for end > i {
+		last := p.s[end-1]
+		if strings.ContainsRune(".,:;!?'\"*_~", rune(last)) ||
+			(last == ')' && strings.Count(p.s[i:end], ")") > strings.Count(p.s[i:end], "(")) {
+			end--
+			continue
+		}
+		break
+	}
+
+	target := p.s[i:end]
+	if strings.HasSuffix(target, "//") || safeURL(target) == "" {
+		p.b.WriteString("h")
+		return i + 1
+	}
+	p.writeLink(target)
+	return end
+}
+
+func (p *inline) writeLink(target string) {
+	escaped := html.EscapeString(target)
+	p.b.WriteString(`<a href="` + escaped + `" rel="nofollow ugc">` + escaped + "</a>")
+}
+
+// unescape removes the backslashes escaping punctuation
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// runLength counts the copies of the byte at i that start there
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n'
+}
+
+func isPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// isWordByte reports whether c belongs to a word, counting every byte of a
+// non-ASCII character as a letter
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
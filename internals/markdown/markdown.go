@@ -0,0 +1,277 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxNesting caps how deep quotes and lists can nest; deeper ones are
+// rendered as plain paragraphs
+const maxNesting = 16
+
+var (
+	headingRe = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	setextRe  = regexp.MustCompile(`^ {0,3}(=+|-+)[ ]*$`)
+	breakRe   = regexp.MustCompile(`^ {0,3}(?:(?:\*[ ]*){3,}|(?:-[ ]*){3,}|(?:_[ ]*){3,})$`)
+	fenceRe   = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})(.*)$")
+	quoteRe   = regexp.MustCompile(`^ {0,3}> ?`)
+	itemRe    = regexp.MustCompile(`^( {0,3})([-*+]|[0-9]{1,9}[.)])( +|$)`)
+)
+
+// Render converts Markdown to HTML. It covers the CommonMark blocks and
+// inlines used in posts (headings, paragraphs, lists, quotes, code, links and
+// emphasis) plus ~~strikethrough~~ and bare links; images are shown as links.
+// Raw HTML is escaped, line breaks inside a paragraph are kept, and the result
+// goes through Sanitize. An @mention links to the user's page only when isUser
+// accepts the name, and stays plain text otherwise; a nil isUser links every
+// name.
+func Render(src string, isUser func(name string) bool) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), false, 0)
+	return sanitize(b.String(), isUser)
+}
+
+// renderBlocks renders lines as a sequence of blocks. Paragraphs are left
+// unwrapped in tight lists, whose items are not separated by blank lines.
+func renderBlocks(b *strings.Builder, lines []string, tight bool, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case isFence(line):
+			i = renderFence(b, lines, i)
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", len(m[1]), renderInline(m[2]), len(m[1]))
+			i++
+		case breakRe.MatchString(line):
+			b.WriteString("<hr>\n")
+			i++
+		case quoteRe.MatchString(line) && depth < maxNesting:
+			i = renderQuote(b, lines, i, depth)
+		case itemRe.MatchString(line) && depth < maxNesting:
+			i = renderList(b, lines, i, depth)
+		default:
+			i = renderParagraph(b, lines, i, tight)
+		}
+	}
+}
+
+// renderParagraph renders the paragraph starting at lines[i], which runs up
+// to a blank line or the start of another block, and returns the line after
+// it. A paragraph underlined with = or - is a heading instead.
+func renderParagraph(b *strings.Builder, lines []string, i int, tight bool) int {
+	var text []string
+	for ; i < len(lines) && !isBlank(lines[i]); i++ {
+		if len(text) > 0 {
+			if m := setextRe.FindStringSubmatch(lines[i]); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, renderInline(strings.Join(text, "\n")), level)
+				return i + 1
+			}
+			if interrupts(lines[i]) {
+				break
+			}
+		}
+		text = append(text, strings.TrimSpace(lines[i]))
+	}
+
+	content := renderInline(strings.Join(text, "\n"))
+	if tight {
+		b.WriteString(content + "\n")
+	} else {
+		b.WriteString("<p>" + content + "</p>\n")
+	}
+	return i
+}
+
+// renderFence renders the fenced code block opened at lines[i], which runs
+// to a closing fence at least as long or the end of the text
+func renderFence(b *strings.Builder, lines []string, i int) int {
+	m := fenceRe.FindStringSubmatch(lines[i])
+	indent, fence := len(m[1]), m[2]
+	lang := ""
+	if fields := strings.Fields(m[3]); len(fields) > 0 {
+		lang = fields[0]
+	}
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		if isClosingFence(lines[i], fence) {
+			i++
+			break
+		}
+		// Lines lose as much indentation as the opening fence had
+		line := lines[i]
+		for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+			line = line[1:]
+		}
+		code = append(code, line)
+	}
+
+	b.WriteString("<pre><code")
+	if lang != "" {
+		b.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+	}
+	b.WriteString(">")
+	for _, line := range code {
+		b.WriteString(html.EscapeString(line) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderQuote renders the block quote starting at lines[i]. Unmarked lines
+// that go on with a quoted paragraph stay in the quote.
+func renderQuote(b *strings.Builder, lines []string, i, depth int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if marker := quoteRe.FindString(line); marker != "" {
+			inner = append(inner, line[len(marker):])
+		} else if !isBlank(line) && !isBlank(inner[len(inner)-1]) && !interrupts(line) {
+			inner = append(inner, line)
+		} else {
+			break
+		}
+	}
+
+	b.WriteString("<blockquote>\n")
+	renderBlocks(b, inner, false, depth+1)
+	b.WriteString("</blockquote>\n")
+	return i
+}
+
+// renderList renders the list starting at lines[i], made of the following
+// items with the same kind of marker. Lines indented past an item's marker
+// belong to it, and a blank line between or inside items makes the list
+// loose, with its paragraphs wrapped in <p>.
+func renderList(b *strings.Builder, lines []string, i, depth int) int {
+	marker := itemRe.FindStringSubmatch(lines[i])[2]
+	loose := false
+
+	var items [][]string
+	for i < len(lines) {
+		m := itemRe.FindStringSubmatch(lines[i])
+		if m == nil || !sameList(m[2], marker) {
+			break
+		}
+		width := len(m[0])
+		if m[3] == "" {
+			width++
+		}
+		item := []string{lines[i][len(m[0]):]}
+
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			switch {
+			case isBlank(line):
+				item = append(item, "")
+				continue
+			case indentOf(line) >= width:
+				item = append(item, line[width:])
+				continue
+			case !itemRe.MatchString(line) && !isBlank(item[len(item)-1]) && !interrupts(line):
+				// Lazy continuation of the item's paragraph
+				item = append(item, line)
+				continue
+			}
+			break
+		}
+
+		// Blank lines at the end of an item only count when another item follows
+		n := len(item)
+		for n > 0 && isBlank(item[n-1]) {
+			n--
+		}
+		if n < len(item) && i < len(lines) {
+			if next := itemRe.FindStringSubmatch(lines[i]); next != nil && sameList(next[2], marker) {
+				loose = true
+			}
+		}
+		for _, line := range item[:n] {
+			if isBlank(line) {
+				loose = true
+			}
+		}
+		items = append(items, item[:n])
+	}
+
+	tag := "ul"
+	if !isBullet(marker) {
+		tag = "ol"
+	}
+	b.WriteString("<" + tag)
+	if start, _ := strconv.Atoi(marker[:len(marker)-1]); tag == "ol" && start != 1 {
+		b.WriteString(` start="` + strconv.Itoa(start) + `"`)
+	}
+	b.WriteString(">\n")
+	for _, item := range items {
+		b.WriteString("<li>")
+		renderBlocks(b, item, !loose, depth+1)
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// interrupts reports whether a line starts a block that ends a paragraph
+// running into it. Only non-empty list items can, ordered ones from 1 only,
+// so numbers that merely start a line of text are left alone.
+func interrupts(line string) bool {
+	if isFence(line) || headingRe.MatchString(line) || breakRe.MatchString(line) || quoteRe.MatchString(line) {
+		return true
+	}
+	m := itemRe.FindStringSubmatch(line)
+	if m == nil || isBlank(line[len(m[0]):]) {
+		return false
+	}
+	return isBullet(m[2]) || m[2][:len(m[2])-1] == "1"
+}
+
+// isFence reports whether a line opens a fenced code block; the info string
+// of a backtick fence cannot hold backticks, so ```code``` stays inline
+func isFence(line string) bool {
+	m := fenceRe.FindStringSubmatch(line)
+	return m != nil && !(m[2][0] == '`' && strings.Contains(m[3], "`"))
+}
+
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	trimmed = strings.TrimRight(trimmed, " ")
+	return len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// sameList reports whether two list markers belong to the same list: the
+// same bullet, or numbers followed by the same delimiter
+func sameList(a, b string) bool {
+	if isBullet(a) || isBullet(b) {
+		return a == b
+	}
+	return a[len(a)-1] == b[len(b)-1]
+}
+
+func isBullet(marker string) bool {
+	return marker == "-" || marker == "*" || marker == "+"
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,131 @@
+package markdown
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type renderTest struct {
+	name, src, want string
+}
+
+func runRenderTests(t *testing.T, tests []renderTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src, nil); got != tt.want {
+				t.Errorf("Render(%q)\n got  %q\n want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderBlocks(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"heading", "# Title", "<h1>Title</h1>\n"},
+		{"heading level 6", "###### Six", "<h6>Six</h6>\n"},
+		{"too many hashes", "####### Seven", "<p>####### Seven</p>\n"},
+		{"setext heading", "Title\n=====", "<h1>Title</h1>\n"},
+		{"setext subheading", "Sub\n---", "<h2>Sub</h2>\n"},
+		{"line break", "a\nb", "<p>a<br>\nb</p>\n"},
+		{"windows line break", "a\r\nb", "<p>a<br>\nb</p>\n"},
+		{"paragraphs", "a\n\nb", "<p>a</p>\n<p>b</p>\n"},
+		{"thematic break", "***", "<hr>\n"},
+		{"dash break", "- - -", "<hr>\n"},
+		{"fence", "```go\nx := 1 < 2\n```", "<pre><code class=\"language-go\">x := 1 &lt; 2\n</code></pre>\n"},
+		{"unclosed fence", "```\nunclosed", "<pre><code>unclosed\n</code></pre>\n"},
+		{"fence keeps markdown", "~~~\n*a* [b](c)\n~~~", "<pre><code>*a* [b](c)\n</code></pre>\n"},
+		{"quote", "> quote\n> more", "<blockquote>\n<p>quote<br>\nmore</p>\n</blockquote>\n"},
+		{"nested quote", "> a\n>> b", "<blockquote>\n<p>a</p>\n<blockquote>\n<p>b</p>\n</blockquote>\n</blockquote>\n"},
+		{"bullet list", "- a\n- b", "<ul>\n<li>a\n</li>\n<li>b\n</li>\n</ul>\n"},
+		{"ordered list", "1. a\n2. b", "<ol>\n<li>a\n</li>\n<li>b\n</li>\n</ol>\n"},
+		{"ordered list start", "3. c\n4. d", "<ol start=\"3\">\n<li>c\n</li>\n<li>d\n</li>\n</ol>\n"},
+		{"loose list", "- a\n\n- b", "<ul>\n<li><p>a</p>\n</li>\n<li><p>b</p>\n</li>\n</ul>\n"},
+		{"nested list", "- a\n  - b", "<ul>\n<li>a\n<ul>\n<li>b\n</li>\n</ul>\n</li>\n</ul>\n"},
+		{"raw html escaped", "<div>x</div>", "<p>&lt;div&gt;x&lt;/div&gt;</p>\n"},
+	})
+}
+
+func TestRenderNestingCap(t *testing.T) {
+	got := Render(strings.Repeat(">", 1000)+" deep", nil)
+	if n := strings.Count(got, "<blockquote>"); n != maxNesting {
+		t.Errorf("got %d nested quotes, want %d", n, maxNesting)
+	}
+}
+
+func TestRenderInlines(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"emphasis", "*em* **strong** ~~del~~", "<p><em>em</em> <strong>strong</strong> <del>del</del></p>\n"},
+		{"underscores", "_a_ __b__", "<p><em>a</em> <strong>b</strong></p>\n"},
+		{"intraword underscores", "snake_case_word", "<p>snake_case_word</p>\n"},
+		{"unclosed emphasis", "*a", "<p>*a</p>\n"},
+		{"code span", "`code <b>`", "<p><code>code &lt;b&gt;</code></p>\n"},
+		{"code span with backtick", "``a ` b``", "<p><code>a ` b</code></p>\n"},
+		{"code span keeps markdown", "`*a*`", "<p><code>*a*</code></p>\n"},
+		{"escapes", `\*not em\*`, "<p>*not em*</p>\n"},
+		{"inline html escaped", "a <b>b</b>", "<p>a &lt;b&gt;b&lt;/b&gt;</p>\n"},
+		{"mention", "@bob hi", "<p><a href=\"/users/bob\" class=\"mention\" rel=\"nofollow ugc\">@bob</a> hi</p>\n"},
+		{"email is not a mention", "a@b.c", "<p>a@b.c</p>\n"},
+	})
+}
+
+func TestRenderKnownMentions(t *testing.T) {
+	isUser := func(name string) bool { return name == "bob" }
+	got := Render("@bob and @nobody, `@bob`", isUser)
+	want := "<p><a href=\"/users/bob\" class=\"mention\" rel=\"nofollow ugc\">@bob</a> and @nobody, <code>@bob</code></p>\n"
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	got := Mentions("@bob, @alice. @bob `@carol` [@dave](x) mail@erin.org @frank-")
+	want := []string{"bob", "alice", "frank"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderLinks(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"link with title", `[text](http://example.com "Title")`, "<p><a href=\"http://example.com\" title=\"Title\" rel=\"nofollow ugc\">text</a></p>\n"},
+		{"single quoted title", `[a](b 'T (x)')`, "<p><a href=\"b\" title=\"T (x)\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"angle brackets", "[a](<b c>)", "<p><a href=\"b c\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"angle brackets with paren", "[a](<b)>)", "<p><a href=\"b)\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"balanced parens", "[a](b(c))", "<p><a href=\"b(c)\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"escaped paren", `[a](b\))`, "<p><a href=\"b)\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"spaces around url", "[a]( b )", "<p><a href=\"b\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"image as link", "![alt](img.png)", "<p><a href=\"img.png\" rel=\"nofollow ugc\">alt</a></p>\n"},
+		{"unclosed target", "[a](b", "<p>[a](b</p>\n"},
+		{"title not set apart", `[a](b"t")`, "<p><a href=\"b&#34;t&#34;\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"emphasis in text", "[*a*](b)", "<p><a href=\"b\" rel=\"nofollow ugc\"><em>a</em></a></p>\n"},
+		{"autolink", "<https://example.com>", "<p><a href=\"https://example.com\" rel=\"nofollow ugc\">https://example.com</a></p>\n"},
+		{"bare link", "see https://example.com/x.", "<p>see <a href=\"https://example.com/x\" rel=\"nofollow ugc\">https://example.com/x</a>.</p>\n"},
+		{"mailto", "[a](mailto:a@b.c)", "<p><a href=\"mailto:a@b.c\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"relative", "[a](/relative)", "<p><a href=\"/relative\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"fragment", "[a](#frag)", "<p><a href=\"#frag\" rel=\"nofollow ugc\">a</a></p>\n"},
+		{"javascript", "[a](javascript:alert(1))", "<p>a</p>\n"},
+		{"javascript mixed case", "[a](JaVaScRiPt:alert(1))", "<p>a</p>\n"},
+		{"javascript in angle brackets", "[a](<javascript:alert(1)>)", "<p>a</p>\n"},
+		{"javascript autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+		{"data", "[a](data:text/html,x)", "<p>a</p>\n"},
+		{"vbscript", "[a](vbscript:x)", "<p>a</p>\n"},
+		{"entity stays literal", "[x](&#106;avascript:alert(1))", "<p><a href=\"&amp;#106;avascript:alert(1)\" rel=\"nofollow ugc\">x</a></p>\n"},
+		{"quote in url", `[a]("onmouseover=alert(1))`, "<p><a href=\"&#34;onmouseover=alert(1)\" rel=\"nofollow ugc\">a</a></p>\n"},
+	})
+}
+
+// TestRenderLinear renders inputs that make a naive link or emphasis parser
+// rescan the rest of the text at every character
+func TestRenderLinear(t *testing.T) {
+	for _, pattern := range []string{"[](", "![](", "[](<", `[](a "`, "[](a '", "[](()", "[a](b", "*a", "`", "<a", "~~a"} {
+		src := strings.Repeat(pattern, 200_000/len(pattern))
+		start := time.Now()
+		Render(src, nil)
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("rendering %d bytes of %q took %v", len(src), pattern, elapsed)
+		}
+	}
+}
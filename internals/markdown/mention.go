@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// MentionPrefix is the path of the user pages @mentions link to
+const MentionPrefix = "/users/"
+
+// Mentions returns the usernames @mentioned in Markdown, each once, in the
+// order they first appear. Mentions inside code are not counted.
+func Mentions(src string) []string {
+	var names []string
+	z := html.NewTokenizer(strings.NewReader(Render(src, nil)))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return names
+		}
+		if tt != html.StartTagToken {
+			continue
+		}
+		if name, ok := mentionName(z.Token()); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+}
+
+// mentionName returns the username an <a class="mention"> tag links to
+func mentionName(tok html.Token) (string, bool) {
+	if tok.Data != "a" || !slices.Contains(tok.Attr, html.Attribute{Key: "class", Val: "mention"}) {
+		return "", false
+	}
+	for _, attr := range tok.Attr {
+		if attr.Key != "href" || !strings.HasPrefix(attr.Val, MentionPrefix) {
+			continue
+		}
+		name, err := url.PathUnescape(strings.TrimPrefix(attr.Val, MentionPrefix))
+		return name, err == nil
+	}
+	return "", false
+}
+
+// mention renders @username at i as a link to the user's page. Usernames
+// are letters, digits, _, . and -, not ending in . or - so that a mention
+// can end a sentence.
+func (p *inline) mention(i int) int {
+	end := i + 1
+	for end < len(p.s) && isNameByte(p.s[end]) {
+		end++
+	}
+	for end > i+1 && (p.s[end-1] == '.' || p.s[end-1] == '-') {
+		end--
+	}
+	if end == i+1 {
+		p.b.WriteString("@")
+		return i + 1
+	}
+
+	name := p.s[i+1 : end]
+	p.b.WriteString(`<a href="` + MentionPrefix + url.PathEscape(name) + `" class="mention">@` + name + "</a>")
+	return end
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
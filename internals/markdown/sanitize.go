@@ -0,0 +1,152 @@
+package markdown
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags lists the only tags Sanitize keeps, with the attributes each
+// may carry
+var allowedTags = map[string][]string{
+	"p": nil, "br": nil, "hr": nil,
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"strong": nil, "em": nil, "del": nil,
+	"code": {"class"}, "pre": nil, "blockquote": nil,
+	"ul": nil, "ol": {"start"}, "li": nil,
+	"a": {"href", "title", "class"},
+}
+
+// droppedTags are left out together with everything inside them
+var droppedTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"template": true, "textarea": true, "title": true, "noscript": true, "svg": true, "math": true,
+}
+
+var (
+	languageRe = regexp.MustCompile(`^language-[A-Za-z0-9_+-]+$`)
+	startRe    = regexp.MustCompile(`^[0-9]{1,9}$`)
+)
+
+// Sanitize keeps only the allowed tags and attributes of an HTML fragment,
+// escaping all text, closing every tag it opens and marking each link
+// rel="nofollow ugc"
+func Sanitize(fragment string) string {
+	return sanitize(fragment, nil)
+}
+
+// sanitize is Sanitize, also turning the @mentions of names isUser rejects
+// back into plain text when isUser is set
+func sanitize(fragment string, isUser func(name string) bool) string {
+	var b strings.Builder
+	var open []string
+	dropped := 0
+	// unlinked is set inside a mention left as text, until its </a>
+	unlinked := false
+
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tok := z.Token()
+
+		switch tt {
+		case html.TextToken:
+			if dropped == 0 {
+				b.WriteString(html.EscapeString(tok.Data))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if droppedTags[tok.Data] {
+				if tt == html.StartTagToken {
+					dropped++
+				}
+				continue
+			}
+			attrs, ok := allowedTags[tok.Data]
+			if dropped > 0 || !ok {
+				continue
+			}
+			if name, ok := mentionName(tok); ok && isUser != nil && !isUser(name) {
+				unlinked = tt == html.StartTagToken
+				continue
+			}
+			writeStartTag(&b, tok, attrs)
+			if tok.Data != "br" && tok.Data != "hr" {
+				open = append(open, tok.Data)
+			}
+		case html.EndTagToken:
+			if droppedTags[tok.Data] {
+				dropped = max(dropped-1, 0)
+				continue
+			}
+			if unlinked && tok.Data == "a" {
+				unlinked = false
+				continue
+			}
+			// Tags left open inside this one are closed with it
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == tok.Data {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// writeStartTag writes a tag with those of its attributes that are allowed
+// and have a safe value
+func writeStartTag(b *strings.Builder, tok html.Token, allowed []string) {
+	b.WriteString("<" + tok.Data)
+	for _, name := range allowed {
+		for _, attr := range tok.Attr {
+			if attr.Key != name || attr.Namespace != "" {
+				continue
+			}
+			value := attr.Val
+			switch {
+			case name == "href":
+				value = safeURL(value)
+			case name == "class" && tok.Data == "code" && !languageRe.MatchString(value),
+				name == "class" && tok.Data == "a" && value != "mention",
+				name == "start" && !startRe.MatchString(value):
+				value = ""
+			}
+			if value != "" {
+				b.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+			}
+			break
+		}
+	}
+	if tok.Data == "a" {
+		b.WriteString(` rel="nofollow ugc"`)
+	}
+	b.WriteString(">")
+}
+
+// safeURL returns a link target that is relative or uses http, https or
+// mailto, and "" for anything else, such as javascript: URLs
+func safeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return ""
+	}
+	switch u.Scheme {
+	case "", "http", "https", "mailto":
+		return raw
+	}
+	return ""
+}
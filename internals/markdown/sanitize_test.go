@@ -0,0 +1,67 @@
+package markdown
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"script", `<script>alert(1)</script>ok`, "ok"},
+		{"img onerror", `<img src=x onerror=alert(1)>`, ""},
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `<a rel="nofollow ugc">x</a>`},
+		{"padded uppercase javascript href", `<a href=" JAVASCRIPT:alert(1)">x</a>`, `<a rel="nofollow ugc">x</a>`},
+		{"entity encoded javascript href", `<a href="&#106;avascript:x">e</a>`, `<a rel="nofollow ugc">e</a>`},
+		{"data href", `<a href="data:text/html;base64,xx">d</a>`, `<a rel="nofollow ugc">d</a>`},
+		{"event handler", `<a href="/ok" onclick="x()">x</a>`, `<a href="/ok" rel="nofollow ugc">x</a>`},
+		{"style attribute", `<p style="x">y</p>`, "<p>y</p>"},
+		{"script inside svg", `<svg><script>x</script></svg>z`, "z"},
+		{"math", `<math><mi>x</mi></math>`, ""},
+		{"iframe", `<iframe src="x"></iframe>`, ""},
+		{"style element", `<style>body{}</style>t`, "t"},
+		{"noscript breakout", `<noscript><p title="</noscript><img src=x onerror=alert(1)>"></noscript>`, "&#34;&gt;"},
+		{"attribute breakout", `<a title="&quot;><script>">t</a>`, `<a title="&#34;&gt;&lt;script&gt;" rel="nofollow ugc">t</a>`},
+		{"other link class", `<a href="x" class="evil">y</a>`, `<a href="x" rel="nofollow ugc">y</a>`},
+		{"mention class", `<a href="/u" class="mention">@u</a>`, `<a href="/u" class="mention" rel="nofollow ugc">@u</a>`},
+		{"code language", `<code class="language-go">x</code>`, `<code class="language-go">x</code>`},
+		{"bad code class", `<code class="x onload">x</code>`, "<code>x</code>"},
+		{"list start", `<ol start="3"><li>a</li></ol>`, `<ol start="3"><li>a</li></ol>`},
+		{"bad list start", `<ol start="3 onclick">`, "<ol></ol>"},
+		{"unclosed tags", `<p><strong>unclosed`, "<p><strong>unclosed</strong></p>"},
+		{"stray end tag", `</p>stray`, "stray"},
+		{"unknown tag", `<div>text</div>`, "text"},
+		{"comment", `<!-- c --><p>x</p>`, "<p>x</p>"},
+		{"text escaped", `<b title="x">"q"&amp;</b>`, "&#34;q&#34;&amp;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sanitize(tt.in); got != tt.want {
+				t.Errorf("Sanitize(%q)\n got  %q\n want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"http://a", "http://a"},
+		{"https://a/b?c#d", "https://a/b?c#d"},
+		{"mailto:a@b.c", "mailto:a@b.c"},
+		{"/a b", "/a b"},
+		{"//example.com", "//example.com"},
+		{"javascript:x", ""},
+		{" javascript:x", ""},
+		{"JAVASCRIPT:x", ""},
+		{"vbscript:x", ""},
+		{"data:text/html,x", ""},
+		{"ftp://x", ""},
+		{"%zz", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := safeURL(tt.in); got != tt.want {
+			t.Errorf("safeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"forum/internals/roles"
+	"forum/internals/store"
+	"net/http"
+	"strings"
+)
+
+// SessionCookie is the name of the login session cookie
+const SessionCookie = "session"
+
+// CurrentUser is the logged in user resolved from the session cookie
+type CurrentUser struct {
+	ID       int
+	Username string
+	Email    string
+	Role     string
+	// Session is the cookie value the user was resolved from
+	Session string
+	// CSRFToken must accompany every state-changing request of this session
+	CSRFToken string
+}
+
+type currentUserKey struct{}
+
+// OptionalAuth resolves the session cookie, when there is one, and stores
+// the CurrentUser in the request context; visitors pass through untouched
+func OptionalAuth(sessions store.SessionStore) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cookie, err := r.Cookie(SessionCookie)
+			if err != nil || cookie.Value == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// One query returns the session's user if it is still valid
+			user, err := sessions.User(r.Context(), cookie.Value)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			SetUserID(r.Context(), user.UserID)
+			ctx := WithUser(r.Context(), &CurrentUser{
+				ID:        user.UserID,
+				Username:  user.Username,
+				Email:     user.Email,
+				Role:      user.Role,
+				Session:   cookie.Value,
+				CSRFToken: user.CSRFToken,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireAuth rejects requests without a CurrentUser: API routes get a JSON
+// 401, pages are redirected to the login form. It must run after OptionalAuth.
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if UserFrom(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			deny(w, r, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+}
+
+// RequirePermission rejects users whose role lacks the permission with a 403.
+// It must run after RequireAuth.
+func RequirePermission(p roles.Permission) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := UserFrom(r.Context()); user == nil || !roles.Can(user.Role, p) {
+				deny(w, r, http.StatusForbidden, "Forbidden")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// deny refuses a request with a JSON error for API routes and plain text for pages
+func deny(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   true,
+		"message": message,
+		"status":  status,
+	})
+}
+
+// WithUser returns a copy of ctx carrying user
+func WithUser(ctx context.Context, user *CurrentUser) context.Context {
+	return context.WithValue(ctx, currentUserKey{}, user)
+}
+
+// UserFrom returns the logged in user, or nil for visitors
+func UserFrom(ctx context.Context) *CurrentUser {
+	user, _ := ctx.Value(currentUserKey{}).(*CurrentUser)
+	return user
+}
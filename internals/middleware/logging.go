@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type logEntryKey struct{}
+
+// logEntry collects details that are only known further down the chain
+type logEntry struct {
+	userID int
+}
+
+// Logger writes one structured access log line per request
+func Logger(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &logEntry{}
+			lrw := &loggingResponseWriter{ResponseWriter: w}
+
+			next.ServeHTTP(lrw, r.WithContext(context.WithValue(r.Context(), logEntryKey{}, entry)))
+
+			status := lrw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "request",
+				slog.String("request_id", RequestIDFrom(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Duration("duration", time.Since(start)),
+				slog.Int("user_id", entry.userID),
+				slog.Int64("bytes", lrw.bytes),
+			)
+		})
+	}
+}
+
+// SetUserID records the authenticated user on the request's access log line
+func SetUserID(ctx context.Context, userID int) {
+	if entry, ok := ctx.Value(logEntryKey{}).(*logEntry); ok {
+		entry.userID = userID
+	}
+}
+
+// loggingResponseWriter wraps http.ResponseWriter to capture status code and size
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (lrw *loggingResponseWriter) WriteHeader(code int) {
+	if lrw.status == 0 {
+		lrw.status = code
+	}
+	lrw.ResponseWriter.WriteHeader(code)
+}
+
+func (lrw *loggingResponseWriter) Write(b []byte) (int, error) {
+	if lrw.status == 0 {
+		lrw.status = http.StatusOK
+	}
+	n, err := lrw.ResponseWriter.Write(b)
+	lrw.bytes += int64(n)
+	return n, err
+}
+
+// Flush lets streaming handlers push data through the wrapper
+func (lrw *loggingResponseWriter) Flush() {
+	if f, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
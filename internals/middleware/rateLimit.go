@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"fmt"
+	"forum/internals/store"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateKey names the client a request counts against; an empty key means
+// the limit does not apply to this request
+type RateKey func(r *http.Request) string
+
+// ByIP counts requests per client IP address
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ByUser counts requests per logged in user
+func ByUser(r *http.Request) string {
+	if user := UserFrom(r.Context()); user != nil {
+		return "user:" + strconv.Itoa(user.ID)
+	}
+	return ""
+}
+
+// ByFormField counts requests per value of a form field, such as the
+// account a login or password reset is for
+func ByFormField(field string) RateKey {
+	return func(r *http.Request) string {
+		value := strings.ToLower(strings.TrimSpace(r.FormValue(field)))
+		if value == "" {
+			return ""
+		}
+		return field + ":" + value
+	}
+}
+
+// RateLimit lets each client named by keys make up to requests requests at
+// once, refilled evenly over window. Requests over the limit of any key get
+// a 429 with Retry-After and count against none of them. A limit of zero
+// requests lets everything through.
+func RateLimit(limits store.RateLimitStore, name string, requests int, window time.Duration, keys ...RateKey) Middleware {
+	return func(next http.Handler) http.Handler {
+		if requests <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var buckets []string
+			for _, key := range keys {
+				if k := key(r); k != "" {
+					buckets = append(buckets, name+":"+k)
+				}
+			}
+			if len(buckets) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, retryAfter, err := limits.Take(r.Context(), buckets, requests, window)
+			if err != nil {
+				// A broken limiter should not take the forum down with it
+				slog.ErrorContext(r.Context(), "rate limit",
+					slog.String("request_id", RequestIDFrom(r.Context())),
+					slog.String("limit", name),
+					slog.Any("error", err),
+				)
+				allowed = true
+			}
+			if !allowed {
+				seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				deny(w, r, http.StatusTooManyRequests, fmt.Sprintf("Too many requests, try again in %d seconds", seconds))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
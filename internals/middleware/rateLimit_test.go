@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"forum/internals/store"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRateLimitRefusedChargesNothing checks that a request one key refuses
+// uses up none of the other keys' budgets
+func TestRateLimitRefusedChargesNothing(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := RateLimit(store.NewMemoryRateLimits(), "login", 1, time.Hour, ByIP, ByFormField("email"))(ok)
+
+	requests := []struct {
+		ip, email string
+		want      int
+	}{
+		{"192.0.2.1", "a@example.com", http.StatusOK},
+		// A fresh IP, but the email has no requests left
+		{"192.0.2.2", "a@example.com", http.StatusTooManyRequests},
+		// So that IP still has its one request
+		{"192.0.2.2", "b@example.com", http.StatusOK},
+		{"192.0.2.2", "c@example.com", http.StatusTooManyRequests},
+	}
+	for i, req := range requests {
+		form := url.Values{"email": {req.email}}
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.RemoteAddr = req.ip + ":1234"
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != req.want {
+			t.Errorf("request %d from %s for %s: got status %d, want %d", i+1, req.ip, req.email, w.Code, req.want)
+		}
+	}
+}
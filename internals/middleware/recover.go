@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+)
+
+// Recover turns a panic into a logged error with its stack trace and lets
+// onPanic write the error response, unless the handler already started one
+func Recover(logger *slog.Logger, onPanic http.HandlerFunc) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
+				logger.ErrorContext(r.Context(), "panic",
+					slog.String("request_id", RequestIDFrom(r.Context())),
+					slog.String("method", r.Method),
+					slog.String("path", r.URL.Path),
+					slog.Any("error", err),
+					slog.String("stack", string(debug.Stack())),
+				)
+
+				if lrw, ok := w.(*loggingResponseWriter); ok && lrw.status != 0 {
+					return
+				}
+				onPanic(w, r)
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
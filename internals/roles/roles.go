@@ -0,0 +1,40 @@
+// Package roles defines the user roles and what each of them may do
+package roles
+
+import "slices"
+
+// The roles a user can have; every account starts as a member
+const (
+	Member    = "member"
+	Moderator = "moderator"
+	Admin     = "admin"
+)
+
+// Permission is something only some roles may do
+type Permission int
+
+const (
+	// ModerateContent allows editing and deleting anyone's posts and comments
+	ModerateContent Permission = iota
+	// ViewModerationLog allows reading who performed which moderation action
+	ViewModerationLog
+	// ManageUsers allows changing the role of other users
+	ManageUsers
+	// ManageCategories allows creating, renaming and deleting categories
+	ManageCategories
+)
+
+var permissions = map[string][]Permission{
+	Moderator: {ModerateContent, ViewModerationLog},
+	Admin:     {ModerateContent, ViewModerationLog, ManageUsers, ManageCategories},
+}
+
+// Valid reports whether role is one of the known roles
+func Valid(role string) bool {
+	return role == Member || role == Moderator || role == Admin
+}
+
+// Can reports whether a user with the role has the permission
+func Can(role string, p Permission) bool {
+	return slices.Contains(permissions[role], p)
+}
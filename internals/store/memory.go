@@ -0,0 +1,1884 @@
+package store
+
+import (
+	"context"
+	"forum/internals/database"
+	"forum/internals/roles"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// NewMemory returns a Store that keeps everything in memory, seeded with the
+// given category names. It is meant for tests that should not need a database file.
+func NewMemory(categories ...string) *Store {
+	m := &memory{
+		users:         map[int]*memUser{},
+		sessions:      map[string]memSession{},
+		posts:         map[int]*memPost{},
+		comments:      map[int]*database.Comment{},
+		postVotes:     map[voteKey]int{},
+		commentVotes:  map[voteKey]int{},
+		notifications: map[int]*database.Notification{},
+		images:        map[int]*database.Image{},
+		reports:       map[int]*database.Report{},
+		tags:          map[int]string{},
+		tagAliases:    map[string]int{},
+		drafts:        map[int]*memDraft{},
+		mentions:      map[mentionKey]bool{},
+	}
+	for _, name := range categories {
+		id := len(m.categories) + 1
+		m.categories = append(m.categories, database.Category{
+			CategoryID: id, Name: name, Slug: strings.ToLower(strings.ReplaceAll(name, " ", "-")), SortOrder: id,
+		})
+	}
+
+	return &Store{
+		Users:         &memUsers{m},
+		Sessions:      &memSessions{m},
+		Posts:         &memPosts{m},
+		Comments:      &memComments{m},
+		Votes:         &memVotes{m},
+		Categories:    &memCategories{m},
+		Notifications: &memNotifications{m},
+		Images:        &memImages{m},
+		RateLimits:    NewMemoryRateLimits(),
+		ModerationLog: &memModerationLog{m},
+		Reports:       &memReports{m},
+		Search:        &memSearch{m},
+		Tags:          &memTags{m},
+		Drafts:        &memDrafts{m},
+		Revisions:     &memRevisions{m},
+		Trash:         &memTrash{m},
+		Mentions:      &memMentions{m},
+	}
+}
+
+// memory is the shared state behind every in-memory store
+type memory struct {
+	mu     sync.Mutex
+	lastID int
+
+	users         map[int]*memUser
+	sessions      map[string]memSession
+	posts         map[int]*memPost
+	comments      map[int]*database.Comment
+	postVotes     map[voteKey]int
+	commentVotes  map[voteKey]int
+	categories    []database.Category
+	notifications map[int]*database.Notification
+	images        map[int]*database.Image
+	moderationLog []database.ModerationAction
+	reports       map[int]*database.Report
+	// tags maps tag IDs to names, and tagAliases the names of merged tags to the tag they went into
+	tags       map[int]string
+	tagAliases map[string]int
+	drafts     map[int]*memDraft
+	// postRevisions and commentRevisions hold every revision, oldest first
+	postRevisions    []database.Revision
+	commentRevisions []database.Revision
+	mentions         map[mentionKey]bool
+}
+
+type memUser struct {
+	database.User
+	Bio string
+}
+
+type memSession struct {
+	UserID    int
+	CSRFToken string
+	Expires   time.Time
+}
+
+type memPost struct {
+	database.Post
+	CategoryIDs []int
+	TagIDs      []int
+}
+
+type memDraft struct {
+	database.Draft
+	CategoryIDs []int
+}
+
+// voteKey identifies the vote of a user on a post or comment
+type voteKey struct {
+	ID     int
+	UserID int
+}
+
+type mentionKey struct {
+	TargetType string
+	TargetID   int
+	UserID     int
+}
+
+// nextID hands out increasing ids shared by every table, like AUTOINCREMENT
+func (m *memory) nextID() int {
+	m.lastID++
+	return m.lastID
+}
+
+// post returns a copy of a post with the joined columns filled in
+func (m *memory) post(p *memPost) database.Post {
+	post := p.Post
+	if u, ok := m.users[post.UserID]; ok {
+		post.Username = u.Username
+	}
+	post.ImageURL, post.ThumbnailURL = "", ""
+	if post.ImageID != nil {
+		if img, ok := m.images[*post.ImageID]; ok {
+			post.ImageURL, post.ThumbnailURL = img.ImageURL, img.ThumbnailURL
+		}
+	}
+	post.Nbrcomments = 0
+	for _, c := range m.comments {
+		if c.PostID == post.PostID && !c.Hidden && c.DeletedAt == nil {
+			post.Nbrcomments++
+		}
+	}
+	post.Nbrlike, post.Nbrdislike = countVotes(m.postVotes, post.PostID)
+	post.Categories = []string{}
+	for _, cat := range m.categories {
+		if slices.Contains(p.CategoryIDs, cat.CategoryID) {
+			post.Categories = append(post.Categories, cat.Name)
+		}
+	}
+	post.Tags = []string{}
+	for _, id := range p.TagIDs {
+		post.Tags = append(post.Tags, m.tags[id])
+	}
+	slices.Sort(post.Tags)
+	return post
+}
+
+// insertPost adds a post with its categories and tags and returns its ID
+func (m *memory) insertPost(post *database.Post, categoryIDs []int) int {
+	id := m.nextID()
+	p := &memPost{Post: *post, CategoryIDs: slices.Clone(categoryIDs), TagIDs: m.tagIDs(post.Tags)}
+	p.PostID = id
+	p.Title, p.Content = stripMarks(p.Title), stripMarks(p.Content)
+	p.CreationDate = time.Now().UTC()
+	m.posts[id] = p
+	return id
+}
+
+// tagIDs resolves tag names like setPostTags, creating the missing tags
+func (m *memory) tagIDs(names []string) []int {
+	var ids []int
+	for _, name := range names {
+		id, ok := m.tagAliases[name]
+		if !ok {
+			for tagID, tagName := range m.tags {
+				if tagName == name {
+					id, ok = tagID, true
+				}
+			}
+		}
+		if !ok {
+			id = m.nextID()
+			m.tags[id] = name
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// live reports whether a comment and its post are both out of the trash
+func (m *memory) live(c *database.Comment) bool {
+	p, ok := m.posts[c.PostID]
+	return c.DeletedAt == nil && ok && p.DeletedAt == nil
+}
+
+// comment returns a copy of a comment with the joined columns filled in
+func (m *memory) comment(c *database.Comment) database.Comment {
+	comment := *c
+	if u, ok := m.users[comment.UserID]; ok {
+		comment.Username = u.Username
+	}
+	if p, ok := m.posts[comment.PostID]; ok {
+		comment.PostTitle = p.Title
+	}
+	comment.NbrLike, comment.NbrDislike = countVotes(m.commentVotes, comment.CommentID)
+	return comment
+}
+
+func countVotes(votes map[voteKey]int, id int) (likes, dislikes int) {
+	for key, vote := range votes {
+		if key.ID != id {
+			continue
+		}
+		if vote == 1 {
+			likes++
+		} else if vote == -1 {
+			dislikes++
+		}
+	}
+	return likes, dislikes
+}
+
+type memUsers struct {
+	m *memory
+}
+
+func (s *memUsers) Create(ctx context.Context, username, email, passwordHash string) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, u := range s.m.users {
+		if u.Username == username || u.Email == email {
+			return 0, ErrConflict
+		}
+	}
+	id := s.m.nextID()
+	s.m.users[id] = &memUser{User: database.User{
+		UserID:           id,
+		Username:         username,
+		Email:            email,
+		PasswordHash:     passwordHash,
+		RegistrationDate: time.Now().UTC(),
+		Role:             roles.Member,
+	}}
+	return id, nil
+}
+
+// find returns a copy of the first user matching the predicate
+func (s *memUsers) find(match func(u *memUser) bool) (*database.User, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, u := range s.m.users {
+		if match(u) {
+			user := u.User
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memUsers) Get(ctx context.Context, userID int) (*database.User, error) {
+	return s.find(func(u *memUser) bool { return u.UserID == userID })
+}
+
+func (s *memUsers) GetByEmail(ctx context.Context, email string) (*database.User, error) {
+	return s.find(func(u *memUser) bool { return u.Email == email })
+}
+
+func (s *memUsers) GetByLogin(ctx context.Context, emailOrUsername string) (*database.User, error) {
+	return s.find(func(u *memUser) bool { return u.Email == emailOrUsername || u.Username == emailOrUsername })
+}
+
+func (s *memUsers) GetByResetToken(ctx context.Context, token string) (*database.User, error) {
+	return s.find(func(u *memUser) bool { return u.ResetToken != nil && *u.ResetToken == token })
+}
+
+func (s *memUsers) EmailExists(ctx context.Context, email string) (bool, error) {
+	_, err := s.GetByEmail(ctx, email)
+	return err == nil, nil
+}
+
+func (s *memUsers) UsernameTaken(ctx context.Context, username string, exceptUserID int) (bool, error) {
+	_, err := s.find(func(u *memUser) bool { return u.Username == username && u.UserID != exceptUserID })
+	return err == nil, nil
+}
+
+// update applies fn to a stored user
+func (s *memUsers) update(userID int, fn func(u *memUser)) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	u, ok := s.m.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	fn(u)
+	return nil
+}
+
+func (s *memUsers) UpdateUsername(ctx context.Context, userID int, username string) error {
+	return s.update(userID, func(u *memUser) { u.Username = username })
+}
+
+func (s *memUsers) UpdateBio(ctx context.Context, userID int, bio string) error {
+	return s.update(userID, func(u *memUser) { u.Bio = bio })
+}
+
+func (s *memUsers) SetResetToken(ctx context.Context, email, token string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, u := range s.m.users {
+		if u.Email == email {
+			u.ResetToken = &token
+		}
+	}
+	return nil
+}
+
+func (s *memUsers) ResetPassword(ctx context.Context, userID int, passwordHash string) error {
+	return s.update(userID, func(u *memUser) {
+		u.PasswordHash = passwordHash
+		u.ResetToken = nil
+	})
+}
+
+func (s *memUsers) List(ctx context.Context) ([]database.User, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var users []database.User
+	for _, u := range s.m.users {
+		users = append(users, u.User)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users, nil
+}
+
+func (s *memUsers) ListByUsernames(ctx context.Context, usernames []string) ([]database.User, error) {
+	users, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(users, func(u database.User) bool {
+		return !slices.Contains(usernames, u.Username)
+	}), nil
+}
+
+func (s *memUsers) SetRole(ctx context.Context, userID int, role string) error {
+	return s.update(userID, func(u *memUser) { u.Role = role })
+}
+
+func (s *memUsers) Profile(ctx context.Context, userID int) (*database.UserProfile, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	u, ok := s.m.users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	profile := &database.UserProfile{
+		UserID:   u.UserID,
+		Username: u.Username,
+		Email:    u.Email,
+		JoinDate: u.RegistrationDate.Format("2006-01-02"),
+		Bio:      u.Bio,
+	}
+
+	for _, p := range s.m.posts {
+		if p.UserID == userID && p.DeletedAt == nil {
+			profile.PostCount++
+		}
+	}
+	for _, c := range s.m.comments {
+		if c.UserID == userID && c.DeletedAt == nil {
+			profile.CommentCount++
+		}
+	}
+	for key, vote := range s.m.postVotes {
+		author := 0
+		if p, ok := s.m.posts[key.ID]; ok && p.DeletedAt == nil {
+			author = p.UserID
+		}
+		switch {
+		case key.UserID == userID && vote == 1:
+			profile.LikesGiven++
+		case key.UserID == userID && vote == -1:
+			profile.DislikesGiven++
+		}
+		switch {
+		case author == userID && vote == 1:
+			profile.LikesReceived++
+		case author == userID && vote == -1:
+			profile.DislikesReceived++
+		}
+	}
+
+	var latest *database.Image
+	for _, img := range s.m.images {
+		if img.UserID == userID && img.ImageType == "profile" && (latest == nil || img.ImageID > latest.ImageID) {
+			latest = img
+		}
+	}
+	if latest != nil {
+		profile.ProfileImage = latest.ThumbnailURL
+	}
+	return profile, nil
+}
+
+type memSessions struct {
+	m *memory
+}
+
+func (s *memSessions) Create(ctx context.Context, userID int, cookieValue, csrfToken string, expires time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.sessions[cookieValue]; ok {
+		return ErrConflict
+	}
+	s.m.sessions[cookieValue] = memSession{UserID: userID, CSRFToken: csrfToken, Expires: expires}
+	return nil
+}
+
+func (s *memSessions) Delete(ctx context.Context, cookieValue string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	delete(s.m.sessions, cookieValue)
+	return nil
+}
+
+func (s *memSessions) DeleteForUser(ctx context.Context, userID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for token, session := range s.m.sessions {
+		if session.UserID == userID {
+			delete(s.m.sessions, token)
+		}
+	}
+	return nil
+}
+
+func (s *memSessions) User(ctx context.Context, cookieValue string) (*database.SessionUser, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	session, ok := s.m.sessions[cookieValue]
+	if !ok || !session.Expires.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	u, ok := s.m.users[session.UserID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &database.SessionUser{User: u.User, CSRFToken: session.CSRFToken}, nil
+}
+
+type memPosts struct {
+	m *memory
+}
+
+func (s *memPosts) Create(ctx context.Context, post *database.Post, categoryIDs []int) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	return s.m.insertPost(post, categoryIDs), nil
+}
+
+func (s *memPosts) Get(ctx context.Context, postID int) (*database.Post, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.posts[postID]
+	if !ok || p.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	post := s.m.post(p)
+	return &post, nil
+}
+
+func (s *memPosts) List(ctx context.Context, filter PostFilter, page Page) ([]database.Post, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	tag := filter.Tag
+	if id, ok := s.m.tagAliases[tag]; ok {
+		tag = s.m.tags[id]
+	}
+
+	var posts []database.Post
+	for _, p := range s.m.posts {
+		if p.DeletedAt != nil {
+			continue
+		}
+		post := s.m.post(p)
+		if filter.Category != "" && !s.m.inCategoryTree(p, filter.Category) {
+			continue
+		}
+		if tag != "" && !slices.Contains(post.Tags, tag) {
+			continue
+		}
+		if filter.AuthorID != 0 && post.UserID != filter.AuthorID {
+			continue
+		}
+		if filter.Author != "" && post.Username != filter.Author {
+			continue
+		}
+		if filter.VotedBy != 0 && s.m.postVotes[voteKey{post.PostID, filter.VotedBy}] != filter.Vote {
+			continue
+		}
+		if post.Hidden && !filter.IncludeHidden {
+			continue
+		}
+		if !filter.Since.IsZero() && post.CreationDate.Before(filter.Since) {
+			continue
+		}
+		posts = append(posts, post)
+	}
+
+	// Matches the ORDER BY of the SQLite listing: score, then newest first
+	sort.Slice(posts, func(i, j int) bool {
+		return filter.Cursor(posts[i]).follows(filter.Cursor(posts[j]), true)
+	})
+	return pageOf(posts, page, filter.Cursor, true), nil
+}
+
+func (s *memPosts) Update(ctx context.Context, postID, editorID int, title, content string, categoryIDs []int, tags []string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.posts[postID]
+	if !ok {
+		return ErrNotFound
+	}
+	title, content = stripMarks(title), stripMarks(content)
+	if tags != nil {
+		p.TagIDs = s.m.tagIDs(tags)
+	}
+	// Tags are not part of revisions, so changing only them is not an edit
+	if title == p.Title && content == p.Content && sameIDs(categoryIDs, p.CategoryIDs) {
+		return nil
+	}
+
+	// The first edit keeps the original version as the first revision
+	if !slices.ContainsFunc(s.m.postRevisions, func(rev database.Revision) bool { return rev.TargetID == postID }) {
+		s.m.postRevisions = append(s.m.postRevisions, s.revision(p, p.UserID, p.CreationDate))
+	}
+	now := time.Now().UTC()
+	p.Title = title
+	p.Content = content
+	p.EditedAt = &now
+	p.CategoryIDs = slices.Clone(categoryIDs)
+	s.m.postRevisions = append(s.m.postRevisions, s.revision(p, editorID, now))
+	return nil
+}
+
+// revision returns the current version of a post as a revision
+func (s *memPosts) revision(p *memPost, editorID int, at time.Time) database.Revision {
+	return database.Revision{
+		RevisionID: s.m.nextID(), TargetID: p.PostID, EditorID: editorID,
+		Title: p.Title, Content: p.Content, Categories: s.m.post(p).Categories, CreationDate: at,
+	}
+}
+
+func (s *memPosts) SetHidden(ctx context.Context, postID int, hidden bool) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.posts[postID]
+	if !ok {
+		return ErrNotFound
+	}
+	p.Hidden = hidden
+	return nil
+}
+
+func (s *memPosts) Delete(ctx context.Context, postID, deletedBy int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.posts[postID]
+	if !ok || p.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now().UTC()
+	p.DeletedAt, p.DeletedBy = &now, deletedBy
+	return nil
+}
+
+func (s *memPosts) Restore(ctx context.Context, postID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.posts[postID]
+	if !ok || p.DeletedAt == nil {
+		return ErrNotFound
+	}
+	p.DeletedAt, p.DeletedBy = nil, 0
+	return nil
+}
+
+// purgePost removes a post with its comments, votes, revisions and
+// notifications; callers hold the lock
+func (m *memory) purgePost(postID int) {
+	for id, c := range m.comments {
+		if c.PostID == postID {
+			m.deleteComment(id)
+		}
+	}
+	for key := range m.postVotes {
+		if key.ID == postID {
+			delete(m.postVotes, key)
+		}
+	}
+	for id, n := range m.notifications {
+		if n.RelatedPostID != nil && *n.RelatedPostID == postID {
+			delete(m.notifications, id)
+		}
+	}
+	m.postRevisions = slices.DeleteFunc(m.postRevisions, func(rev database.Revision) bool {
+		return rev.TargetID == postID
+	})
+	m.deleteMentions("post", postID)
+	delete(m.posts, postID)
+}
+
+type memComments struct {
+	m *memory
+}
+
+func (s *memComments) Create(ctx context.Context, comment *database.Comment) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.posts[comment.PostID]; !ok {
+		return 0, ErrNotFound
+	}
+	id := s.m.nextID()
+	c := *comment
+	c.CommentID = id
+	c.Content = stripMarks(c.Content)
+	c.CreationDate = time.Now().UTC()
+	s.m.comments[id] = &c
+	return id, nil
+}
+
+func (s *memComments) Get(ctx context.Context, commentID int) (*database.Comment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.comments[commentID]
+	if !ok || !s.m.live(c) {
+		return nil, ErrNotFound
+	}
+	comment := s.m.comment(c)
+	return &comment, nil
+}
+
+// list returns the matching comments, oldest first
+func (s *memComments) list(match func(c *database.Comment) bool) []database.Comment {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var comments []database.Comment
+	for _, c := range s.m.comments {
+		if match(c) {
+			comments = append(comments, s.m.comment(c))
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if !comments[i].CreationDate.Equal(comments[j].CreationDate) {
+			return comments[i].CreationDate.Before(comments[j].CreationDate)
+		}
+		return comments[i].CommentID < comments[j].CommentID
+	})
+	return comments
+}
+
+func (s *memComments) ListByPost(ctx context.Context, postID int, page Page) ([]database.Comment, error) {
+	comments := s.list(func(c *database.Comment) bool { return c.PostID == postID })
+
+	var roots []database.Comment
+	for _, c := range comments {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		}
+	}
+	keep := make(map[int]bool)
+	for _, c := range pageOf(roots, page, CommentCursor, false) {
+		keep[c.CommentID] = true
+	}
+
+	// Parents are older than their replies, so they are decided first
+	var list []database.Comment
+	for _, c := range comments {
+		if c.ParentID != nil {
+			keep[c.CommentID] = keep[*c.ParentID]
+		}
+		if keep[c.CommentID] {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
+
+func (s *memComments) ListByUser(ctx context.Context, userID int, page Page) ([]database.Comment, error) {
+	comments := s.list(func(c *database.Comment) bool { return c.UserID == userID && s.m.live(c) })
+	slices.Reverse(comments)
+	return pageOf(comments, page, CommentCursor, true), nil
+}
+
+func (s *memComments) Update(ctx context.Context, commentID, editorID int, content string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.comments[commentID]
+	if !ok {
+		return ErrNotFound
+	}
+	// Saving the same content again is not an edit
+	content = stripMarks(content)
+	if content == c.Content {
+		return nil
+	}
+
+	// The first edit keeps the original version as the first revision
+	if !slices.ContainsFunc(s.m.commentRevisions, func(rev database.Revision) bool { return rev.TargetID == commentID }) {
+		s.m.commentRevisions = append(s.m.commentRevisions, database.Revision{
+			RevisionID: s.m.nextID(), TargetID: commentID, EditorID: c.UserID, Content: c.Content, CreationDate: c.CreationDate,
+		})
+	}
+	now := time.Now().UTC()
+	c.Content = content
+	c.EditedAt = &now
+	s.m.commentRevisions = append(s.m.commentRevisions, database.Revision{
+		RevisionID: s.m.nextID(), TargetID: commentID, EditorID: editorID, Content: c.Content, CreationDate: now,
+	})
+	return nil
+}
+
+func (s *memComments) SetHidden(ctx context.Context, commentID int, hidden bool) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.comments[commentID]
+	if !ok {
+		return ErrNotFound
+	}
+	c.Hidden = hidden
+	return nil
+}
+
+func (s *memComments) Delete(ctx context.Context, commentID, deletedBy int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.comments[commentID]
+	if !ok || c.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now().UTC()
+	c.DeletedAt, c.DeletedBy = &now, deletedBy
+	return nil
+}
+
+func (s *memComments) Restore(ctx context.Context, commentID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.comments[commentID]
+	if !ok || c.DeletedAt == nil {
+		return ErrNotFound
+	}
+	c.DeletedAt, c.DeletedBy = nil, 0
+	return nil
+}
+
+// deleteComment removes a comment with its votes and notifications; callers hold the lock
+func (m *memory) deleteComment(commentID int) {
+	for key := range m.commentVotes {
+		if key.ID == commentID {
+			delete(m.commentVotes, key)
+		}
+	}
+	for id, n := range m.notifications {
+		if n.RelatedCommentID != nil && *n.RelatedCommentID == commentID {
+			delete(m.notifications, id)
+		}
+	}
+	m.commentRevisions = slices.DeleteFunc(m.commentRevisions, func(rev database.Revision) bool {
+		return rev.TargetID == commentID
+	})
+	m.deleteMentions("comment", commentID)
+	parentID := m.comments[commentID].ParentID
+	for _, c := range m.comments {
+		if c.ParentID != nil && *c.ParentID == commentID {
+			c.ParentID = parentID
+		}
+	}
+	delete(m.comments, commentID)
+}
+
+func (s *memComments) Commenters(ctx context.Context, postID int, exclude ...int) ([]int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var users []int
+	for _, c := range s.m.comments {
+		if c.PostID == postID && c.DeletedAt == nil && !slices.Contains(exclude, c.UserID) && !slices.Contains(users, c.UserID) {
+			users = append(users, c.UserID)
+		}
+	}
+	sort.Ints(users)
+	return users, nil
+}
+
+type memVotes struct {
+	m *memory
+}
+
+func (s *memVotes) get(votes map[voteKey]int, id, userID int) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	return votes[voteKey{id, userID}], nil
+}
+
+func (s *memVotes) set(votes map[voteKey]int, id, userID, vote int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if vote == 0 {
+		delete(votes, voteKey{id, userID})
+	} else {
+		votes[voteKey{id, userID}] = vote
+	}
+	return nil
+}
+
+func (s *memVotes) counts(votes map[voteKey]int, id int) (likes, dislikes int, err error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	likes, dislikes = countVotes(votes, id)
+	return likes, dislikes, nil
+}
+
+func (s *memVotes) PostVote(ctx context.Context, postID, userID int) (int, error) {
+	return s.get(s.m.postVotes, postID, userID)
+}
+
+func (s *memVotes) SetPostVote(ctx context.Context, postID, userID, vote int) error {
+	return s.set(s.m.postVotes, postID, userID, vote)
+}
+
+func (s *memVotes) PostCounts(ctx context.Context, postID int) (likes, dislikes int, err error) {
+	return s.counts(s.m.postVotes, postID)
+}
+
+func (s *memVotes) CommentVote(ctx context.Context, commentID, userID int) (int, error) {
+	return s.get(s.m.commentVotes, commentID, userID)
+}
+
+func (s *memVotes) SetCommentVote(ctx context.Context, commentID, userID, vote int) error {
+	return s.set(s.m.commentVotes, commentID, userID, vote)
+}
+
+func (s *memVotes) CommentCounts(ctx context.Context, commentID int) (likes, dislikes int, err error) {
+	return s.counts(s.m.commentVotes, commentID)
+}
+
+type memCategories struct {
+	m *memory
+}
+
+// category returns the index of a category in the shared slice, or -1
+func (s *memCategories) category(categoryID int) int {
+	return slices.IndexFunc(s.m.categories, func(c database.Category) bool { return c.CategoryID == categoryID })
+}
+
+// checkParent fails with ErrNotFound if parentID is not a category, and with
+// ErrCycle if it is categoryID or one of its descendants
+func (s *memCategories) checkParent(categoryID int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	for id := parentID; id != nil; {
+		i := s.category(*id)
+		if i < 0 {
+			return ErrNotFound
+		}
+		if *id == categoryID {
+			return ErrCycle
+		}
+		id = s.m.categories[i].ParentID
+	}
+	return nil
+}
+
+// inCategory reports whether a category is the one named or one of its descendants
+func (m *memory) inCategory(categoryID int, name string) bool {
+	for id := &categoryID; id != nil; {
+		i := slices.IndexFunc(m.categories, func(c database.Category) bool { return c.CategoryID == *id })
+		if i < 0 {
+			return false
+		}
+		if m.categories[i].Name == name {
+			return true
+		}
+		id = m.categories[i].ParentID
+	}
+	return false
+}
+
+// inCategoryTree reports whether a post is in the category named or one of its descendants
+func (m *memory) inCategoryTree(p *memPost, name string) bool {
+	return slices.ContainsFunc(p.CategoryIDs, func(id int) bool { return m.inCategory(id, name) })
+}
+
+// taken reports whether another category than categoryID has the name or slug
+func (s *memCategories) taken(categoryID int, name, slug string) bool {
+	return slices.ContainsFunc(s.m.categories, func(c database.Category) bool {
+		return c.CategoryID != categoryID && (c.Name == name || c.Slug == slug)
+	})
+}
+
+func (s *memCategories) List(ctx context.Context, includeArchived bool) ([]database.Category, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var categories []database.Category
+	for _, c := range s.m.categories {
+		if includeArchived || !c.Archived {
+			categories = append(categories, c)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].SortOrder != categories[j].SortOrder {
+			return categories[i].SortOrder < categories[j].SortOrder
+		}
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
+
+func (s *memCategories) Get(ctx context.Context, categoryID int) (*database.Category, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	i := s.category(categoryID)
+	if i < 0 {
+		return nil, ErrNotFound
+	}
+	category := s.m.categories[i]
+	return &category, nil
+}
+
+func (s *memCategories) Create(ctx context.Context, category *database.Category) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if err := s.checkParent(0, category.ParentID); err != nil {
+		return 0, err
+	}
+	if s.taken(0, category.Name, category.Slug) {
+		return 0, ErrConflict
+	}
+	stored := *category
+	stored.CategoryID = s.m.nextID()
+	stored.Archived = false
+	stored.SortOrder = 1
+	for _, c := range s.m.categories {
+		stored.SortOrder = max(stored.SortOrder, c.SortOrder+1)
+	}
+	s.m.categories = append(s.m.categories, stored)
+	return stored.CategoryID, nil
+}
+
+func (s *memCategories) Update(ctx context.Context, category *database.Category) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	i := s.category(category.CategoryID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if err := s.checkParent(category.CategoryID, category.ParentID); err != nil {
+		return err
+	}
+	if s.taken(category.CategoryID, category.Name, category.Slug) {
+		return ErrConflict
+	}
+	c := &s.m.categories[i]
+	c.Name, c.Slug, c.Description, c.Color, c.Icon = category.Name, category.Slug, category.Description, category.Color, category.Icon
+	c.ParentID = category.ParentID
+	return nil
+}
+
+func (s *memCategories) Reorder(ctx context.Context, categoryIDs []int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	sorted := slices.Clone(s.m.categories)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].SortOrder < sorted[j].SortOrder })
+	current := make([]int, len(sorted))
+	for i, c := range sorted {
+		current[i] = c.CategoryID
+	}
+	order, err := reordered(current, categoryIDs)
+	if err != nil {
+		return err
+	}
+	for i, id := range order {
+		s.m.categories[s.category(id)].SortOrder = i + 1
+	}
+	return nil
+}
+
+func (s *memCategories) SetArchived(ctx context.Context, categoryID int, archived bool) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	i := s.category(categoryID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.m.categories[i].Archived = archived
+	return nil
+}
+
+func (s *memCategories) Merge(ctx context.Context, fromID, intoID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if s.category(fromID) < 0 {
+		return ErrNotFound
+	}
+	if err := s.checkParent(fromID, &intoID); err != nil {
+		return err
+	}
+	s.reparent(fromID, &intoID)
+	for _, p := range s.m.posts {
+		p.CategoryIDs = replaceCategory(p.CategoryIDs, fromID, intoID)
+	}
+	for _, d := range s.m.drafts {
+		d.CategoryIDs = replaceCategory(d.CategoryIDs, fromID, intoID)
+	}
+	from := s.category(fromID)
+	s.m.categories = slices.Delete(s.m.categories, from, from+1)
+	return nil
+}
+
+// replaceCategory swaps fromID for intoID in a list of category IDs,
+// keeping intoID once
+func replaceCategory(categoryIDs []int, fromID, intoID int) []int {
+	i := slices.Index(categoryIDs, fromID)
+	if i < 0 {
+		return categoryIDs
+	}
+	categoryIDs = slices.Delete(categoryIDs, i, i+1)
+	if !slices.Contains(categoryIDs, intoID) {
+		categoryIDs = append(categoryIDs, intoID)
+	}
+	return categoryIDs
+}
+
+// reparent moves the sub-categories of a category into parentID
+func (s *memCategories) reparent(categoryID int, parentID *int) {
+	for i, c := range s.m.categories {
+		if c.ParentID != nil && *c.ParentID == categoryID {
+			s.m.categories[i].ParentID = parentID
+		}
+	}
+}
+
+func (s *memCategories) Delete(ctx context.Context, categoryID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, p := range s.m.posts {
+		if slices.Contains(p.CategoryIDs, categoryID) {
+			return ErrConflict
+		}
+	}
+	i := s.category(categoryID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.reparent(categoryID, s.m.categories[i].ParentID)
+	for _, d := range s.m.drafts {
+		d.CategoryIDs = slices.DeleteFunc(d.CategoryIDs, func(id int) bool { return id == categoryID })
+	}
+	s.m.categories = slices.Delete(s.m.categories, i, i+1)
+	return nil
+}
+
+type memNotifications struct {
+	m *memory
+}
+
+func (s *memNotifications) Create(ctx context.Context, n *database.Notification) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	n.NotificationID = s.m.nextID()
+	n.CreationDate = time.Now().UTC()
+	stored := *n
+	s.m.notifications[n.NotificationID] = &stored
+	return nil
+}
+
+func (s *memNotifications) HasRecent(ctx context.Context, n *database.Notification, window time.Duration) (bool, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	since := time.Now().Add(-window)
+	for _, other := range s.m.notifications {
+		if other.UserID == n.UserID && other.Type == n.Type &&
+			other.Title == n.Title && other.Message == n.Message &&
+			sameID(other.RelatedPostID, n.RelatedPostID) &&
+			sameID(other.RelatedCommentID, n.RelatedCommentID) &&
+			sameID(other.RelatedUserID, n.RelatedUserID) &&
+			other.CreationDate.After(since) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sameID compares two optional ids the way SQL's IS operator does
+func sameID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (s *memNotifications) Get(ctx context.Context, notificationID int) (*database.Notification, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	n, ok := s.m.notifications[notificationID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	notification := *n
+	return &notification, nil
+}
+
+func (s *memNotifications) List(ctx context.Context, userID int, read bool, page Page) ([]database.Notification, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var list []database.Notification
+	for _, n := range s.m.notifications {
+		if n.UserID == userID && n.IsRead == read {
+			list = append(list, *n)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].CreationDate.Equal(list[j].CreationDate) {
+			return list[i].CreationDate.After(list[j].CreationDate)
+		}
+		return list[i].NotificationID > list[j].NotificationID
+	})
+	return pageOf(list, page, NotificationCursor, true), nil
+}
+
+func (s *memNotifications) MarkRead(ctx context.Context, notificationID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	n, ok := s.m.notifications[notificationID]
+	if !ok {
+		return ErrNotFound
+	}
+	n.IsRead = true
+	return nil
+}
+
+func (s *memNotifications) MarkAllRead(ctx context.Context, userID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, n := range s.m.notifications {
+		if n.UserID == userID {
+			n.IsRead = true
+		}
+	}
+	return nil
+}
+
+func (s *memNotifications) UnreadCount(ctx context.Context, userID int) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	count := 0
+	for _, n := range s.m.notifications {
+		if n.UserID == userID && !n.IsRead {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memNotifications) DeleteReadBefore(ctx context.Context, before time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for id, n := range s.m.notifications {
+		if n.IsRead && n.CreationDate.Before(before) {
+			delete(s.m.notifications, id)
+		}
+	}
+	return nil
+}
+
+func (s *memNotifications) CreateSystem(ctx context.Context, userIDs []int, title, message string) error {
+	for _, userID := range userIDs {
+		n := &database.Notification{UserID: userID, Type: "system", Title: title, Message: message}
+		if err := s.Create(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type memImages struct {
+	m *memory
+}
+
+func (s *memImages) Create(ctx context.Context, img *database.Image) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, other := range s.m.images {
+		if other.Filename == img.Filename {
+			return ErrConflict
+		}
+	}
+	img.ImageID = s.m.nextID()
+	img.UploadDate = time.Now().UTC()
+	stored := *img
+	s.m.images[img.ImageID] = &stored
+	return nil
+}
+
+func (s *memImages) GetByFilename(ctx context.Context, filename string) (*database.Image, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, img := range s.m.images {
+		if img.Filename == filename {
+			image := *img
+			return &image, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memImages) ListByUser(ctx context.Context, userID int, imageType string) ([]database.Image, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var list []database.Image
+	for _, img := range s.m.images {
+		if img.UserID == userID && strings.EqualFold(img.ImageType, imageType) {
+			list = append(list, *img)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ImageID > list[j].ImageID })
+	return list, nil
+}
+
+func (s *memImages) Delete(ctx context.Context, filename string) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for id, img := range s.m.images {
+		if img.Filename != filename {
+			continue
+		}
+		for _, p := range s.m.posts {
+			if p.ImageID != nil && *p.ImageID == id {
+				p.ImageID = nil
+			}
+		}
+		for _, d := range s.m.drafts {
+			if d.ImageID != nil && *d.ImageID == id {
+				d.ImageID = nil
+			}
+		}
+		delete(s.m.images, id)
+		return nil
+	}
+	return ErrNotFound
+}
+
+type memModerationLog struct {
+	m *memory
+}
+
+func (s *memModerationLog) Record(ctx context.Context, action *database.ModerationAction) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	action.ActionID = s.m.nextID()
+	action.CreationDate = time.Now().UTC()
+	s.m.moderationLog = append(s.m.moderationLog, *action)
+	return nil
+}
+
+func (s *memModerationLog) List(ctx context.Context, limit, offset int) ([]database.ModerationAction, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var list []database.ModerationAction
+	for i := len(s.m.moderationLog) - 1 - offset; i >= 0 && len(list) < limit; i-- {
+		action := s.m.moderationLog[i]
+		if u, ok := s.m.users[action.ActorID]; ok {
+			action.ActorName = u.Username
+		}
+		list = append(list, action)
+	}
+	return list, nil
+}
+
+type memReports struct {
+	m *memory
+}
+
+// report returns a copy of a report with the reporter's username filled in
+func (m *memory) report(rp *database.Report) database.Report {
+	report := *rp
+	if u, ok := m.users[report.ReporterID]; ok {
+		report.ReporterName = u.Username
+	}
+	return report
+}
+
+func (s *memReports) Create(ctx context.Context, report *database.Report) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, other := range s.m.reports {
+		if other.Status == "open" && other.ReporterID == report.ReporterID &&
+			other.TargetType == report.TargetType && other.TargetID == report.TargetID {
+			return 0, ErrConflict
+		}
+	}
+	stored := *report
+	stored.ReportID = s.m.nextID()
+	stored.Status = "open"
+	stored.CreationDate = time.Now().UTC()
+	s.m.reports[stored.ReportID] = &stored
+	return stored.ReportID, nil
+}
+
+func (s *memReports) Get(ctx context.Context, reportID int) (*database.Report, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	rp, ok := s.m.reports[reportID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	report := s.m.report(rp)
+	return &report, nil
+}
+
+// open returns the open reports matching the predicate, oldest first
+func (s *memReports) open(match func(rp *database.Report) bool) []database.Report {
+	var list []database.Report
+	for _, rp := range s.m.reports {
+		if rp.Status == "open" && match(rp) {
+			list = append(list, s.m.report(rp))
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ReportID < list[j].ReportID })
+	return list
+}
+
+func (s *memReports) ListOpen(ctx context.Context, limit, offset int) ([]database.Report, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	list := s.open(func(*database.Report) bool { return true })
+	if offset >= len(list) {
+		return nil, nil
+	}
+	return list[offset:min(offset+limit, len(list))], nil
+}
+
+func (s *memReports) Resolve(ctx context.Context, targetType string, targetID int, resolution string, resolvedBy int) ([]database.Report, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	resolved := s.open(func(rp *database.Report) bool { return rp.TargetType == targetType && rp.TargetID == targetID })
+	for i := range resolved {
+		rp := s.m.reports[resolved[i].ReportID]
+		rp.Status, rp.Resolution = "resolved", resolution
+		resolved[i].Status, resolved[i].Resolution = rp.Status, rp.Resolution
+	}
+	return resolved, nil
+}
+
+// memSearch approximates the FTS5 search: every term must start a word of the
+// text, without stemming, and texts with more matching words rank first
+type memSearch struct {
+	m *memory
+}
+
+func (s *memSearch) Posts(ctx context.Context, q SearchQuery, page Page) ([]database.SearchHit, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var hits []database.SearchHit
+	for _, p := range s.m.posts {
+		post := s.m.post(p)
+		if post.Hidden || post.DeletedAt != nil || !s.matches(q, post.PostID, post.Username, post.CreationDate) {
+			continue
+		}
+		title, inTitle := markTerms(post.Title, q.Terms)
+		content, inContent := markTerms(post.Content, q.Terms)
+		if !containsAll(inTitle, inContent, q.Terms) {
+			continue
+		}
+		hits = append(hits, database.SearchHit{
+			PostID: post.PostID, Title: title, Snippet: content,
+			Username: post.Username, CreationDate: post.CreationDate,
+			Rank: -float64(10*len(inTitle) + len(inContent)),
+		})
+	}
+	return sortHits(hits, page), nil
+}
+
+func (s *memSearch) Comments(ctx context.Context, q SearchQuery, page Page) ([]database.SearchHit, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var hits []database.SearchHit
+	for _, c := range s.m.comments {
+		comment := s.m.comment(c)
+		p, ok := s.m.posts[comment.PostID]
+		if comment.Hidden || !ok || p.Hidden || !s.m.live(c) || !s.matches(q, comment.PostID, comment.Username, comment.CreationDate) {
+			continue
+		}
+		content, found := markTerms(comment.Content, q.Terms)
+		if !containsAll(found, nil, q.Terms) {
+			continue
+		}
+		hits = append(hits, database.SearchHit{
+			PostID: comment.PostID, CommentID: comment.CommentID, Title: comment.PostTitle, Snippet: content,
+			Username: comment.Username, CreationDate: comment.CreationDate, Rank: -float64(len(found)),
+		})
+	}
+	return sortHits(hits, page), nil
+}
+
+// matches reports whether a text passes the filters of the query
+func (s *memSearch) matches(q SearchQuery, postID int, author string, created time.Time) bool {
+	if q.Category != "" && !s.m.inCategoryTree(s.m.posts[postID], q.Category) {
+		return false
+	}
+	if q.Author != "" && author != q.Author {
+		return false
+	}
+	if !q.From.IsZero() && created.Before(q.From) {
+		return false
+	}
+	return q.To.IsZero() || created.Before(q.To)
+}
+
+// markTerms surrounds the words of text starting with a term with MarkStart
+// and MarkEnd, and returns the terms of every marked word
+func markTerms(text string, terms []string) (string, []string) {
+	var marked strings.Builder
+	var found []string
+	isWord := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !isWord(runes[i]) {
+			marked.WriteRune(runes[i])
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && isWord(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+		term := ""
+		for _, t := range terms {
+			if strings.HasPrefix(strings.ToLower(word), strings.ToLower(t)) {
+				term = t
+				break
+			}
+		}
+		if term != "" {
+			found = append(found, term)
+			word = MarkStart + word + MarkEnd
+		}
+		marked.WriteString(word)
+		i = end
+	}
+	return marked.String(), found
+}
+
+// containsAll reports whether every term was found in one of the lists
+func containsAll(a, b, terms []string) bool {
+	for _, t := range terms {
+		if !slices.Contains(a, t) && !slices.Contains(b, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// sortHits orders hits like the SQLite search, best rank then lowest id, and cuts the page
+func sortHits(hits []database.SearchHit, page Page) []database.SearchHit {
+	sort.Slice(hits, func(i, j int) bool {
+		return SearchCursor(hits[i]).follows(SearchCursor(hits[j]), false)
+	})
+	return pageOf(hits, page, SearchCursor, false)
+}
+
+type memDrafts struct {
+	m *memory
+}
+
+// draft returns a copy of a draft with the joined columns filled in
+func (s *memDrafts) draft(d *memDraft) database.Draft {
+	draft := d.Draft
+	draft.ImageFilename, draft.ImageURL = "", ""
+	if draft.ImageID != nil {
+		if img, ok := s.m.images[*draft.ImageID]; ok {
+			draft.ImageFilename, draft.ImageURL = img.Filename, img.ImageURL
+		}
+	}
+	draft.Categories = []string{}
+	for _, cat := range s.m.categories {
+		if slices.Contains(d.CategoryIDs, cat.CategoryID) {
+			draft.Categories = append(draft.Categories, cat.Name)
+		}
+	}
+	return draft
+}
+
+func (s *memDrafts) Save(ctx context.Context, draft *database.Draft, categoryIDs []int) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	stored := &memDraft{Draft: *draft, CategoryIDs: slices.Clone(categoryIDs)}
+	if draft.DraftID == 0 {
+		stored.DraftID = s.m.nextID()
+	} else if d, ok := s.m.drafts[draft.DraftID]; !ok || d.UserID != draft.UserID {
+		return 0, ErrNotFound
+	}
+	stored.UpdatedAt = time.Now().UTC()
+	s.m.drafts[stored.DraftID] = stored
+	return stored.DraftID, nil
+}
+
+func (s *memDrafts) Get(ctx context.Context, draftID int) (*database.Draft, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	d, ok := s.m.drafts[draftID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	draft := s.draft(d)
+	return &draft, nil
+}
+
+func (s *memDrafts) ListByUser(ctx context.Context, userID int, page Page) ([]database.Draft, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var drafts []database.Draft
+	for _, d := range s.m.drafts {
+		if d.UserID == userID {
+			drafts = append(drafts, s.draft(d))
+		}
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		return DraftCursor(drafts[i]).follows(DraftCursor(drafts[j]), true)
+	})
+	return pageOf(drafts, page, DraftCursor, true), nil
+}
+
+func (s *memDrafts) Delete(ctx context.Context, draftID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.drafts[draftID]; !ok {
+		return ErrNotFound
+	}
+	delete(s.m.drafts, draftID)
+	return nil
+}
+
+func (s *memDrafts) Publish(ctx context.Context, draftID int, post *database.Post, categoryIDs []int) (int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	if _, ok := s.m.drafts[draftID]; !ok {
+		return 0, ErrNotFound
+	}
+	delete(s.m.drafts, draftID)
+	return s.m.insertPost(post, categoryIDs), nil
+}
+
+type memRevisions struct {
+	m *memory
+}
+
+// list returns the revisions of target from a revision list, editor names filled in
+func (s *memRevisions) list(revisions *[]database.Revision, targetID int) []database.Revision {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var list []database.Revision
+	for _, rev := range *revisions {
+		if rev.TargetID == targetID {
+			list = append(list, s.withEditor(rev))
+		}
+	}
+	return list
+}
+
+// get finds a revision by ID in a revision list
+func (s *memRevisions) get(revisions *[]database.Revision, revisionID int) (*database.Revision, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for _, rev := range *revisions {
+		if rev.RevisionID == revisionID {
+			rev = s.withEditor(rev)
+			return &rev, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memRevisions) withEditor(rev database.Revision) database.Revision {
+	if u, ok := s.m.users[rev.EditorID]; ok {
+		rev.Editor = u.Username
+	}
+	rev.Categories = slices.Clone(rev.Categories)
+	return rev
+}
+
+func (s *memRevisions) ListPost(ctx context.Context, postID int) ([]database.Revision, error) {
+	return s.list(&s.m.postRevisions, postID), nil
+}
+
+func (s *memRevisions) GetPost(ctx context.Context, revisionID int) (*database.Revision, error) {
+	return s.get(&s.m.postRevisions, revisionID)
+}
+
+func (s *memRevisions) ListComment(ctx context.Context, commentID int) ([]database.Revision, error) {
+	return s.list(&s.m.commentRevisions, commentID), nil
+}
+
+func (s *memRevisions) GetComment(ctx context.Context, revisionID int) (*database.Revision, error) {
+	return s.get(&s.m.commentRevisions, revisionID)
+}
+
+type memTags struct {
+	m *memory
+}
+
+// tag returns a tag with its count of visible posts
+func (s *memTags) tag(tagID int) database.Tag {
+	tag := database.Tag{TagID: tagID, Name: s.m.tags[tagID]}
+	for _, p := range s.m.posts {
+		if !p.Hidden && p.DeletedAt == nil && slices.Contains(p.TagIDs, tagID) {
+			tag.PostCount++
+		}
+	}
+	return tag
+}
+
+func (s *memTags) List(ctx context.Context, prefix string, limit int) ([]database.Tag, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var tags []database.Tag
+	for id, name := range s.m.tags {
+		if tag := s.tag(id); strings.HasPrefix(name, prefix) && tag.PostCount > 0 {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	return tags[:min(limit, len(tags))], nil
+}
+
+func (s *memTags) Get(ctx context.Context, name string) (*database.Tag, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for id, tagName := range s.m.tags {
+		if tagName == name {
+			tag := s.tag(id)
+			return &tag, nil
+		}
+	}
+	if id, ok := s.m.tagAliases[name]; ok {
+		tag := s.tag(id)
+		return &tag, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (s *memTags) Merge(ctx context.Context, fromID, intoID int) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	name, ok := s.m.tags[fromID]
+	if _, found := s.m.tags[intoID]; !ok || !found {
+		return ErrNotFound
+	}
+	for _, p := range s.m.posts {
+		if i := slices.Index(p.TagIDs, fromID); i >= 0 {
+			p.TagIDs = slices.Delete(p.TagIDs, i, i+1)
+			if !slices.Contains(p.TagIDs, intoID) {
+				p.TagIDs = append(p.TagIDs, intoID)
+			}
+		}
+	}
+	for alias, id := range s.m.tagAliases {
+		if id == fromID {
+			s.m.tagAliases[alias] = intoID
+		}
+	}
+	s.m.tagAliases[name] = intoID
+	delete(s.m.tags, fromID)
+	return nil
+}
+
+type memTrash struct {
+	m *memory
+}
+
+func (s *memTrash) Post(ctx context.Context, postID int) (*database.Post, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	p, ok := s.m.posts[postID]
+	if !ok || p.DeletedAt == nil {
+		return nil, ErrNotFound
+	}
+	post := s.m.post(p)
+	return &post, nil
+}
+
+func (s *memTrash) Comment(ctx context.Context, commentID int) (*database.Comment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	c, ok := s.m.comments[commentID]
+	if !ok || c.DeletedAt == nil {
+		return nil, ErrNotFound
+	}
+	comment := s.m.comment(c)
+	return &comment, nil
+}
+
+func (s *memTrash) Posts(ctx context.Context, userID int, since time.Time, page Page) ([]database.Post, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var posts []database.Post
+	for _, p := range s.m.posts {
+		if p.UserID == userID && p.DeletedAt != nil && !p.DeletedAt.Before(since) {
+			posts = append(posts, s.m.post(p))
+		}
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return TrashPostCursor(posts[i]).follows(TrashPostCursor(posts[j]), true)
+	})
+	return pageOf(posts, page, TrashPostCursor, true), nil
+}
+
+func (s *memTrash) Comments(ctx context.Context, userID int, since time.Time, page Page) ([]database.Comment, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var comments []database.Comment
+	for _, c := range s.m.comments {
+		if c.UserID == userID && c.DeletedAt != nil && !c.DeletedAt.Before(since) {
+			comments = append(comments, s.m.comment(c))
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return TrashCommentCursor(comments[i]).follows(TrashCommentCursor(comments[j]), true)
+	})
+	return pageOf(comments, page, TrashCommentCursor, true), nil
+}
+
+func (s *memTrash) Purge(ctx context.Context, before time.Time) error {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	for id, p := range s.m.posts {
+		if p.DeletedAt != nil && p.DeletedAt.Before(before) {
+			s.m.purgePost(id)
+		}
+	}
+
+	// Removing a comment can leave its deleted parent without replies
+	for purged := true; purged; {
+		purged = false
+		for id, c := range s.m.comments {
+			if c.DeletedAt == nil || !c.DeletedAt.Before(before) || s.hasReplies(id) {
+				continue
+			}
+			s.m.deleteComment(id)
+			purged = true
+		}
+	}
+
+	// The rest keep their place in the thread with nothing left of them
+	for id, c := range s.m.comments {
+		if c.DeletedAt != nil && c.DeletedAt.Before(before) {
+			c.Content = ""
+			s.m.commentRevisions = slices.DeleteFunc(s.m.commentRevisions, func(rev database.Revision) bool {
+				return rev.TargetID == id
+			})
+		}
+	}
+	return nil
+}
+
+// hasReplies reports whether any comment replies to the given one; callers hold the lock
+func (s *memTrash) hasReplies(commentID int) bool {
+	for _, c := range s.m.comments {
+		if c.ParentID != nil && *c.ParentID == commentID {
+			return true
+		}
+	}
+	return false
+}
+
+type memMentions struct {
+	m *memory
+}
+
+func (s *memMentions) Add(ctx context.Context, targetType string, targetID int, userIDs []int) ([]int, error) {
+	s.m.mu.Lock()
+	defer s.m.mu.Unlock()
+
+	var added []int
+	for _, userID := range userIDs {
+		key := mentionKey{targetType, targetID, userID}
+		if !s.m.mentions[key] {
+			s.m.mentions[key] = true
+			added = append(added, userID)
+		}
+	}
+	return added, nil
+}
+
+// deleteMentions forgets who was mentioned in a post or comment; callers hold the lock
+func (m *memory) deleteMentions(targetType string, targetID int) {
+	for key := range m.mentions {
+		if key.TargetType == targetType && key.TargetID == targetID {
+			delete(m.mentions, key)
+		}
+	}
+}
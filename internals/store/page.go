@@ -0,0 +1,139 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum/internals/database"
+	"time"
+)
+
+// Page selects one page of a listing. Listings are ordered by creation time
+// and ID, so a page picks up right after the last row of the previous one
+// and stays stable while new rows are inserted.
+type Page struct {
+	// Limit is the most rows to return; zero returns every row
+	Limit int
+	// After is the position of the last row of the previous page, nil for the first page
+	After *Cursor
+}
+
+// Cursor is the position of a row in a listing. Listings sorted by a score
+// order by Score first; the ID breaks ties between rows created in the same
+// second.
+type Cursor struct {
+	Score float64   `json:"s,omitempty"`
+	Time  time.Time `json:"t"`
+	ID    int       `json:"id"`
+	// At is the Unix time scores that change with age were computed for, so
+	// every page of a listing ranks rows the same way
+	At int64 `json:"at,omitempty"`
+}
+
+// ErrBadCursor is returned when a cursor from a client cannot be decoded
+var ErrBadCursor = errors.New("invalid cursor")
+
+// Encode turns the cursor into the opaque token handed to API clients
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token made by Cursor.Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID <= 0 {
+		return nil, ErrBadCursor
+	}
+	return &c, nil
+}
+
+// CommentCursor returns the position of a comment in a comment listing
+func CommentCursor(c database.Comment) Cursor {
+	return Cursor{Time: c.CreationDate, ID: c.CommentID}
+}
+
+// TrashPostCursor returns the position of a post in a trash listing
+func TrashPostCursor(p database.Post) Cursor {
+	return Cursor{Time: *p.DeletedAt, ID: p.PostID}
+}
+
+// TrashCommentCursor returns the position of a comment in a trash listing
+func TrashCommentCursor(c database.Comment) Cursor {
+	return Cursor{Time: *c.DeletedAt, ID: c.CommentID}
+}
+
+// NotificationCursor returns the position of a notification in a notification listing
+func NotificationCursor(n database.Notification) Cursor {
+	return Cursor{Time: n.CreationDate, ID: n.NotificationID}
+}
+
+// DraftCursor returns the position of a draft in a draft listing
+func DraftCursor(d database.Draft) Cursor {
+	return Cursor{Time: d.UpdatedAt, ID: d.DraftID}
+}
+
+// follows reports whether position comes after the cursor in a listing
+// sorted newest first when desc is set, oldest first otherwise
+func (c Cursor) follows(position Cursor, desc bool) bool {
+	if position.Score != c.Score {
+		return (position.Score < c.Score) == desc
+	}
+	if !position.Time.Equal(c.Time) {
+		return position.Time.Before(c.Time) == desc
+	}
+	return position.ID != c.ID && (position.ID < c.ID) == desc
+}
+
+// sqlAfter returns the condition keeping the rows after the page's cursor in
+// a query ordered by timeColumn and idColumn, or "" on the first page
+func (p Page) sqlAfter(timeColumn, idColumn string, desc bool) (string, []any) {
+	if p.After == nil {
+		return "", nil
+	}
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (?, ?)", timeColumn, idColumn, op), []any{sqliteTime(p.After.Time), p.After.ID}
+}
+
+// sqlLimit returns the LIMIT clause of the page, or "" when it has no limit
+func (p Page) sqlLimit() string {
+	if p.Limit <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" LIMIT %d", p.Limit)
+}
+
+// pageOf cuts the page out of a listing that is already sorted
+func pageOf[T any](list []T, p Page, position func(T) Cursor, desc bool) []T {
+	if p.After != nil {
+		start := len(list)
+		for i, item := range list {
+			if p.After.follows(position(item), desc) {
+				start = i
+				break
+			}
+		}
+		list = list[start:]
+	}
+	if p.Limit > 0 && len(list) > p.Limit {
+		list = list[:p.Limit]
+	}
+	return list
+}
+
+// SearchCursor returns the position of a hit in a search listing
+func SearchCursor(h database.SearchHit) Cursor {
+	id := h.PostID
+	if h.CommentID != 0 {
+		id = h.CommentID
+	}
+	return Cursor{Score: h.Rank, ID: id}
+}
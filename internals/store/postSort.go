@@ -0,0 +1,91 @@
+package store
+
+import (
+	"fmt"
+	"forum/internals/database"
+	"time"
+)
+
+// PostSort is the order of a post listing
+type PostSort string
+
+const (
+	// SortNew lists the newest posts first
+	SortNew PostSort = "new"
+	// SortTop lists the posts with the most likes net of dislikes first
+	SortTop PostSort = "top"
+	// SortHot ranks votes and comments, decaying with the post's age
+	SortHot PostSort = "hot"
+	// SortComments lists the most discussed posts first
+	SortComments PostSort = "comments"
+)
+
+// Valid reports whether s is a known sort order
+func (s PostSort) Valid() bool {
+	switch s {
+	case SortNew, SortTop, SortHot, SortComments:
+		return true
+	}
+	return false
+}
+
+// hotScore weighs the net votes and comments of a post against its age in
+// hours; the +2 keeps brand new posts from dominating. sqlHotScore computes
+// the same value, so keep the two in step.
+func hotScore(net, comments int, age time.Duration) float64 {
+	hours := float64(int64(age/time.Second))/3600.0 + 2
+	return float64(net+comments) / (hours * hours)
+}
+
+// Score columns of the sorted listings, matching postScore
+const (
+	sqlNetVotes     = "(SELECT COALESCE(SUM(ld.vote), 0) FROM LikesDislikes ld WHERE ld.post_id = p.post_id)"
+	sqlCommentCount = "(SELECT COUNT(*) FROM Comments c WHERE c.post_id = p.post_id AND NOT c.hidden AND c.deleted_at IS NULL)"
+)
+
+// sqlScore returns the SQL expression of the filter's sort score and its
+// arguments, or "" when posts are sorted by date alone
+func (f PostFilter) sqlScore() (string, []any) {
+	switch f.Sort {
+	case SortTop:
+		return sqlNetVotes, nil
+	case SortComments:
+		return sqlCommentCount, nil
+	case SortHot:
+		age := "MAX(0, ? - CAST(strftime('%s', p.creation_date) AS INTEGER))"
+		hours := fmt.Sprintf("(%s / 3600.0 + 2)", age)
+		return fmt.Sprintf("(%s + %s) / (%s * %s)", sqlNetVotes, sqlCommentCount, hours, hours),
+			[]any{f.at().Unix(), f.at().Unix()}
+	}
+	return "", nil
+}
+
+// postScore is the sort score of a post, as sqlScore computes it
+func (f PostFilter) postScore(p database.Post) float64 {
+	switch f.Sort {
+	case SortTop:
+		return float64(p.Nbrlike - p.Nbrdislike)
+	case SortComments:
+		return float64(p.Nbrcomments)
+	case SortHot:
+		return hotScore(p.Nbrlike-p.Nbrdislike, p.Nbrcomments, max(f.at().Sub(p.CreationDate), 0))
+	}
+	return 0
+}
+
+func (f PostFilter) at() time.Time {
+	if f.At.IsZero() {
+		return time.Now().Truncate(time.Second)
+	}
+	return f.At.Truncate(time.Second)
+}
+
+// Cursor returns the position of a post in a listing with this filter
+func (f PostFilter) Cursor(p database.Post) Cursor {
+	c := Cursor{Score: f.postScore(p), Time: p.CreationDate, ID: p.PostID}
+	// Hot scores and time windows depend on when the first page was served
+	if f.Sort == SortHot || !f.Since.IsZero() {
+		c.At = f.at().Unix()
+	}
+	return c
+}
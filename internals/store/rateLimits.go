@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket holding up to capacity tokens that refill evenly
+// over window, so a client can burst capacity requests and then one more
+// every window/capacity
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// refill adds the tokens that arrived between the last update and now, and
+// returns how long until the bucket holds a whole token, zero if it does
+func (b *bucket) refill(capacity int, window time.Duration, now time.Time) time.Duration {
+	rate := float64(capacity) / window.Seconds()
+	if b.updated.IsZero() {
+		b.tokens = float64(capacity)
+	} else {
+		b.tokens = min(float64(capacity), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	}
+	b.updated = now
+
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// takeAll refills buckets up to now and removes one token from each of them,
+// or from none when any is empty, returning how long until all have one
+func takeAll(buckets []*bucket, capacity int, window time.Duration, now time.Time) (bool, time.Duration) {
+	var retryAfter time.Duration
+	for _, b := range buckets {
+		retryAfter = max(retryAfter, b.refill(capacity, window, now))
+	}
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+	for _, b := range buckets {
+		b.tokens--
+	}
+	return true, 0
+}
+
+// fullAt is when the bucket will have refilled completely; from then on it
+// behaves exactly like a new one and need not be kept
+func (b *bucket) fullAt(capacity int, window time.Duration) time.Time {
+	rate := float64(capacity) / window.Seconds()
+	return b.updated.Add(time.Duration((float64(capacity) - b.tokens) / rate * float64(time.Second)))
+}
+
+// NewMemoryRateLimits returns a RateLimitStore that keeps its buckets in
+// memory; limits start over whenever the server restarts
+func NewMemoryRateLimits() RateLimitStore {
+	return &memRateLimits{buckets: map[string]*memBucket{}}
+}
+
+type memBucket struct {
+	bucket
+	// expires is when the bucket has refilled and can be forgotten
+	expires time.Time
+}
+
+type memRateLimits struct {
+	mu      sync.Mutex
+	buckets map[string]*memBucket
+	takes   int
+}
+
+func (s *memRateLimits) Take(_ context.Context, keys []string, capacity int, window time.Duration) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+
+	// Every so often forget the buckets that have refilled completely
+	if s.takes++; s.takes%1000 == 0 {
+		for k, b := range s.buckets {
+			if b.expires.Before(now) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	buckets := make([]*bucket, len(keys))
+	for i, key := range keys {
+		b, ok := s.buckets[key]
+		if !ok || b.expires.Before(now) {
+			b = &memBucket{}
+			s.buckets[key] = b
+		}
+		buckets[i] = &b.bucket
+	}
+	allowed, retryAfter := takeAll(buckets, capacity, window, now)
+	for _, key := range keys {
+		b := s.buckets[key]
+		b.expires = b.fullAt(capacity, window)
+	}
+	return allowed, retryAfter, nil
+}
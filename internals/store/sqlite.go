@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internals/database"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// NewSQLite returns a Store backed by the shared SQLite connection pool
+func NewSQLite(db *sql.DB) *Store {
+	return &Store{
+		Users:         &sqliteUsers{db},
+		Sessions:      &sqliteSessions{db},
+		Posts:         &sqlitePosts{db},
+		Comments:      &sqliteComments{db},
+		Votes:         &sqliteVotes{db},
+		Categories:    &sqliteCategories{db},
+		Notifications: &sqliteNotifications{db},
+		Images:        &sqliteImages{db},
+		RateLimits:    &sqliteRateLimits{db},
+		ModerationLog: &sqliteModerationLog{db},
+		Reports:       &sqliteReports{db},
+		Search:        &sqliteSearch{db},
+		Tags:          &sqliteTags{db},
+		Drafts:        &sqliteDrafts{db},
+		Revisions:     &sqliteRevisions{db},
+		Trash:         &sqliteTrash{db},
+		Mentions:      &sqliteMentions{db},
+	}
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// queryAll runs a query and maps every row through the type's ScanRows
+func queryAll[T any, PT interface {
+	*T
+	database.Table
+}](ctx context.Context, q querier, query string, args ...any) ([]T, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []T
+	for rows.Next() {
+		var item T
+		if err := PT(&item).ScanRows(rows); err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+	return list, rows.Err()
+}
+
+// queryOne runs a query expected to return a single row
+func queryOne[T any, PT interface {
+	*T
+	database.Table
+}](ctx context.Context, q querier, query string, args ...any) (*T, error) {
+	var item T
+	err := PT(&item).ScanRows(q.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// queryInts runs a query returning a single integer column
+func queryInts(ctx context.Context, q querier, query string, args ...any) ([]int, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []int
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// withTx runs fn inside a transaction, committing only if it succeeds
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execMustAffect runs a statement and returns ErrNotFound if no row changed
+func execMustAffect(ctx context.Context, q querier, query string, args ...any) error {
+	res, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// placeholders returns "?, ?, ?" for n arguments
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// conflict turns a unique constraint violation into ErrConflict
+func conflict(err error) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return ErrConflict
+	}
+	return err
+}
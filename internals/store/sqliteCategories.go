@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+	"slices"
+)
+
+// categoryColumns selects categories in the column order expected by Category.ScanRows
+const categoryColumns = `
+	SELECT category_id, name, description, slug, color, icon, sort_order, archived, parent_id
+	FROM Categories`
+
+// sqlPostsInCategory selects the IDs of the posts in the category named by
+// its one argument or in any of its descendants
+const sqlPostsInCategory = `
+	SELECT pc.post_id FROM PostCategories pc
+	WHERE pc.category_id IN (
+		WITH RECURSIVE tree(id) AS (
+			SELECT category_id FROM Categories WHERE name = ?
+			UNION
+			SELECT c.category_id FROM Categories c JOIN tree ON c.parent_id = tree.id
+		)
+		SELECT id FROM tree)`
+
+// sqlIsAncestor counts 1 when the category given first is the category given
+// second or one of its ancestors, by walking up the parents of the second
+const sqlIsAncestor = `
+	WITH RECURSIVE up(id) AS (
+		SELECT ?2
+		UNION
+		SELECT c.parent_id FROM Categories c JOIN up ON c.category_id = up.id WHERE c.parent_id IS NOT NULL
+	)
+	SELECT COUNT(*) FROM up WHERE id = ?1`
+
+type sqliteCategories struct {
+	db *sql.DB
+}
+
+func (s *sqliteCategories) List(ctx context.Context, includeArchived bool) ([]database.Category, error) {
+	query := categoryColumns
+	if !includeArchived {
+		query += " WHERE NOT archived"
+	}
+	return queryAll[database.Category](ctx, s.db, query+" ORDER BY sort_order, name")
+}
+
+func (s *sqliteCategories) Get(ctx context.Context, categoryID int) (*database.Category, error) {
+	return queryOne[database.Category](ctx, s.db, categoryColumns+" WHERE category_id = ?", categoryID)
+}
+
+func (s *sqliteCategories) Create(ctx context.Context, category *database.Category) (int, error) {
+	var id int64
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := checkParent(ctx, tx, 0, category.ParentID); err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, `
+			INSERT INTO Categories (name, slug, description, color, icon, parent_id, sort_order)
+			VALUES (?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(sort_order), 0) + 1 FROM Categories))`,
+			category.Name, category.Slug, category.Description, category.Color, category.Icon, category.ParentID)
+		if err != nil {
+			return conflict(err)
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	return int(id), err
+}
+
+func (s *sqliteCategories) Update(ctx context.Context, category *database.Category) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := checkParent(ctx, tx, category.CategoryID, category.ParentID); err != nil {
+			return err
+		}
+		return conflict(execMustAffect(ctx, tx, `
+			UPDATE Categories SET name = ?, slug = ?, description = ?, color = ?, icon = ?, parent_id = ?
+			WHERE category_id = ?`,
+			category.Name, category.Slug, category.Description, category.Color, category.Icon, category.ParentID,
+			category.CategoryID))
+	})
+}
+
+// checkParent fails with ErrNotFound if parentID is not a category, and with
+// ErrCycle if it is categoryID or one of its descendants
+func checkParent(ctx context.Context, tx *sql.Tx, categoryID int, parentID *int) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, err := queryOne[database.Category](ctx, tx, categoryColumns+" WHERE category_id = ?", *parentID); err != nil {
+		return err
+	}
+	var inside int
+	if err := tx.QueryRowContext(ctx, sqlIsAncestor, categoryID, *parentID).Scan(&inside); err != nil {
+		return err
+	}
+	if inside > 0 {
+		return ErrCycle
+	}
+	return nil
+}
+
+func (s *sqliteCategories) Reorder(ctx context.Context, categoryIDs []int) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		current, err := queryInts(ctx, tx, "SELECT category_id FROM Categories ORDER BY sort_order, name")
+		if err != nil {
+			return err
+		}
+		order, err := reordered(current, categoryIDs)
+		if err != nil {
+			return err
+		}
+		for i, id := range order {
+			if _, err := tx.ExecContext(ctx, "UPDATE Categories SET sort_order = ? WHERE category_id = ?", i+1, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteCategories) SetArchived(ctx context.Context, categoryID int, archived bool) error {
+	return execMustAffect(ctx, s.db, "UPDATE Categories SET archived = ? WHERE category_id = ?", archived, categoryID)
+}
+
+func (s *sqliteCategories) Merge(ctx context.Context, fromID, intoID int) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := checkParent(ctx, tx, fromID, &intoID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE Categories SET parent_id = ? WHERE parent_id = ?", intoID, fromID); err != nil {
+			return err
+		}
+		// Posts already in both categories keep their one row for the target
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO PostCategories (post_id, category_id)
+			SELECT post_id, ? FROM PostCategories WHERE category_id = ?`, intoID, fromID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM PostCategories WHERE category_id = ?", fromID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO DraftCategories (draft_id, category_id)
+			SELECT draft_id, ? FROM DraftCategories WHERE category_id = ?`, intoID, fromID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM DraftCategories WHERE category_id = ?", fromID); err != nil {
+			return err
+		}
+		return execMustAffect(ctx, tx, "DELETE FROM Categories WHERE category_id = ?", fromID)
+	})
+}
+
+func (s *sqliteCategories) Delete(ctx context.Context, categoryID int) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var used int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM PostCategories WHERE category_id = ?", categoryID).Scan(&used); err != nil {
+			return err
+		}
+		if used > 0 {
+			return ErrConflict
+		}
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE Categories SET parent_id = (SELECT parent_id FROM Categories WHERE category_id = ?1)
+			WHERE parent_id = ?1`, categoryID); err != nil {
+			return err
+		}
+		// Drafts simply lose the category
+		if _, err := tx.ExecContext(ctx, "DELETE FROM DraftCategories WHERE category_id = ?", categoryID); err != nil {
+			return err
+		}
+		return execMustAffect(ctx, tx, "DELETE FROM Categories WHERE category_id = ?", categoryID)
+	})
+}
+
+// reordered moves the given IDs, in their order, before the rest of current.
+// It fails with ErrNotFound if an ID is not in current.
+func reordered(current, first []int) ([]int, error) {
+	order := make([]int, 0, len(current))
+	for _, id := range first {
+		if !slices.Contains(current, id) {
+			return nil, ErrNotFound
+		}
+		if !slices.Contains(order, id) {
+			order = append(order, id)
+		}
+	}
+	for _, id := range current {
+		if !slices.Contains(order, id) {
+			order = append(order, id)
+		}
+	}
+	return order, nil
+}
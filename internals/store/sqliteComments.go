@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internals/database"
+)
+
+// commentColumns selects comments in the column order expected by Comment.ScanRows
+const commentColumns = `
+	SELECT c.comment_id, c.post_id, p.title, c.parent_comment_id, c.user_id, u.username, c.content, c.creation_date,
+		(SELECT COUNT(*) FROM CommentLikes cl WHERE cl.comment_id = c.comment_id AND cl.vote = 1),
+		(SELECT COUNT(*) FROM CommentLikes cl WHERE cl.comment_id = c.comment_id AND cl.vote = -1),
+		c.hidden, c.edited_at, c.deleted_at, c.deleted_by
+	FROM Comments c
+	JOIN Users u ON c.user_id = u.user_id
+	JOIN Posts p ON c.post_id = p.post_id`
+
+type sqliteComments struct {
+	db *sql.DB
+}
+
+func (s *sqliteComments) Create(ctx context.Context, comment *database.Comment) (int, error) {
+	res, err := s.db.ExecContext(ctx, "INSERT INTO Comments (post_id, user_id, content, parent_comment_id) VALUES (?, ?, ?, ?)",
+		comment.PostID, comment.UserID, stripMarks(comment.Content), comment.ParentID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *sqliteComments) Get(ctx context.Context, commentID int) (*database.Comment, error) {
+	return queryOne[database.Comment](ctx, s.db, commentColumns+`
+		WHERE c.comment_id = ? AND c.deleted_at IS NULL AND p.deleted_at IS NULL`, commentID)
+}
+
+func (s *sqliteComments) ListByPost(ctx context.Context, postID int, page Page) ([]database.Comment, error) {
+	roots := "post_id = ? AND parent_comment_id IS NULL"
+	args := []any{postID}
+	if after, afterArgs := page.sqlAfter("creation_date", "comment_id", false); after != "" {
+		roots += " AND " + after
+		args = append(args, afterArgs...)
+	}
+
+	// The page is counted in top-level comments; the replies below them come along
+	return queryAll[database.Comment](ctx, s.db, `
+		WITH RECURSIVE thread(id) AS (
+			SELECT comment_id FROM (
+				SELECT comment_id FROM Comments WHERE `+roots+`
+				ORDER BY creation_date ASC, comment_id ASC`+page.sqlLimit()+`
+			)
+			UNION ALL
+			SELECT r.comment_id FROM Comments r JOIN thread t ON r.parent_comment_id = t.id
+		)`+commentColumns+`
+		WHERE c.comment_id IN (SELECT id FROM thread)
+		ORDER BY c.creation_date ASC, c.comment_id ASC`, args...)
+}
+
+func (s *sqliteComments) ListByUser(ctx context.Context, userID int, page Page) ([]database.Comment, error) {
+	query := commentColumns + " WHERE c.user_id = ? AND c.deleted_at IS NULL AND p.deleted_at IS NULL"
+	args := []any{userID}
+	if after, afterArgs := page.sqlAfter("c.creation_date", "c.comment_id", true); after != "" {
+		query += " AND " + after
+		args = append(args, afterArgs...)
+	}
+	query += " ORDER BY c.creation_date DESC, c.comment_id DESC" + page.sqlLimit()
+	return queryAll[database.Comment](ctx, s.db, query, args...)
+}
+
+func (s *sqliteComments) Update(ctx context.Context, commentID, editorID int, content string) error {
+	content = stripMarks(content)
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var current string
+		err := tx.QueryRowContext(ctx, "SELECT content FROM Comments WHERE comment_id = ?", commentID).Scan(&current)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		// Saving the same content again is not an edit
+		if content == current {
+			return nil
+		}
+
+		// The first edit keeps the original version as the first revision
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO CommentRevisions (comment_id, editor_id, content, creation_date)
+			SELECT comment_id, user_id, content, creation_date
+			FROM Comments
+			WHERE comment_id = ?1 AND NOT EXISTS (SELECT 1 FROM CommentRevisions WHERE comment_id = ?1)`, commentID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE Comments SET content = ?, edited_at = CURRENT_TIMESTAMP WHERE comment_id = ?",
+			content, commentID); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO CommentRevisions (comment_id, editor_id, content) VALUES (?, ?, ?)",
+			commentID, editorID, content)
+		return err
+	})
+}
+
+func (s *sqliteComments) SetHidden(ctx context.Context, commentID int, hidden bool) error {
+	return execMustAffect(ctx, s.db, "UPDATE Comments SET hidden = ? WHERE comment_id = ?", hidden, commentID)
+}
+
+func (s *sqliteComments) Delete(ctx context.Context, commentID, deletedBy int) error {
+	return execMustAffect(ctx, s.db, `
+		UPDATE Comments SET deleted_at = CURRENT_TIMESTAMP, deleted_by = ?
+		WHERE comment_id = ? AND deleted_at IS NULL`, deletedBy, commentID)
+}
+
+func (s *sqliteComments) Restore(ctx context.Context, commentID int) error {
+	return execMustAffect(ctx, s.db, `
+		UPDATE Comments SET deleted_at = NULL, deleted_by = NULL
+		WHERE comment_id = ? AND deleted_at IS NOT NULL`, commentID)
+}
+
+func (s *sqliteComments) Commenters(ctx context.Context, postID int, exclude ...int) ([]int, error) {
+	query := "SELECT DISTINCT user_id FROM Comments WHERE post_id = ? AND deleted_at IS NULL"
+	args := []any{postID}
+	if len(exclude) > 0 {
+		query += " AND user_id NOT IN (" + placeholders(len(exclude)) + ")"
+		for _, id := range exclude {
+			args = append(args, id)
+		}
+	}
+	return queryInts(ctx, s.db, query, args...)
+}
+
+type sqliteVotes struct {
+	db *sql.DB
+}
+
+func (s *sqliteVotes) PostVote(ctx context.Context, postID, userID int) (int, error) {
+	return scanVote(s.db.QueryRowContext(ctx, "SELECT vote FROM LikesDislikes WHERE post_id = ? AND user_id = ?", postID, userID))
+}
+
+func (s *sqliteVotes) SetPostVote(ctx context.Context, postID, userID, vote int) error {
+	if vote == 0 {
+		_, err := s.db.ExecContext(ctx, "DELETE FROM LikesDislikes WHERE post_id = ? AND user_id = ?", postID, userID)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO LikesDislikes (post_id, user_id, vote) VALUES (?, ?, ?)
+		ON CONFLICT(post_id, user_id) DO UPDATE SET vote = excluded.vote`, postID, userID, vote)
+	return err
+}
+
+func (s *sqliteVotes) PostCounts(ctx context.Context, postID int) (likes, dislikes int, err error) {
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN vote = 1 THEN 1 ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN vote = -1 THEN 1 ELSE 0 END), 0)
+		FROM LikesDislikes WHERE post_id = ?`, postID).Scan(&likes, &dislikes)
+	return likes, dislikes, err
+}
+
+func (s *sqliteVotes) CommentVote(ctx context.Context, commentID, userID int) (int, error) {
+	return scanVote(s.db.QueryRowContext(ctx, "SELECT vote FROM CommentLikes WHERE comment_id = ? AND user_id = ?", commentID, userID))
+}
+
+func (s *sqliteVotes) SetCommentVote(ctx context.Context, commentID, userID, vote int) error {
+	if vote == 0 {
+		_, err := s.db.ExecContext(ctx, "DELETE FROM CommentLikes WHERE comment_id = ? AND user_id = ?", commentID, userID)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO CommentLikes (comment_id, user_id, vote) VALUES (?, ?, ?)
+		ON CONFLICT(comment_id, user_id) DO UPDATE SET vote = excluded.vote`, commentID, userID, vote)
+	return err
+}
+
+func (s *sqliteVotes) CommentCounts(ctx context.Context, commentID int) (likes, dislikes int, err error) {
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(CASE WHEN vote = 1 THEN 1 ELSE 0 END), 0),
+		       COALESCE(SUM(CASE WHEN vote = -1 THEN 1 ELSE 0 END), 0)
+		FROM CommentLikes WHERE comment_id = ?`, commentID).Scan(&likes, &dislikes)
+	return likes, dislikes, err
+}
+
+// scanVote reads a single vote, treating a missing row as no vote
+func scanVote(row *sql.Row) (int, error) {
+	var vote int
+	err := row.Scan(&vote)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return vote, err
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+)
+
+// draftColumns selects drafts in the column order expected by Draft.ScanRows
+const draftColumns = `
+	SELECT d.draft_id, d.user_id, d.title, d.content, d.image_id, i.filename, i.image_url, d.tags, d.updated_at
+	FROM Drafts d
+	LEFT JOIN Images i ON d.image_id = i.image_id`
+
+type sqliteDrafts struct {
+	db *sql.DB
+}
+
+func (s *sqliteDrafts) Save(ctx context.Context, draft *database.Draft, categoryIDs []int) (int, error) {
+	draftID := draft.DraftID
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if draftID == 0 {
+			res, err := tx.ExecContext(ctx, "INSERT INTO Drafts (user_id, title, content, image_id, tags) VALUES (?, ?, ?, ?, ?)",
+				draft.UserID, draft.Title, draft.Content, draft.ImageID, draft.Tags)
+			if err != nil {
+				return err
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			draftID = int(id)
+		} else {
+			if err := execMustAffect(ctx, tx, `
+				UPDATE Drafts SET title = ?, content = ?, image_id = ?, tags = ?, updated_at = CURRENT_TIMESTAMP
+				WHERE draft_id = ? AND user_id = ?`,
+				draft.Title, draft.Content, draft.ImageID, draft.Tags, draftID, draft.UserID); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM DraftCategories WHERE draft_id = ?", draftID); err != nil {
+				return err
+			}
+		}
+		for _, categoryID := range categoryIDs {
+			if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO DraftCategories (draft_id, category_id) VALUES (?, ?)",
+				draftID, categoryID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return draftID, err
+}
+
+func (s *sqliteDrafts) Get(ctx context.Context, draftID int) (*database.Draft, error) {
+	draft, err := queryOne[database.Draft](ctx, s.db, draftColumns+" WHERE d.draft_id = ?", draftID)
+	if err != nil {
+		return nil, err
+	}
+	drafts := []database.Draft{*draft}
+	if err := loadDraftCategories(ctx, s.db, drafts); err != nil {
+		return nil, err
+	}
+	return &drafts[0], nil
+}
+
+func (s *sqliteDrafts) ListByUser(ctx context.Context, userID int, page Page) ([]database.Draft, error) {
+	query := draftColumns + " WHERE d.user_id = ?"
+	args := []any{userID}
+	if after, afterArgs := page.sqlAfter("d.updated_at", "d.draft_id", true); after != "" {
+		query += " AND " + after
+		args = append(args, afterArgs...)
+	}
+	query += " ORDER BY d.updated_at DESC, d.draft_id DESC" + page.sqlLimit()
+
+	drafts, err := queryAll[database.Draft](ctx, s.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadDraftCategories(ctx, s.db, drafts); err != nil {
+		return nil, err
+	}
+	return drafts, nil
+}
+
+func (s *sqliteDrafts) Delete(ctx context.Context, draftID int) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		return deleteDraft(ctx, tx, draftID)
+	})
+}
+
+func (s *sqliteDrafts) Publish(ctx context.Context, draftID int, post *database.Post, categoryIDs []int) (int, error) {
+	var postID int
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := deleteDraft(ctx, tx, draftID); err != nil {
+			return err
+		}
+		var err error
+		postID, err = insertPost(ctx, tx, post, categoryIDs)
+		return err
+	})
+	return postID, err
+}
+
+// deleteDraft removes a draft with its categories
+func deleteDraft(ctx context.Context, tx *sql.Tx, draftID int) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM DraftCategories WHERE draft_id = ?", draftID); err != nil {
+		return err
+	}
+	return execMustAffect(ctx, tx, "DELETE FROM Drafts WHERE draft_id = ?", draftID)
+}
+
+// loadDraftCategories fills in the category names of every draft with one query
+func loadDraftCategories(ctx context.Context, q querier, drafts []database.Draft) error {
+	if len(drafts) == 0 {
+		return nil
+	}
+
+	index := make(map[int]*database.Draft, len(drafts))
+	args := make([]any, len(drafts))
+	for i := range drafts {
+		drafts[i].Categories = []string{}
+		index[drafts[i].DraftID] = &drafts[i]
+		args[i] = drafts[i].DraftID
+	}
+
+	return loadNames(ctx, q, `
+		SELECT dc.draft_id, c.name
+		FROM DraftCategories dc
+		JOIN Categories c ON dc.category_id = c.category_id
+		WHERE dc.draft_id IN (`+placeholders(len(args))+`)
+		ORDER BY c.category_id`, args, func(draftID int, name string) {
+		index[draftID].Categories = append(index[draftID].Categories, name)
+	})
+}
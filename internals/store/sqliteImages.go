@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+)
+
+// imageColumns selects images in the column order expected by Image.ScanRows
+const imageColumns = `
+	SELECT image_id, user_id, filename, original_name, file_size, file_type, image_type,
+		image_url, COALESCE(thumbnail_url, ''), upload_date
+	FROM Images`
+
+type sqliteImages struct {
+	db *sql.DB
+}
+
+func (s *sqliteImages) Create(ctx context.Context, img *database.Image) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO Images (user_id, filename, original_name, file_size, file_type, image_type, image_url, thumbnail_url)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		img.UserID, img.Filename, img.OriginalName, img.FileSize, img.FileType, img.ImageType, img.ImageURL, img.ThumbnailURL)
+	if err != nil {
+		return conflict(err)
+	}
+	id, err := res.LastInsertId()
+	img.ImageID = int(id)
+	return err
+}
+
+func (s *sqliteImages) GetByFilename(ctx context.Context, filename string) (*database.Image, error) {
+	return queryOne[database.Image](ctx, s.db, imageColumns+" WHERE filename = ?", filename)
+}
+
+func (s *sqliteImages) ListByUser(ctx context.Context, userID int, imageType string) ([]database.Image, error) {
+	return queryAll[database.Image](ctx, s.db, imageColumns+" WHERE user_id = ? AND image_type = ? ORDER BY upload_date DESC, image_id DESC", userID, imageType)
+}
+
+func (s *sqliteImages) Delete(ctx context.Context, filename string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "UPDATE Posts SET image_id = NULL WHERE image_id IN (SELECT image_id FROM Images WHERE filename = ?)", filename); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE Drafts SET image_id = NULL WHERE image_id IN (SELECT image_id FROM Images WHERE filename = ?)", filename); err != nil {
+			return err
+		}
+		return execMustAffect(ctx, tx, "DELETE FROM Images WHERE filename = ?", filename)
+	})
+}
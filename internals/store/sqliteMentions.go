@@ -0,0 +1,32 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+)
+
+type sqliteMentions struct {
+	db *sql.DB
+}
+
+func (s *sqliteMentions) Add(ctx context.Context, targetType string, targetID int, userIDs []int) ([]int, error) {
+	var added []int
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		for _, userID := range userIDs {
+			res, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO Mentions (target_type, target_id, user_id) VALUES (?, ?, ?)",
+				targetType, targetID, userID)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				added = append(added, userID)
+			}
+		}
+		return nil
+	})
+	return added, err
+}
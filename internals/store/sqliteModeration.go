@@ -0,0 +1,33 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+)
+
+type sqliteModerationLog struct {
+	db *sql.DB
+}
+
+func (s *sqliteModerationLog) Record(ctx context.Context, action *database.ModerationAction) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO ModerationLog (actor_id, action, target_type, target_id, details)
+		VALUES (?, ?, ?, ?, ?)`,
+		action.ActorID, action.Action, action.TargetType, action.TargetID, action.Details)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	action.ActionID = int(id)
+	return err
+}
+
+func (s *sqliteModerationLog) List(ctx context.Context, limit, offset int) ([]database.ModerationAction, error) {
+	return queryAll[database.ModerationAction](ctx, s.db, `
+		SELECT l.log_id, l.actor_id, u.username, l.action, l.target_type, l.target_id, l.details, l.creation_date
+		FROM ModerationLog l
+		JOIN Users u ON u.user_id = l.actor_id
+		ORDER BY l.creation_date DESC, l.log_id DESC
+		LIMIT ? OFFSET ?`, limit, offset)
+}
@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"forum/internals/database"
+	"time"
+)
+
+// notificationColumns selects notifications in the column order expected by Notification.ScanRows
+const notificationColumns = `
+	SELECT notification_id, user_id, type, title, message,
+		related_post_id, related_comment_id, related_user_id,
+		COALESCE(is_read, 0), creation_date
+	FROM Notifications`
+
+// sqliteTime formats a time the way CURRENT_TIMESTAMP stores it
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+type sqliteNotifications struct {
+	db *sql.DB
+}
+
+func (s *sqliteNotifications) Create(ctx context.Context, n *database.Notification) error {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO Notifications (user_id, type, title, message, related_post_id, related_comment_id, related_user_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		n.UserID, n.Type, n.Title, n.Message, n.RelatedPostID, n.RelatedCommentID, n.RelatedUserID)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	n.NotificationID = int(id)
+	return err
+}
+
+func (s *sqliteNotifications) HasRecent(ctx context.Context, n *database.Notification, window time.Duration) (bool, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM Notifications
+		WHERE user_id = ? AND type = ? AND title = ? AND message = ?
+		AND related_post_id IS ? AND related_comment_id IS ? AND related_user_id IS ?
+		AND creation_date > ?`,
+		n.UserID, n.Type, n.Title, n.Message, n.RelatedPostID, n.RelatedCommentID, n.RelatedUserID, sqliteTime(time.Now().Add(-window)),
+	).Scan(&count)
+	return count > 0, err
+}
+
+func (s *sqliteNotifications) Get(ctx context.Context, notificationID int) (*database.Notification, error) {
+	return queryOne[database.Notification](ctx, s.db, notificationColumns+" WHERE notification_id = ?", notificationID)
+}
+
+func (s *sqliteNotifications) List(ctx context.Context, userID int, read bool, page Page) ([]database.Notification, error) {
+	readFilter := "(is_read = 0 OR is_read IS NULL)"
+	if read {
+		readFilter = "is_read = 1"
+	}
+	query := fmt.Sprintf("%s WHERE user_id = ? AND %s", notificationColumns, readFilter)
+	args := []any{userID}
+	if after, afterArgs := page.sqlAfter("creation_date", "notification_id", true); after != "" {
+		query += " AND " + after
+		args = append(args, afterArgs...)
+	}
+	query += " ORDER BY creation_date DESC, notification_id DESC" + page.sqlLimit()
+	return queryAll[database.Notification](ctx, s.db, query, args...)
+}
+
+func (s *sqliteNotifications) MarkRead(ctx context.Context, notificationID int) error {
+	return execMustAffect(ctx, s.db, "UPDATE Notifications SET is_read = 1 WHERE notification_id = ?", notificationID)
+}
+
+func (s *sqliteNotifications) MarkAllRead(ctx context.Context, userID int) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE Notifications SET is_read = 1 WHERE user_id = ? AND (is_read = 0 OR is_read IS NULL)", userID)
+	return err
+}
+
+func (s *sqliteNotifications) UnreadCount(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM Notifications WHERE user_id = ? AND (is_read = 0 OR is_read IS NULL)", userID).Scan(&count)
+	return count, err
+}
+
+func (s *sqliteNotifications) DeleteReadBefore(ctx context.Context, before time.Time) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM Notifications WHERE is_read = 1 AND creation_date < ?", sqliteTime(before))
+	return err
+}
+
+func (s *sqliteNotifications) CreateSystem(ctx context.Context, userIDs []int, title, message string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, "INSERT INTO Notifications (user_id, type, title, message) VALUES (?, 'system', ?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, userID := range userIDs {
+			if _, err := stmt.ExecContext(ctx, userID, title, message); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,311 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"forum/internals/database"
+	"slices"
+	"strings"
+)
+
+// postColumns selects posts in the column order expected by Post.ScanRows
+const postColumns = `
+	SELECT p.post_id, p.user_id, u.username, p.title, p.content, p.image_id, p.creation_date,
+		(SELECT COUNT(*) FROM Comments c WHERE c.post_id = p.post_id AND NOT c.hidden AND c.deleted_at IS NULL),
+		(SELECT COUNT(*) FROM LikesDislikes ld WHERE ld.post_id = p.post_id AND ld.vote = 1),
+		(SELECT COUNT(*) FROM LikesDislikes ld WHERE ld.post_id = p.post_id AND ld.vote = -1),
+		i.image_url, i.thumbnail_url, p.hidden, p.edited_at, p.deleted_at, p.deleted_by
+	FROM Posts p
+	JOIN Users u ON p.user_id = u.user_id
+	LEFT JOIN Images i ON p.image_id = i.image_id`
+
+type sqlitePosts struct {
+	db *sql.DB
+}
+
+func (s *sqlitePosts) Create(ctx context.Context, post *database.Post, categoryIDs []int) (int, error) {
+	var postID int
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		postID, err = insertPost(ctx, tx, post, categoryIDs)
+		return err
+	})
+	return postID, err
+}
+
+func (s *sqlitePosts) Get(ctx context.Context, postID int) (*database.Post, error) {
+	return getPost(ctx, s.db, postColumns+" WHERE p.post_id = ? AND p.deleted_at IS NULL", postID)
+}
+
+func (s *sqlitePosts) List(ctx context.Context, filter PostFilter, page Page) ([]database.Post, error) {
+	where := []string{"p.deleted_at IS NULL"}
+	var args []any
+
+	if filter.Category != "" {
+		where = append(where, "p.post_id IN ("+sqlPostsInCategory+")")
+		args = append(args, filter.Category)
+	}
+	if filter.Tag != "" {
+		where = append(where, `p.post_id IN (
+			SELECT pt.post_id FROM PostTags pt
+			JOIN Tags t ON pt.tag_id = t.tag_id
+			WHERE t.name = ? OR t.tag_id = (SELECT tag_id FROM TagAliases WHERE alias = ?))`)
+		args = append(args, filter.Tag, filter.Tag)
+	}
+	if filter.AuthorID != 0 {
+		where = append(where, "p.user_id = ?")
+		args = append(args, filter.AuthorID)
+	}
+	if filter.Author != "" {
+		where = append(where, "u.username = ?")
+		args = append(args, filter.Author)
+	}
+	if filter.VotedBy != 0 {
+		where = append(where, "p.post_id IN (SELECT post_id FROM LikesDislikes WHERE user_id = ? AND vote = ?)")
+		args = append(args, filter.VotedBy, filter.Vote)
+	}
+	if !filter.IncludeHidden {
+		where = append(where, "NOT p.hidden")
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "p.creation_date >= ?")
+		args = append(args, sqliteTime(filter.Since))
+	}
+
+	// Sorted listings rank by score first, then newest first like the default
+	order := "p.creation_date DESC, p.post_id DESC"
+	score, scoreArgs := filter.sqlScore()
+	if score != "" {
+		order = score + " DESC, " + order
+	}
+	if page.After != nil {
+		if score != "" {
+			where = append(where, "("+score+", p.creation_date, p.post_id) < (?, ?, ?)")
+			args = append(args, scoreArgs...)
+			args = append(args, page.After.Score, sqliteTime(page.After.Time), page.After.ID)
+		} else {
+			after, afterArgs := page.sqlAfter("p.creation_date", "p.post_id", true)
+			where = append(where, after)
+			args = append(args, afterArgs...)
+		}
+	}
+
+	query := postColumns + " WHERE " + strings.Join(where, " AND ")
+	query += " ORDER BY " + order + page.sqlLimit()
+	args = append(args, scoreArgs...)
+
+	posts, err := queryAll[database.Post](ctx, s.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadPostLabels(ctx, s.db, posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (s *sqlitePosts) Update(ctx context.Context, postID, editorID int, title, content string, categoryIDs []int, tags []string) error {
+	title, content = stripMarks(title), stripMarks(content)
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var currentTitle, currentContent string
+		err := tx.QueryRowContext(ctx, "SELECT title, content FROM Posts WHERE post_id = ?", postID).Scan(&currentTitle, &currentContent)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		currentCategories, err := queryInts(ctx, tx, "SELECT category_id FROM PostCategories WHERE post_id = ?", postID)
+		if err != nil {
+			return err
+		}
+
+		// Tags are not part of revisions, so changing only them is not an edit
+		if title == currentTitle && content == currentContent && sameIDs(categoryIDs, currentCategories) {
+			return s.retag(ctx, tx, postID, tags)
+		}
+
+		// The first edit keeps the original version as the first revision
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO PostRevisions (post_id, editor_id, title, content, categories, creation_date)
+			SELECT p.post_id, p.user_id, p.title, p.content, `+sqlPostCategoryNames+`, p.creation_date
+			FROM Posts p
+			WHERE p.post_id = ?1 AND NOT EXISTS (SELECT 1 FROM PostRevisions WHERE post_id = ?1)`, postID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE Posts SET title = ?, content = ?, edited_at = CURRENT_TIMESTAMP WHERE post_id = ?",
+			title, content, postID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM PostCategories WHERE post_id = ?", postID); err != nil {
+			return err
+		}
+		if err := setPostCategories(ctx, tx, postID, categoryIDs); err != nil {
+			return err
+		}
+		if err := s.retag(ctx, tx, postID, tags); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO PostRevisions (post_id, editor_id, title, content, categories)
+			SELECT p.post_id, ?, p.title, p.content, `+sqlPostCategoryNames+`
+			FROM Posts p
+			WHERE p.post_id = ?`, editorID, postID)
+		return err
+	})
+}
+
+// retag replaces a post's tags, unless tags is nil
+func (s *sqlitePosts) retag(ctx context.Context, tx *sql.Tx, postID int, tags []string) error {
+	if tags == nil {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM PostTags WHERE post_id = ?", postID); err != nil {
+		return err
+	}
+	return setPostTags(ctx, tx, postID, tags)
+}
+
+func (s *sqlitePosts) SetHidden(ctx context.Context, postID int, hidden bool) error {
+	return execMustAffect(ctx, s.db, "UPDATE Posts SET hidden = ? WHERE post_id = ?", hidden, postID)
+}
+
+func (s *sqlitePosts) Delete(ctx context.Context, postID, deletedBy int) error {
+	return execMustAffect(ctx, s.db, `
+		UPDATE Posts SET deleted_at = CURRENT_TIMESTAMP, deleted_by = ?
+		WHERE post_id = ? AND deleted_at IS NULL`, deletedBy, postID)
+}
+
+func (s *sqlitePosts) Restore(ctx context.Context, postID int) error {
+	return execMustAffect(ctx, s.db, `
+		UPDATE Posts SET deleted_at = NULL, deleted_by = NULL
+		WHERE post_id = ? AND deleted_at IS NOT NULL`, postID)
+}
+
+// getPost runs a query for one post and fills in its categories and tags
+func getPost(ctx context.Context, q querier, query string, args ...any) (*database.Post, error) {
+	post, err := queryOne[database.Post](ctx, q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	posts := []database.Post{*post}
+	if err := loadPostLabels(ctx, q, posts); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
+}
+
+// insertPost adds a post with its categories and tags
+func insertPost(ctx context.Context, tx *sql.Tx, post *database.Post, categoryIDs []int) (int, error) {
+	res, err := tx.ExecContext(ctx, "INSERT INTO Posts (user_id, title, content, image_id) VALUES (?, ?, ?, ?)",
+		post.UserID, stripMarks(post.Title), stripMarks(post.Content), post.ImageID)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	postID := int(id)
+	if err := setPostCategories(ctx, tx, postID, categoryIDs); err != nil {
+		return 0, err
+	}
+	return postID, setPostTags(ctx, tx, postID, post.Tags)
+}
+
+// setPostCategories links a post to each of the given categories
+// sameIDs reports whether a and b hold the same IDs, in any order and
+// ignoring repeats
+func sameIDs(a, b []int) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+func setPostCategories(ctx context.Context, tx *sql.Tx, postID int, categoryIDs []int) error {
+	for _, categoryID := range categoryIDs {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO PostCategories (post_id, category_id) VALUES (?, ?)",
+			postID, categoryID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setPostTags tags a post with each of the given names, creating the tags
+// that do not exist yet. A name left by a merge gets the tag it was merged into.
+func setPostTags(ctx context.Context, tx *sql.Tx, postID int, names []string) error {
+	for _, name := range names {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO Tags (name)
+			SELECT ?1 WHERE NOT EXISTS (SELECT 1 FROM TagAliases WHERE alias = ?1)`, name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO PostTags (post_id, tag_id)
+			VALUES (?1, COALESCE((SELECT tag_id FROM TagAliases WHERE alias = ?2), (SELECT tag_id FROM Tags WHERE name = ?2)))`,
+			postID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadPostLabels fills in the category and tag names of every post with one
+// query each
+func loadPostLabels(ctx context.Context, q querier, posts []database.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	index := make(map[int]*database.Post, len(posts))
+	args := make([]any, len(posts))
+	for i := range posts {
+		posts[i].Categories = []string{}
+		posts[i].Tags = []string{}
+		index[posts[i].PostID] = &posts[i]
+		args[i] = posts[i].PostID
+	}
+
+	err := loadNames(ctx, q, `
+		SELECT pc.post_id, c.name
+		FROM PostCategories pc
+		JOIN Categories c ON pc.category_id = c.category_id
+		WHERE pc.post_id IN (`+placeholders(len(args))+`)
+		ORDER BY c.category_id`, args, func(postID int, name string) {
+		index[postID].Categories = append(index[postID].Categories, name)
+	})
+	if err != nil {
+		return err
+	}
+	return loadNames(ctx, q, `
+		SELECT pt.post_id, t.name
+		FROM PostTags pt
+		JOIN Tags t ON pt.tag_id = t.tag_id
+		WHERE pt.post_id IN (`+placeholders(len(args))+`)
+		ORDER BY t.name`, args, func(postID int, name string) {
+		index[postID].Tags = append(index[postID].Tags, name)
+	})
+}
+
+// loadNames runs a query returning row IDs with a name, such as post IDs with
+// their tags, and hands each row to add
+func loadNames(ctx context.Context, q querier, query string, args []any, add func(id int, name string)) error {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		add(id, name)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type sqliteRateLimits struct{ db *sql.DB }
+
+func (s *sqliteRateLimits) Take(ctx context.Context, keys []string, capacity int, window time.Duration) (bool, time.Duration, error) {
+	var allowed bool
+	var retryAfter time.Duration
+	now := time.Now()
+
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		// Writing first takes the write lock up front, and refilled buckets
+		// are dropped as they are no different from missing ones
+		if _, err := tx.ExecContext(ctx, `DELETE FROM RateLimits WHERE full_at < ?`, now.UnixMilli()); err != nil {
+			return err
+		}
+
+		buckets := make([]*bucket, len(keys))
+		for i, key := range keys {
+			b := &bucket{}
+			var updated int64
+			err := tx.QueryRowContext(ctx, `SELECT tokens, updated_at FROM RateLimits WHERE bucket_key = ?`, key).
+				Scan(&b.tokens, &updated)
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+			case err != nil:
+				return err
+			default:
+				b.updated = time.UnixMilli(updated)
+			}
+			buckets[i] = b
+		}
+
+		// A refused request charges nothing, so the buckets need no update
+		allowed, retryAfter = takeAll(buckets, capacity, window, now)
+		if !allowed {
+			return nil
+		}
+		for i, b := range buckets {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO RateLimits (bucket_key, tokens, updated_at, full_at) VALUES (?, ?, ?, ?)
+				ON CONFLICT(bucket_key) DO UPDATE SET tokens = excluded.tokens, updated_at = excluded.updated_at, full_at = excluded.full_at`,
+				keys[i], b.tokens, b.updated.UnixMilli(), b.fullAt(capacity, window).UnixMilli())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return allowed, retryAfter, err
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+	"time"
+)
+
+// reportColumns selects reports in the column order expected by Report.ScanRows
+const reportColumns = `
+	SELECT r.report_id, r.reporter_id, u.username, r.target_type, r.target_id, r.reason, r.details,
+		r.status, COALESCE(r.resolution, ''), r.creation_date
+	FROM Reports r
+	JOIN Users u ON r.reporter_id = u.user_id`
+
+type sqliteReports struct {
+	db *sql.DB
+}
+
+func (s *sqliteReports) Create(ctx context.Context, report *database.Report) (int, error) {
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO Reports (reporter_id, target_type, target_id, reason, details)
+		VALUES (?, ?, ?, ?, ?)`,
+		report.ReporterID, report.TargetType, report.TargetID, report.Reason, report.Details)
+	if err != nil {
+		return 0, conflict(err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *sqliteReports) Get(ctx context.Context, reportID int) (*database.Report, error) {
+	return queryOne[database.Report](ctx, s.db, reportColumns+" WHERE r.report_id = ?", reportID)
+}
+
+func (s *sqliteReports) ListOpen(ctx context.Context, limit, offset int) ([]database.Report, error) {
+	return queryAll[database.Report](ctx, s.db, reportColumns+`
+		WHERE r.status = 'open'
+		ORDER BY r.creation_date ASC, r.report_id ASC
+		LIMIT ? OFFSET ?`, limit, offset)
+}
+
+func (s *sqliteReports) Resolve(ctx context.Context, targetType string, targetID int, resolution string, resolvedBy int) ([]database.Report, error) {
+	var resolved []database.Report
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		resolved, err = queryAll[database.Report](ctx, tx, reportColumns+`
+			WHERE r.status = 'open' AND r.target_type = ? AND r.target_id = ?`, targetType, targetID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE Reports SET status = 'resolved', resolution = ?, resolved_by = ?, resolved_at = ?
+			WHERE status = 'open' AND target_type = ? AND target_id = ?`,
+			resolution, resolvedBy, time.Now().UTC(), targetType, targetID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range resolved {
+		resolved[i].Status = "resolved"
+		resolved[i].Resolution = resolution
+	}
+	return resolved, nil
+}
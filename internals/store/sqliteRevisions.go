@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+)
+
+// sqlPostCategoryNames is the JSON array of the category names of post p,
+// as stored in PostRevisions
+const sqlPostCategoryNames = `(
+	SELECT json_group_array(c.name)
+	FROM PostCategories pc
+	JOIN Categories c ON pc.category_id = c.category_id
+	WHERE pc.post_id = p.post_id)`
+
+// postRevisionColumns and commentRevisionColumns select revisions in the
+// column order expected by Revision.ScanRows
+const (
+	postRevisionColumns = `
+		SELECT r.revision_id, r.post_id, r.editor_id, u.username, r.title, r.content, r.categories, r.creation_date
+		FROM PostRevisions r
+		JOIN Users u ON r.editor_id = u.user_id`
+	commentRevisionColumns = `
+		SELECT r.revision_id, r.comment_id, r.editor_id, u.username, '', r.content, '[]', r.creation_date
+		FROM CommentRevisions r
+		JOIN Users u ON r.editor_id = u.user_id`
+)
+
+type sqliteRevisions struct {
+	db *sql.DB
+}
+
+func (s *sqliteRevisions) ListPost(ctx context.Context, postID int) ([]database.Revision, error) {
+	return queryAll[database.Revision](ctx, s.db, postRevisionColumns+" WHERE r.post_id = ? ORDER BY r.revision_id", postID)
+}
+
+func (s *sqliteRevisions) GetPost(ctx context.Context, revisionID int) (*database.Revision, error) {
+	return queryOne[database.Revision](ctx, s.db, postRevisionColumns+" WHERE r.revision_id = ?", revisionID)
+}
+
+func (s *sqliteRevisions) ListComment(ctx context.Context, commentID int) ([]database.Revision, error) {
+	return queryAll[database.Revision](ctx, s.db, commentRevisionColumns+" WHERE r.comment_id = ? ORDER BY r.revision_id", commentID)
+}
+
+func (s *sqliteRevisions) GetComment(ctx context.Context, revisionID int) (*database.Revision, error) {
+	return queryOne[database.Revision](ctx, s.db, commentRevisionColumns+" WHERE r.revision_id = ?", revisionID)
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+	"strings"
+)
+
+// MarkStart and MarkEnd surround the matched terms in search titles and
+// snippets. Posts and comments are stored without them (see stripMarks), so
+// the text can be escaped before the marks are turned into HTML.
+const (
+	MarkStart = "\x02"
+	MarkEnd   = "\x03"
+)
+
+var markStripper = strings.NewReplacer(MarkStart, "", MarkEnd, "")
+
+// stripMarks removes the search marks from a post title or from post or
+// comment content about to be stored, as search results read their text back
+// from the stored rows
+func stripMarks(s string) string {
+	return markStripper.Replace(s)
+}
+
+type sqliteSearch struct {
+	db *sql.DB
+}
+
+func (s *sqliteSearch) Posts(ctx context.Context, q SearchQuery, page Page) ([]database.SearchHit, error) {
+	// Matches in the title weigh ten times more than in the content
+	query := `
+		SELECT p.post_id, 0, highlight(PostsSearch, 0, ?1, ?2), snippet(PostsSearch, 1, ?1, ?2, '…', 24),
+			u.username, p.creation_date, bm25(PostsSearch, 10.0, 1.0) AS rank
+		FROM PostsSearch
+		JOIN Posts p ON p.post_id = PostsSearch.rowid
+		JOIN Users u ON p.user_id = u.user_id
+		WHERE PostsSearch MATCH ?3 AND NOT p.hidden AND p.deleted_at IS NULL`
+	args := []any{MarkStart, MarkEnd, matchQuery(q.Terms)}
+	where, whereArgs := q.sqlFilters("p.creation_date")
+	return s.hits(ctx, query+where, append(args, whereArgs...), "post_id", page)
+}
+
+func (s *sqliteSearch) Comments(ctx context.Context, q SearchQuery, page Page) ([]database.SearchHit, error) {
+	query := `
+		SELECT p.post_id, c.comment_id, p.title, snippet(CommentsSearch, 0, ?1, ?2, '…', 24),
+			u.username, c.creation_date, bm25(CommentsSearch) AS rank
+		FROM CommentsSearch
+		JOIN Comments c ON c.comment_id = CommentsSearch.rowid
+		JOIN Posts p ON c.post_id = p.post_id
+		JOIN Users u ON c.user_id = u.user_id
+		WHERE CommentsSearch MATCH ?3 AND NOT c.hidden AND NOT p.hidden
+			AND c.deleted_at IS NULL AND p.deleted_at IS NULL`
+	args := []any{MarkStart, MarkEnd, matchQuery(q.Terms)}
+	// The category is the post's, the author and dates are the comment's
+	where, whereArgs := q.sqlFilters("c.creation_date")
+	return s.hits(ctx, query+where, append(args, whereArgs...), "comment_id", page)
+}
+
+// hits runs a search query and returns the page of hits ordered by rank,
+// with idColumn of the query breaking ties
+func (s *sqliteSearch) hits(ctx context.Context, query string, args []any, idColumn string, page Page) ([]database.SearchHit, error) {
+	// bm25 is only known once the row matched, so the page is cut from the ranked rows
+	query = "SELECT * FROM (" + query + ") AS hit"
+	if page.After != nil {
+		query += " WHERE (rank, hit." + idColumn + ") > (?, ?)"
+		args = append(args, page.After.Score, page.After.ID)
+	}
+	query += " ORDER BY rank ASC, hit." + idColumn + " ASC" + page.sqlLimit()
+	return queryAll[database.SearchHit](ctx, s.db, query, args...)
+}
+
+// sqlFilters returns the conditions of the query's filters on a post p, an
+// author u and the creation date in dateColumn
+func (q SearchQuery) sqlFilters(dateColumn string) (string, []any) {
+	var where strings.Builder
+	var args []any
+	if q.Category != "" {
+		where.WriteString(" AND p.post_id IN (" + sqlPostsInCategory + ")")
+		args = append(args, q.Category)
+	}
+	if q.Author != "" {
+		where.WriteString(" AND u.username = ?")
+		args = append(args, q.Author)
+	}
+	if !q.From.IsZero() {
+		where.WriteString(" AND " + dateColumn + " >= ?")
+		args = append(args, sqliteTime(q.From))
+	}
+	if !q.To.IsZero() {
+		where.WriteString(" AND " + dateColumn + " < ?")
+		args = append(args, sqliteTime(q.To))
+	}
+	return where.String(), args
+}
+
+// matchQuery turns search terms into an FTS5 query. Each term is quoted so
+// user input cannot use the query syntax, and the last one also matches as a
+// prefix, for results while the user is still typing.
+func matchQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	if len(quoted) > 0 {
+		quoted[len(quoted)-1] += "*"
+	}
+	return strings.Join(quoted, " ")
+}
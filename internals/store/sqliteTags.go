@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+)
+
+// tagColumns selects tags in the column order expected by Tag.ScanRows
+const tagColumns = `
+	SELECT t.tag_id, t.name,
+		(SELECT COUNT(*) FROM PostTags pt JOIN Posts p ON pt.post_id = p.post_id
+		 WHERE pt.tag_id = t.tag_id AND NOT p.hidden AND p.deleted_at IS NULL) AS post_count
+	FROM Tags t`
+
+type sqliteTags struct {
+	db *sql.DB
+}
+
+func (s *sqliteTags) List(ctx context.Context, prefix string, limit int) ([]database.Tag, error) {
+	return queryAll[database.Tag](ctx, s.db, `
+		SELECT * FROM (`+tagColumns+` WHERE t.name LIKE ?1 || '%')
+		WHERE post_count > 0
+		ORDER BY post_count DESC, name
+		LIMIT ?2`, prefix, limit)
+}
+
+func (s *sqliteTags) Get(ctx context.Context, name string) (*database.Tag, error) {
+	return queryOne[database.Tag](ctx, s.db, tagColumns+`
+		WHERE t.name = ?1 OR t.tag_id = (SELECT tag_id FROM TagAliases WHERE alias = ?1)`, name)
+}
+
+func (s *sqliteTags) Merge(ctx context.Context, fromID, intoID int) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		from, err := queryOne[database.Tag](ctx, tx, tagColumns+" WHERE t.tag_id = ?", fromID)
+		if err != nil {
+			return err
+		}
+		if _, err := queryOne[database.Tag](ctx, tx, tagColumns+" WHERE t.tag_id = ?", intoID); err != nil {
+			return err
+		}
+		// Posts already tagged with both keep their one row for the target
+		if _, err := tx.ExecContext(ctx, `
+			INSERT OR IGNORE INTO PostTags (post_id, tag_id)
+			SELECT post_id, ? FROM PostTags WHERE tag_id = ?`, intoID, fromID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM PostTags WHERE tag_id = ?", fromID); err != nil {
+			return err
+		}
+		// Earlier aliases of the merged tag follow it
+		if _, err := tx.ExecContext(ctx, "UPDATE TagAliases SET tag_id = ? WHERE tag_id = ?", intoID, fromID); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO TagAliases (alias, tag_id) VALUES (?, ?)", from.Name, intoID); err != nil {
+			return err
+		}
+		return execMustAffect(ctx, tx, "DELETE FROM Tags WHERE tag_id = ?", fromID)
+	})
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+	"time"
+)
+
+// sqlPurgedPosts selects the posts moved to the trash before ?1
+const sqlPurgedPosts = "SELECT post_id FROM Posts WHERE deleted_at < ?1"
+
+// sqlPurgedComments selects the comments moved to the trash before ?1 that
+// no other comment replies to
+const sqlPurgedComments = `
+	SELECT c.comment_id FROM Comments c
+	WHERE c.deleted_at < ?1
+		AND NOT EXISTS (SELECT 1 FROM Comments r WHERE r.parent_comment_id = c.comment_id)`
+
+type sqliteTrash struct {
+	db *sql.DB
+}
+
+func (s *sqliteTrash) Post(ctx context.Context, postID int) (*database.Post, error) {
+	return getPost(ctx, s.db, postColumns+" WHERE p.post_id = ? AND p.deleted_at IS NOT NULL", postID)
+}
+
+func (s *sqliteTrash) Comment(ctx context.Context, commentID int) (*database.Comment, error) {
+	return queryOne[database.Comment](ctx, s.db, commentColumns+" WHERE c.comment_id = ? AND c.deleted_at IS NOT NULL", commentID)
+}
+
+func (s *sqliteTrash) Posts(ctx context.Context, userID int, since time.Time, page Page) ([]database.Post, error) {
+	query := postColumns + " WHERE p.user_id = ? AND p.deleted_at >= ?"
+	args := []any{userID, sqliteTime(since)}
+	if after, afterArgs := page.sqlAfter("p.deleted_at", "p.post_id", true); after != "" {
+		query += " AND " + after
+		args = append(args, afterArgs...)
+	}
+	query += " ORDER BY p.deleted_at DESC, p.post_id DESC" + page.sqlLimit()
+
+	posts, err := queryAll[database.Post](ctx, s.db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadPostLabels(ctx, s.db, posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (s *sqliteTrash) Comments(ctx context.Context, userID int, since time.Time, page Page) ([]database.Comment, error) {
+	query := commentColumns + " WHERE c.user_id = ? AND c.deleted_at >= ?"
+	args := []any{userID, sqliteTime(since)}
+	if after, afterArgs := page.sqlAfter("c.deleted_at", "c.comment_id", true); after != "" {
+		query += " AND " + after
+		args = append(args, afterArgs...)
+	}
+	query += " ORDER BY c.deleted_at DESC, c.comment_id DESC" + page.sqlLimit()
+	return queryAll[database.Comment](ctx, s.db, query, args...)
+}
+
+func (s *sqliteTrash) Purge(ctx context.Context, before time.Time) error {
+	cutoff := sqliteTime(before)
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		// Posts go with everything attached to them, children first since
+		// foreign keys are enforced
+		postSteps := []string{
+			`DELETE FROM Notifications WHERE related_post_id IN (` + sqlPurgedPosts + `)
+				OR related_comment_id IN (SELECT comment_id FROM Comments WHERE post_id IN (` + sqlPurgedPosts + `))`,
+			"DELETE FROM CommentLikes WHERE comment_id IN (SELECT comment_id FROM Comments WHERE post_id IN (" + sqlPurgedPosts + "))",
+			"DELETE FROM CommentRevisions WHERE comment_id IN (SELECT comment_id FROM Comments WHERE post_id IN (" + sqlPurgedPosts + "))",
+			"DELETE FROM Mentions WHERE target_type = 'comment' AND target_id IN (SELECT comment_id FROM Comments WHERE post_id IN (" + sqlPurgedPosts + "))",
+			"UPDATE Comments SET parent_comment_id = NULL WHERE post_id IN (" + sqlPurgedPosts + ")",
+			"DELETE FROM Comments WHERE post_id IN (" + sqlPurgedPosts + ")",
+			"DELETE FROM LikesDislikes WHERE post_id IN (" + sqlPurgedPosts + ")",
+			"DELETE FROM PostCategories WHERE post_id IN (" + sqlPurgedPosts + ")",
+			"DELETE FROM PostTags WHERE post_id IN (" + sqlPurgedPosts + ")",
+			"DELETE FROM PostRevisions WHERE post_id IN (" + sqlPurgedPosts + ")",
+			"DELETE FROM Mentions WHERE target_type = 'post' AND target_id IN (" + sqlPurgedPosts + ")",
+			"DELETE FROM Posts WHERE deleted_at < ?1",
+		}
+		for _, step := range postSteps {
+			if _, err := tx.ExecContext(ctx, step, cutoff); err != nil {
+				return err
+			}
+		}
+
+		// Comments without replies go; removing one can leave its deleted
+		// parent without replies, so this repeats until none is left
+		commentSteps := []string{
+			"DELETE FROM CommentLikes WHERE comment_id IN (" + sqlPurgedComments + ")",
+			"DELETE FROM CommentRevisions WHERE comment_id IN (" + sqlPurgedComments + ")",
+			"DELETE FROM Notifications WHERE related_comment_id IN (" + sqlPurgedComments + ")",
+			"DELETE FROM Mentions WHERE target_type = 'comment' AND target_id IN (" + sqlPurgedComments + ")",
+		}
+		for {
+			for _, step := range commentSteps {
+				if _, err := tx.ExecContext(ctx, step, cutoff); err != nil {
+					return err
+				}
+			}
+			res, err := tx.ExecContext(ctx, "DELETE FROM Comments WHERE comment_id IN ("+sqlPurgedComments+")", cutoff)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				break
+			}
+		}
+
+		// The rest keep their place in the thread with nothing left of them
+		if _, err := tx.ExecContext(ctx, "DELETE FROM CommentRevisions WHERE comment_id IN (SELECT comment_id FROM Comments WHERE deleted_at < ?)",
+			cutoff); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE Comments SET content = '' WHERE deleted_at < ? AND content != ''", cutoff)
+		return err
+	})
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"forum/internals/database"
+	"time"
+)
+
+const userColumns = `SELECT user_id, username, email, password_hash, registration_date, reset_token, role FROM Users`
+
+type sqliteUsers struct {
+	db *sql.DB
+}
+
+func (s *sqliteUsers) Create(ctx context.Context, username, email, passwordHash string) (int, error) {
+	res, err := s.db.ExecContext(ctx, "INSERT INTO Users (username, email, password_hash) VALUES (?, ?, ?)",
+		username, email, passwordHash)
+	if err != nil {
+		return 0, conflict(err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+func (s *sqliteUsers) Get(ctx context.Context, userID int) (*database.User, error) {
+	return queryOne[database.User](ctx, s.db, userColumns+" WHERE user_id = ?", userID)
+}
+
+func (s *sqliteUsers) GetByEmail(ctx context.Context, email string) (*database.User, error) {
+	return queryOne[database.User](ctx, s.db, userColumns+" WHERE email = ?", email)
+}
+
+func (s *sqliteUsers) GetByLogin(ctx context.Context, emailOrUsername string) (*database.User, error) {
+	return queryOne[database.User](ctx, s.db, userColumns+" WHERE email = ? OR username = ?", emailOrUsername, emailOrUsername)
+}
+
+func (s *sqliteUsers) GetByResetToken(ctx context.Context, token string) (*database.User, error) {
+	return queryOne[database.User](ctx, s.db, userColumns+" WHERE reset_token = ?", token)
+}
+
+func (s *sqliteUsers) EmailExists(ctx context.Context, email string) (bool, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users WHERE email = ?", email).Scan(&exists)
+	return exists > 0, err
+}
+
+func (s *sqliteUsers) UsernameTaken(ctx context.Context, username string, exceptUserID int) (bool, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users WHERE username = ? AND user_id != ?", username, exceptUserID).Scan(&exists)
+	return exists > 0, err
+}
+
+func (s *sqliteUsers) UpdateUsername(ctx context.Context, userID int, username string) error {
+	return execMustAffect(ctx, s.db, "UPDATE Users SET username = ? WHERE user_id = ?", username, userID)
+}
+
+func (s *sqliteUsers) UpdateBio(ctx context.Context, userID int, bio string) error {
+	return execMustAffect(ctx, s.db, "UPDATE Users SET bio = ? WHERE user_id = ?", bio, userID)
+}
+
+func (s *sqliteUsers) SetResetToken(ctx context.Context, email, token string) error {
+	_, err := s.db.ExecContext(ctx, "UPDATE Users SET reset_token = ? WHERE email = ?", token, email)
+	return err
+}
+
+func (s *sqliteUsers) ResetPassword(ctx context.Context, userID int, passwordHash string) error {
+	return execMustAffect(ctx, s.db, "UPDATE Users SET password_hash = ?, reset_token = NULL WHERE user_id = ?", passwordHash, userID)
+}
+
+func (s *sqliteUsers) List(ctx context.Context) ([]database.User, error) {
+	return queryAll[database.User](ctx, s.db, userColumns+" ORDER BY username")
+}
+
+func (s *sqliteUsers) ListByUsernames(ctx context.Context, usernames []string) ([]database.User, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(usernames))
+	for i, name := range usernames {
+		args[i] = name
+	}
+	return queryAll[database.User](ctx, s.db, userColumns+" WHERE username IN ("+placeholders(len(args))+") ORDER BY username", args...)
+}
+
+func (s *sqliteUsers) SetRole(ctx context.Context, userID int, role string) error {
+	return execMustAffect(ctx, s.db, "UPDATE Users SET role = ? WHERE user_id = ?", role, userID)
+}
+
+func (s *sqliteUsers) Profile(ctx context.Context, userID int) (*database.UserProfile, error) {
+	var profile database.UserProfile
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			u.user_id,
+			u.username,
+			u.email,
+			COALESCE( strftime('%Y-%m-%d', u.registration_date), '' ) AS join_date,
+			COALESCE(u.bio, '')
+		FROM Users u
+		WHERE u.user_id = ?
+	`, userID).Scan(&profile.UserID, &profile.Username, &profile.Email, &profile.JoinDate, &profile.Bio)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counters := []struct {
+		dst   *int
+		query string
+	}{
+		{&profile.PostCount, `SELECT COUNT(*) FROM Posts WHERE user_id = ? AND deleted_at IS NULL`},
+		{&profile.CommentCount, `SELECT COUNT(*) FROM Comments WHERE user_id = ? AND deleted_at IS NULL`},
+		{&profile.LikesGiven, `SELECT COUNT(*) FROM LikesDislikes WHERE user_id = ? AND vote = 1`},
+		{&profile.LikesReceived, `
+			SELECT COUNT(*)
+			FROM LikesDislikes ld
+			JOIN Posts p ON ld.post_id = p.post_id
+			WHERE p.user_id = ? AND ld.vote = 1 AND p.deleted_at IS NULL`},
+		{&profile.DislikesGiven, `SELECT COUNT(*) FROM LikesDislikes WHERE user_id = ? AND vote = -1`},
+		{&profile.DislikesReceived, `
+			SELECT COUNT(*)
+			FROM LikesDislikes ld
+			JOIN Posts p ON ld.post_id = p.post_id
+			WHERE p.user_id = ? AND ld.vote = -1 AND p.deleted_at IS NULL`},
+	}
+	for _, c := range counters {
+		if err := s.db.QueryRowContext(ctx, c.query, userID).Scan(c.dst); err != nil {
+			return nil, err
+		}
+	}
+
+	// Profile image is optional
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(thumbnail_url, '')
+		FROM Images
+		WHERE user_id = ? AND image_type = 'profile'
+		ORDER BY upload_date DESC
+		LIMIT 1
+	`, userID).Scan(&profile.ProfileImage)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return &profile, nil
+}
+
+type sqliteSessions struct {
+	db *sql.DB
+}
+
+func (s *sqliteSessions) Create(ctx context.Context, userID int, cookieValue, csrfToken string, expires time.Time) error {
+	_, err := s.db.ExecContext(ctx, "INSERT INTO Sessions (user_id, cookie_value, csrf_token, expiration_date) VALUES (?, ?, ?, ?)",
+		userID, cookieValue, csrfToken, expires.UTC())
+	return conflict(err)
+}
+
+func (s *sqliteSessions) Delete(ctx context.Context, cookieValue string) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM Sessions WHERE cookie_value = ?", cookieValue)
+	return err
+}
+
+func (s *sqliteSessions) DeleteForUser(ctx context.Context, userID int) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM Sessions WHERE user_id = ?", userID)
+	return err
+}
+
+func (s *sqliteSessions) User(ctx context.Context, cookieValue string) (*database.SessionUser, error) {
+	return queryOne[database.SessionUser](ctx, s.db, `
+		SELECT u.user_id, u.username, u.email, u.password_hash, u.registration_date, u.reset_token, u.role, s.csrf_token
+		FROM Sessions s
+		JOIN Users u ON u.user_id = s.user_id
+		WHERE s.cookie_value = ? AND s.expiration_date > ?`, cookieValue, time.Now().UTC())
+}
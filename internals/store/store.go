@@ -0,0 +1,322 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"forum/internals/database"
+	"time"
+)
+
+// ErrNotFound is returned when the requested row does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a row would break a uniqueness rule
+var ErrConflict = errors.New("already exists")
+
+// ErrCycle is returned when a category would end up inside itself
+var ErrCycle = errors.New("category would contain itself")
+
+// Store groups every repository the handlers use
+type Store struct {
+	Users         UserStore
+	Sessions      SessionStore
+	Posts         PostStore
+	Comments      CommentStore
+	Votes         VoteStore
+	Categories    CategoryStore
+	Notifications NotificationStore
+	Images        ImageStore
+	RateLimits    RateLimitStore
+	ModerationLog ModerationLogStore
+	Reports       ReportStore
+	Search        SearchStore
+	Tags          TagStore
+	Drafts        DraftStore
+	Revisions     RevisionStore
+	Trash         TrashStore
+	Mentions      MentionStore
+}
+
+// UserStore manages user accounts and profiles
+type UserStore interface {
+	Create(ctx context.Context, username, email, passwordHash string) (int, error)
+	Get(ctx context.Context, userID int) (*database.User, error)
+	GetByEmail(ctx context.Context, email string) (*database.User, error)
+	// GetByLogin finds a user by email address or username
+	GetByLogin(ctx context.Context, emailOrUsername string) (*database.User, error)
+	GetByResetToken(ctx context.Context, token string) (*database.User, error)
+	EmailExists(ctx context.Context, email string) (bool, error)
+	// UsernameTaken reports whether a user other than exceptUserID has the username
+	UsernameTaken(ctx context.Context, username string, exceptUserID int) (bool, error)
+	UpdateUsername(ctx context.Context, userID int, username string) error
+	UpdateBio(ctx context.Context, userID int, bio string) error
+	SetResetToken(ctx context.Context, email, token string) error
+	// ResetPassword stores a new password hash and clears the reset token
+	ResetPassword(ctx context.Context, userID int, passwordHash string) error
+	Profile(ctx context.Context, userID int) (*database.UserProfile, error)
+	// List returns every user ordered by username
+	List(ctx context.Context) ([]database.User, error)
+	// ListByUsernames returns the users with any of the given usernames
+	ListByUsernames(ctx context.Context, usernames []string) ([]database.User, error)
+	SetRole(ctx context.Context, userID int, role string) error
+}
+
+// SessionStore manages login sessions
+type SessionStore interface {
+	Create(ctx context.Context, userID int, cookieValue, csrfToken string, expires time.Time) error
+	Delete(ctx context.Context, cookieValue string) error
+	DeleteForUser(ctx context.Context, userID int) error
+	// User returns the owner of an unexpired session with the session's CSRF token
+	User(ctx context.Context, cookieValue string) (*database.SessionUser, error)
+}
+
+// PostFilter narrows down a post listing; zero values mean no filtering
+type PostFilter struct {
+	// Category lists the posts of a category and of its descendants
+	Category string
+	// Tag lists the posts with the tag of that name, or of the tag it was
+	// merged into
+	Tag      string
+	AuthorID int
+	// Author lists the posts of the user with that username
+	Author string
+	// VotedBy with Vote lists the posts a user liked (1) or disliked (-1)
+	VotedBy int
+	Vote    int
+	// IncludeHidden also lists posts hidden by a moderator
+	IncludeHidden bool
+	// Since keeps the posts created at or after it
+	Since time.Time
+
+	// Sort orders the listing, newest first by default
+	Sort PostSort
+	// At is the moment hot scores are computed for; every page of a
+	// listing passes the same one
+	At time.Time
+}
+
+// PostStore manages posts and their category associations
+type PostStore interface {
+	// Create adds a post in the given categories, tagged with post.Tags
+	Create(ctx context.Context, post *database.Post, categoryIDs []int) (int, error)
+	// Get returns a post unless it is in the trash
+	Get(ctx context.Context, postID int) (*database.Post, error)
+	// List returns a page of the matching posts in the filter's sort order,
+	// leaving out the posts in the trash
+	List(ctx context.Context, filter PostFilter, page Page) ([]database.Post, error)
+	// Update replaces a post's title, content, categories and tags, nil tags
+	// keeping the current ones, and records the result as a revision by
+	// editorID. Changing only the tags records no revision and leaves the
+	// post unmarked as edited.
+	Update(ctx context.Context, postID, editorID int, title, content string, categoryIDs []int, tags []string) error
+	SetHidden(ctx context.Context, postID int, hidden bool) error
+	// Delete moves a post to the trash on behalf of deletedBy; its comments,
+	// votes and revisions stay with it until it is restored or purged
+	Delete(ctx context.Context, postID, deletedBy int) error
+	// Restore takes a post out of the trash
+	Restore(ctx context.Context, postID int) error
+}
+
+// CommentStore manages comments on posts
+type CommentStore interface {
+	Create(ctx context.Context, comment *database.Comment) (int, error)
+	// Get returns a comment unless it or its post is in the trash
+	Get(ctx context.Context, commentID int) (*database.Comment, error)
+	// ListByPost returns a page of a post's top-level comments, oldest first,
+	// each followed by all of its replies. Comments in the trash are listed
+	// too, so threads can keep a placeholder where they were.
+	ListByPost(ctx context.Context, postID int, page Page) ([]database.Comment, error)
+	// ListByUser returns a page of a user's comments, newest first, leaving
+	// out the ones in the trash or on posts in the trash
+	ListByUser(ctx context.Context, userID int, page Page) ([]database.Comment, error)
+	// Update replaces a comment's content and records it as a revision by
+	// editorID; saving the same content again does neither
+	Update(ctx context.Context, commentID, editorID int, content string) error
+	SetHidden(ctx context.Context, commentID int, hidden bool) error
+	// Delete moves a comment to the trash on behalf of deletedBy
+	Delete(ctx context.Context, commentID, deletedBy int) error
+	// Restore takes a comment out of the trash
+	Restore(ctx context.Context, commentID int) error
+	// Commenters returns the distinct users with a live comment on a post,
+	// minus the excluded ones
+	Commenters(ctx context.Context, postID int, exclude ...int) ([]int, error)
+}
+
+// VoteStore manages likes and dislikes; a vote is 1, -1 or 0 for none
+type VoteStore interface {
+	PostVote(ctx context.Context, postID, userID int) (int, error)
+	SetPostVote(ctx context.Context, postID, userID, vote int) error
+	PostCounts(ctx context.Context, postID int) (likes, dislikes int, err error)
+	CommentVote(ctx context.Context, commentID, userID int) (int, error)
+	SetCommentVote(ctx context.Context, commentID, userID, vote int) error
+	CommentCounts(ctx context.Context, commentID int) (likes, dislikes int, err error)
+}
+
+// CategoryStore manages the post categories
+type CategoryStore interface {
+	// List returns the categories in their sort order, leaving out archived
+	// ones unless includeArchived is set
+	List(ctx context.Context, includeArchived bool) ([]database.Category, error)
+	Get(ctx context.Context, categoryID int) (*database.Category, error)
+	// Create adds a category, failing with ErrConflict if the name or slug is
+	// taken and ErrNotFound if the parent does not exist. It goes after every
+	// other category.
+	Create(ctx context.Context, category *database.Category) (int, error)
+	// Update saves a category's name, slug, description, color, icon and
+	// parent, failing with ErrConflict if the name or slug is taken,
+	// ErrNotFound if the parent does not exist and ErrCycle if the parent is
+	// the category itself or one of its descendants
+	Update(ctx context.Context, category *database.Category) error
+	// Reorder gives the categories the order of categoryIDs; categories left
+	// out go after them, keeping their order
+	Reorder(ctx context.Context, categoryIDs []int) error
+	SetArchived(ctx context.Context, categoryID int, archived bool) error
+	// Merge moves every post, draft and sub-category of a category into
+	// another one and deletes it, failing with ErrCycle if the other one is
+	// a descendant
+	Merge(ctx context.Context, fromID, intoID int) error
+	// Delete removes a category, failing with ErrConflict while posts still
+	// use it, those in the trash included; its sub-categories move up to its
+	// parent and drafts drop it
+	Delete(ctx context.Context, categoryID int) error
+}
+
+// TagStore manages the free-form post tags. Tags are set through PostStore;
+// names given there are looked up among the aliases left by merges first.
+type TagStore interface {
+	// List returns up to limit tags used by visible posts whose name starts
+	// with prefix, most used first
+	List(ctx context.Context, prefix string, limit int) ([]database.Tag, error)
+	// Get returns the tag with the name, or the one it was merged into
+	Get(ctx context.Context, name string) (*database.Tag, error)
+	// Merge moves every post of a tag to another one and deletes it, keeping
+	// its name as an alias of the other tag
+	Merge(ctx context.Context, fromID, intoID int) error
+}
+
+// DraftStore manages the posts writers have not published yet
+type DraftStore interface {
+	// Save creates a draft when draft.DraftID is 0 and otherwise replaces its
+	// title, content, image, tags and categories, failing with ErrNotFound
+	// unless the draft belongs to draft.UserID. It returns the draft's ID.
+	Save(ctx context.Context, draft *database.Draft, categoryIDs []int) (int, error)
+	Get(ctx context.Context, draftID int) (*database.Draft, error)
+	// ListByUser returns a page of a user's drafts, last saved first
+	ListByUser(ctx context.Context, userID int, page Page) ([]database.Draft, error)
+	Delete(ctx context.Context, draftID int) error
+	// Publish creates a post from a draft like PostStore.Create and deletes
+	// the draft in the same step
+	Publish(ctx context.Context, draftID int, post *database.Post, categoryIDs []int) (int, error)
+}
+
+// RevisionStore reads the version history recorded by PostStore.Update and
+// CommentStore.Update. Content that was never edited has no revisions; once
+// edited, its first revision is the original and its last the current version.
+type RevisionStore interface {
+	// ListPost returns every revision of a post, oldest first
+	ListPost(ctx context.Context, postID int) ([]database.Revision, error)
+	GetPost(ctx context.Context, revisionID int) (*database.Revision, error)
+	// ListComment returns every revision of a comment, oldest first
+	ListComment(ctx context.Context, commentID int) ([]database.Revision, error)
+	GetComment(ctx context.Context, revisionID int) (*database.Revision, error)
+}
+
+// TrashStore reads and empties the trash PostStore.Delete and
+// CommentStore.Delete move content into
+type TrashStore interface {
+	// Post returns a post in the trash
+	Post(ctx context.Context, postID int) (*database.Post, error)
+	// Comment returns a comment in the trash
+	Comment(ctx context.Context, commentID int) (*database.Comment, error)
+	// Posts returns a page of a user's posts moved to the trash since the
+	// given time, last deleted first
+	Posts(ctx context.Context, userID int, since time.Time, page Page) ([]database.Post, error)
+	// Comments returns a page of a user's comments moved to the trash since
+	// the given time, last deleted first
+	Comments(ctx context.Context, userID int, since time.Time, page Page) ([]database.Comment, error)
+	// Purge removes for good what was moved to the trash before the given
+	// time: posts with their comments, votes, categories, tags, revisions and
+	// notifications, and comments with theirs. A comment that still has
+	// replies is emptied instead and stays as their placeholder.
+	Purge(ctx context.Context, before time.Time) error
+}
+
+// MentionStore remembers who has been @mentioned in each post ("post") and
+// comment ("comment")
+type MentionStore interface {
+	// Add records users mentioned in a post or comment and returns those that
+	// had not been mentioned in it before
+	Add(ctx context.Context, targetType string, targetID int, userIDs []int) ([]int, error)
+}
+
+// NotificationStore manages user notifications
+type NotificationStore interface {
+	Create(ctx context.Context, n *database.Notification) error
+	// HasRecent reports whether the same notification was already created within the window
+	HasRecent(ctx context.Context, n *database.Notification, window time.Duration) (bool, error)
+	Get(ctx context.Context, notificationID int) (*database.Notification, error)
+	// List returns a page of a user's read or unread notifications, newest first
+	List(ctx context.Context, userID int, read bool, page Page) ([]database.Notification, error)
+	MarkRead(ctx context.Context, notificationID int) error
+	MarkAllRead(ctx context.Context, userID int) error
+	UnreadCount(ctx context.Context, userID int) (int, error)
+	DeleteReadBefore(ctx context.Context, before time.Time) error
+	CreateSystem(ctx context.Context, userIDs []int, title, message string) error
+}
+
+// ImageStore manages uploaded image metadata
+type ImageStore interface {
+	Create(ctx context.Context, img *database.Image) error
+	GetByFilename(ctx context.Context, filename string) (*database.Image, error)
+	ListByUser(ctx context.Context, userID int, imageType string) ([]database.Image, error)
+	// Delete removes the image record and detaches it from any posts and drafts
+	Delete(ctx context.Context, filename string) error
+}
+
+// RateLimitStore keeps the token buckets of the rate limiter
+type RateLimitStore interface {
+	// Take removes a token from each of the buckets named keys, which hold up
+	// to capacity tokens refilled over window. When any of them is empty none
+	// is charged: the request is not allowed and retryAfter says when to try
+	// again.
+	Take(ctx context.Context, keys []string, capacity int, window time.Duration) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// ModerationLogStore records moderator and admin actions
+type ModerationLogStore interface {
+	Record(ctx context.Context, action *database.ModerationAction) error
+	// List returns the most recent actions first
+	List(ctx context.Context, limit, offset int) ([]database.ModerationAction, error)
+}
+
+// ReportStore manages content reports and the moderation queue
+type ReportStore interface {
+	// Create files a report, failing with ErrConflict if the reporter
+	// already has an open report on the same target
+	Create(ctx context.Context, report *database.Report) (int, error)
+	Get(ctx context.Context, reportID int) (*database.Report, error)
+	// ListOpen returns the open reports, oldest first
+	ListOpen(ctx context.Context, limit, offset int) ([]database.Report, error)
+	// Resolve closes every open report on a target and returns them
+	Resolve(ctx context.Context, targetType string, targetID int, resolution string, resolvedBy int) ([]database.Report, error)
+}
+
+// SearchQuery is a full-text search; the zero values of the filters match everything
+type SearchQuery struct {
+	// Terms must all appear as words; the last one may also start a longer word
+	Terms []string
+	// Category also matches its descendants
+	Category string
+	// Author is the username of the writer
+	Author string
+	// From and To bound the creation date, To excluded
+	From, To time.Time
+}
+
+// SearchStore runs full-text searches over visible posts and comments
+type SearchStore interface {
+	// Posts returns a page of the matching posts, best match first
+	Posts(ctx context.Context, q SearchQuery, page Page) ([]database.SearchHit, error)
+	// Comments returns a page of the matching comments, best match first
+	Comments(ctx context.Context, q SearchQuery, page Page) ([]database.SearchHit, error)
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"slices"
+	"strings"
+)
+
+// DiffLine is one line of a line-by-line diff: Op is "equal" for a line both
+// texts share, "delete" for one only the old text has and "insert" for one
+// only the new text has
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxDiffEdits caps how many changes DiffLines looks for between two parts
+// of the texts before it settles for showing one replaced by the other, which
+// keeps diffs of unrelated texts fast
+const maxDiffEdits = 1000
+
+// DiffLines compares two texts line by line, keeping as many lines unchanged
+// as possible. Deleted lines come before the lines inserted in their place.
+// It uses Myers' algorithm in linear space, so long texts with few changes
+// are cheap to compare.
+func DiffLines(before, after string) []DiffLine {
+	a, b := splitLines(before), splitLines(after)
+
+	// Lines are compared by number, the same number for equal lines
+	numbers := map[string]int{}
+	number := func(lines []string) []int {
+		ns := make([]int, len(lines))
+		for i, line := range lines {
+			n, ok := numbers[line]
+			if !ok {
+				n = len(numbers)
+				numbers[line] = n
+			}
+			ns[i] = n
+		}
+		return ns
+	}
+	d := differ{a: a, b: b, an: number(a), bn: number(b)}
+	d.diff(0, len(a), 0, len(b))
+
+	// Within each run of changes, deleted lines go first
+	diff := d.out
+	for i := 0; i < len(diff); {
+		if diff[i].Op == "equal" {
+			i++
+			continue
+		}
+		j := i
+		for j < len(diff) && diff[j].Op != "equal" {
+			j++
+		}
+		slices.SortStableFunc(diff[i:j], func(x, y DiffLine) int {
+			switch {
+			case x.Op == y.Op:
+				return 0
+			case x.Op == "delete":
+				return -1
+			}
+			return 1
+		})
+		i = j
+	}
+	return diff
+}
+
+// differ holds the two texts being compared, with their line numbers, and
+// the diff found so far
+type differ struct {
+	a, b   []string
+	an, bn []int
+	out    []DiffLine
+}
+
+// diff appends the diff of a[aLo:aHi] and b[bLo:bHi]
+func (d *differ) diff(aLo, aHi, bLo, bHi int) {
+	// Lines shared at both ends are kept as they are
+	start, end := aLo, aHi
+	for aLo < aHi && bLo < bHi && d.an[aLo] == d.bn[bLo] {
+		aLo++
+		bLo++
+	}
+	d.equal(start, aLo)
+	for aLo < aHi && bLo < bHi && d.an[aHi-1] == d.bn[bHi-1] {
+		aHi--
+		bHi--
+	}
+
+	if aLo == aHi || bLo == bHi {
+		d.replace(aLo, aHi, bLo, bHi)
+	} else if x, y, ok := d.split(aLo, aHi, bLo, bHi); ok {
+		d.diff(aLo, x, bLo, y)
+		d.diff(x, aHi, y, bHi)
+	} else {
+		d.replace(aLo, aHi, bLo, bHi)
+	}
+	d.equal(aHi, end)
+}
+
+// split finds a point on a shortest edit path between a[aLo:aHi] and
+// b[bLo:bHi], by searching from both ends at once until the paths meet. It
+// reports false when the parts differ by more than maxDiffEdits changes.
+func (d *differ) split(aLo, aHi, bLo, bHi int) (x, y int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := min((n+m+1)/2, maxDiffEdits)
+
+	// forward[k] and backward[k] are how far along a the furthest paths on
+	// diagonal k reach, from the start and from the end
+	offset := maxD + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for e := 0; e <= maxD; e++ {
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.an[aLo+x] == d.bn[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if back := delta - k; odd && back >= -(e-1) && back <= e-1 && x+backward[offset+back] >= n {
+				return aLo + x, bLo + y, true
+			}
+		}
+		for k := -e; k <= e; k += 2 {
+			var x int
+			if k == -e || (k != e && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.an[aHi-1-x] == d.bn[bHi-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if front := delta - k; !odd && front >= -e && front <= e && x+forward[offset+front] >= n {
+				return aHi - x, bHi - y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// equal appends a[lo:hi] as unchanged lines
+func (d *differ) equal(lo, hi int) {
+	for _, line := range d.a[lo:hi] {
+		d.out = append(d.out, DiffLine{"equal", line})
+	}
+}
+
+// replace appends a[aLo:aHi] as deleted and b[bLo:bHi] as inserted
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for _, line := range d.a[aLo:aHi] {
+		d.out = append(d.out, DiffLine{"delete", line})
+	}
+	for _, line := range d.b[bLo:bHi] {
+		d.out = append(d.out, DiffLine{"insert", line})
+	}
+}
+
+// splitLines splits a text into lines, with no lines at all for ""
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
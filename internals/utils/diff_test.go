@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after string
+		want          []DiffLine
+	}{
+		{"empty", "", "", []DiffLine{}},
+		{"added", "", "a", []DiffLine{{"insert", "a"}}},
+		{"removed", "a", "", []DiffLine{{"delete", "a"}}},
+		{"unchanged", "a\nb", "a\nb", []DiffLine{{"equal", "a"}, {"equal", "b"}}},
+		{"changed line", "a\nb\nc", "a\nx\nc", []DiffLine{{"equal", "a"}, {"delete", "b"}, {"insert", "x"}, {"equal", "c"}}},
+		{"windows line endings", "a\r\nb", "a\nb", []DiffLine{{"equal", "a"}, {"equal", "b"}}},
+		{"moved line", "a\nb\nc", "b\nc\na", []DiffLine{{"delete", "a"}, {"equal", "b"}, {"equal", "c"}, {"insert", "a"}}},
+		{"deletes first", "a\nb\nc\nd", "x\nb\ny\nd", []DiffLine{
+			{"delete", "a"}, {"insert", "x"}, {"equal", "b"}, {"delete", "c"}, {"insert", "y"}, {"equal", "d"},
+		}},
+		{"keeps most lines", "a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc", []DiffLine{
+			{"delete", "a"}, {"insert", "c"}, {"equal", "b"}, {"delete", "c"}, {"equal", "a"}, {"equal", "b"},
+			{"delete", "b"}, {"equal", "a"}, {"insert", "c"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffLines(tt.before, tt.after)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines(%q, %q)\n got  %v\n want %v", tt.before, tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesUnrelated checks that texts too different to diff line by line
+// are shown as replaced, rather than searched for their few shared lines
+func TestDiffLinesUnrelated(t *testing.T) {
+	before := strings.Repeat("a\n", 3*maxDiffEdits) + "end"
+	after := strings.Repeat("b\n", 3*maxDiffEdits) + "end"
+	got := DiffLines(before, after)
+	if n := len(got); n != 6*maxDiffEdits+1 || got[n-1] != (DiffLine{"equal", "end"}) {
+		t.Fatalf("got %d lines ending with %v", n, got[n-1])
+	}
+	for _, line := range got[:3*maxDiffEdits] {
+		if line.Op != "delete" {
+			t.Fatalf("got %v among the deleted lines", line)
+		}
+	}
+}
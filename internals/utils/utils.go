@@ -3,12 +3,13 @@ package utils
 import (
 	"crypto/rand"
 	"fmt"
-	"forum/internals/database"
+	"forum/internals/middleware"
 	"html/template"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
+	"unicode"
 )
 
 // TemplateData holds data to pass to templates
@@ -18,7 +19,9 @@ type TemplateData struct {
 	UserID     int
 	Message    string
 	Error      string
-	Data       interface{}
+	// CSRFToken goes into a hidden csrf_token field of every POST form
+	CSRFToken string
+	Data      interface{}
 }
 
 func FileService(filename string, w http.ResponseWriter, data any) {
@@ -29,17 +32,18 @@ func FileService(filename string, w http.ResponseWriter, data any) {
 	tmpl.Execute(w, data)
 }
 
-// FileServiceWithAuth serves templates with authentication context
+// FileServiceWithAuth serves templates with the logged in user from the request context
 func FileServiceWithAuth(filename string, w http.ResponseWriter, r *http.Request, data interface{}) {
 	templateData := &TemplateData{
 		Data: data,
 	}
 
 	// Check if user is logged in
-	if cookie, err := r.Cookie("session"); err == nil && IsValidSession(cookie.Value) {
+	if user := middleware.UserFrom(r.Context()); user != nil {
 		templateData.IsLoggedIn = true
-		templateData.UserID = GetUserIDFromSession(cookie.Value)
-		templateData.Username = GetUsernameFromSession(cookie.Value)
+		templateData.UserID = user.ID
+		templateData.Username = user.Username
+		templateData.CSRFToken = user.CSRFToken
 	}
 
 	tmpl, err := template.ParseFiles("frontend/templates/" + filename)
@@ -166,90 +170,6 @@ func GenerateCookieValue() string {
 	return string(result)
 }
 
-// IsValidSession returns true if the given session cookie exists and is not expired.
-func IsValidSession(cookieValue string) bool {
-	db := database.CreateTable()
-	defer db.Close()
-
-	var expiration time.Time
-	err := db.QueryRow(
-		"SELECT expiration_date FROM Sessions WHERE cookie_value = ?",
-		cookieValue,
-	).Scan(&expiration)
-	if err != nil {
-		return false // not found, or some other DB error
-	}
-	return time.Now().Before(expiration)
-}
-
-// GetUserIDFromSession returns the user ID for a given session cookie
-func GetUserIDFromSession(cookieValue string) int {
-	db := database.CreateTable()
-	defer db.Close()
-
-	var userID int
-	err := db.QueryRow("SELECT user_id FROM Sessions WHERE cookie_value = ? AND expiration_date > datetime('now')", cookieValue).Scan(&userID)
-	if err != nil {
-		return 0
-	}
-	return userID
-}
-
-// GetUsernameFromSession returns the username for a given session cookie
-func GetUsernameFromSession(cookieValue string) string {
-	db := database.CreateTable()
-	defer db.Close()
-
-	var username string
-	err := db.QueryRow(`
-		SELECT u.username 
-		FROM Users u 
-		JOIN Sessions s ON u.user_id = s.user_id 
-		WHERE s.cookie_value = ? AND s.expiration_date > datetime('now')
-	`, cookieValue).Scan(&username)
-	if err != nil {
-		return ""
-	}
-	return username
-}
-
-// CheckAuth is a middleware to check if user is authenticated
-func CheckAuth(r *http.Request) (bool, int, string) {
-	cookie, err := r.Cookie("session")
-	if err != nil {
-		return false, 0, ""
-	}
-
-	if !IsValidSession(cookie.Value) {
-		return false, 0, ""
-	}
-
-	userID := GetUserIDFromSession(cookie.Value)
-	username := GetUsernameFromSession(cookie.Value)
-
-	return true, userID, username
-}
-
-// UpdateSessionUsername updates the username in the Sessions table
-func UpdateSessionUsername(cookieValue string, newUsername string) {
-	db := database.CreateTable()
-	defer db.Close()
-
-	// Ενημερώνουμε το username για το session ώστε να φαίνεται άμεσα στο frontend
-	_, err := db.Exec(`
-		UPDATE Users 
-		SET username = ?
-		WHERE user_id = (
-			SELECT user_id 
-			FROM Sessions 
-			WHERE cookie_value = ?
-		)
-	`, newUsername, cookieValue)
-
-	if err != nil {
-		fmt.Println("Error updating session username:", err)
-	}
-}
 func FormatTimeAgo(t time.Time) string {
 	duration := time.Since(t)
 
@@ -276,3 +196,21 @@ func TruncateText(text string, maxLength int) string {
 	}
 	return text[:maxLength] + "..."
 }
+
+// Slugify turns a name into the lowercase, dash-separated form used in URLs
+func Slugify(name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return slug.String()
+}
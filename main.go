@@ -1,21 +1,109 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"forum/internals/config"
 	"forum/internals/database"
 	"forum/internals/handlers"
+	"forum/internals/store"
+	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// shutdownTimeout is how long requests in flight get to finish on shutdown
+const shutdownTimeout = 10 * time.Second
+
+const usage = `usage:
+  forum [flags]                 start the web server
+  forum migrate status [flags]  list applied and pending migrations
+  forum migrate up [flags]      apply pending migrations
+  forum role <user> <role>      make a user (username or email) a member, moderator or admin`
+
 func main() {
+	args := os.Args[1:]
+	command := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(args)
+	case "migrate":
+		migrate(args)
+	case "role":
+		setRole(args)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// serve starts the web server
+func serve(args []string) {
+	// Load configuration from defaults, config file, environment and flags
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Open the shared connection pool used by every handler
+	db, err := database.Open(cfg.DBPath, cfg.DBPool)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
 	// Initialize database
-	database.InitializeDatabase()
+	if err := database.InitializeDatabase(db); err != nil {
+		log.Fatal(err)
+	}
+
+	// Access logs and panics are written as structured lines on stderr
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	st := store.NewSQLite(db)
+	if cfg.RateLimits.Backend == "memory" {
+		st.RateLimits = store.NewMemoryRateLimits()
+	}
+	app := handlers.NewApp(st, cfg)
+
+	// Background jobs and the server stop on an interrupt or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Content deleted longer ago than the retention window is purged in the background
+	go app.PurgeTrash(ctx)
+	go app.Events.Run(ctx)
+
+	srv := &http.Server{Addr: cfg.Addr(), Handler: app.Routes()}
+	// Event streams never finish on their own, so they are ended first
+	srv.RegisterOnShutdown(app.Events.Close)
 
-	fmt.Println("Server running on http://localhost:8080")
+	shutdown := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		shutdown <- srv.Shutdown(shutdownCtx)
+	}()
 
-	// Setup routes
-	handlers.SetupRoutes()
+	fmt.Println("Server running on " + cfg.BaseURL)
 
 	// Start server
-	http.ListenAndServe(":8080", nil)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	if err := <-shutdown; err != nil {
+		log.Printf("Shutdown: %v", err)
+	}
+	fmt.Println("Server stopped")
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"forum/internals/config"
+	"forum/internals/database"
+	"log"
+	"os"
+)
+
+// migrate runs the "forum migrate status|up" command
+func migrate(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	action, args := args[0], args[1:]
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.Open(cfg.DBPath, cfg.DBPool)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	switch action {
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range states {
+			status := "pending"
+			if s.AppliedAt != nil {
+				status = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-32s %s\n", s.Version, s.Name, status)
+		}
+	case "up":
+		applied, err := database.Migrate(db)
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
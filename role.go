@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"forum/internals/config"
+	"forum/internals/database"
+	"forum/internals/roles"
+	"forum/internals/store"
+	"log"
+	"os"
+)
+
+// setRole runs the "forum role <username|email> <role>" command, which is
+// how the first admin is appointed
+func setRole(args []string) {
+	if len(args) < 2 || !roles.Valid(args[1]) {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	login, role, args := args[0], args[1], args[2:]
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := database.Open(cfg.DBPath, cfg.DBPool)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := database.Migrate(db); err != nil {
+		log.Fatal(err)
+	}
+
+	users := store.NewSQLite(db).Users
+	user, err := users.GetByLogin(context.Background(), login)
+	if err != nil {
+		log.Fatalf("user %q: %v", login, err)
+	}
+	if err := users.SetRole(context.Background(), user.UserID, role); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s is now %s (was %s)\n", user.Username, role, user.Role)
+}